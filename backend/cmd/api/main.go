@@ -7,9 +7,14 @@ import (
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/hadiabbas/fittrack-backend/internal/catalog"
 	"github.com/hadiabbas/fittrack-backend/internal/handlers"
 	"github.com/hadiabbas/fittrack-backend/internal/middleware"
 	"github.com/hadiabbas/fittrack-backend/pkg/database"
+	"github.com/hadiabbas/fittrack-backend/pkg/mailer"
+	"github.com/hadiabbas/fittrack-backend/pkg/nutrition"
+	"github.com/hadiabbas/fittrack-backend/pkg/revocation"
+	"github.com/hadiabbas/fittrack-backend/pkg/utils"
 	"github.com/joho/godotenv"
 )
 
@@ -20,15 +25,67 @@ func main() {
 	}
 
 	// Validate required environment variables
-	requiredEnvVars := []string{"SUPABASE_URL", "SUPABASE_ANON_KEY", "JWT_SECRET"}
+	backend := database.BackendFromEnv()
+	var requiredEnvVars []string
+	if backend == database.BackendSupabase {
+		requiredEnvVars = append(requiredEnvVars, "SUPABASE_URL", "SUPABASE_ANON_KEY", "SUPABASE_SERVICE_KEY")
+	}
+	if os.Getenv("ENVIRONMENT") == "production" {
+		requiredEnvVars = append(requiredEnvVars, "JWT_KEYS_DIR")
+	}
 	for _, envVar := range requiredEnvVars {
 		if os.Getenv(envVar) == "" {
 			log.Fatalf("Error: %s environment variable is required", envVar)
 		}
 	}
 
-	// Initialize Supabase client
-	db := database.NewSupabaseClient()
+	// Initialize storage backend
+	db, err := database.NewStore(backend)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if backend == database.BackendMemory {
+		log.Println("Warning: using in-memory storage, data will be lost on restart")
+	}
+
+	// Add the built-in exercises to the catalog, or update them
+	if err := catalog.Seed(db); err != nil {
+		log.Printf("Warning: failed to seed the exercise catalog: %v", err)
+	}
+
+	// Initialize nutrition parser for food text logging
+	parser, err := nutrition.NewParserFromEnv()
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	// Initialize the access token signing keys and rotate them in the background
+	keys, err := utils.NewKeyManagerFromEnv()
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if os.Getenv("JWT_KEYS_DIR") == "" {
+		log.Println("Warning: JWT_KEYS_DIR is not set, signing keys live in memory and tokens will not survive a restart")
+	}
+	stopRotation := keys.StartRotation()
+	defer stopRotation()
+
+	// Initialize the access token revocation list
+	revoked, err := revocation.NewStoreFromEnv(db)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	// Initialize the mailer for password reset and confirmation emails
+	mail, err := mailer.NewMailerFromEnv()
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	// The other mailers write reset and confirmation codes in plain text to
+	// the log or disk instead of delivering them
+	if os.Getenv("ENVIRONMENT") == "production" && os.Getenv("MAILER") != mailer.MailerSMTP {
+		log.Fatalf("Error: MAILER must be smtp when ENVIRONMENT=production")
+	}
 
 	// Initialize Gin router
 	if os.Getenv("ENVIRONMENT") == "production" {
@@ -40,7 +97,8 @@ func main() {
 	// CORS configuration
 	config := cors.DefaultConfig()
 	config.AllowOrigins = []string{"*"} // In production, set this to your specific domain
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization", "X-Timezone"}
+	config.ExposeHeaders = []string{"X-Total-Count", "X-Limit", "X-Offset"}
 	router.Use(cors.New(config))
 
 	// Health check endpoint
@@ -51,20 +109,32 @@ func main() {
 		})
 	})
 
+	// Public keys for verifying FitTrack access tokens
+	keysHandler := handlers.NewKeysHandler(keys)
+	router.GET("/.well-known/jwks.json", keysHandler.GetJWKS)
+
 	// API v1 routes
 	v1 := router.Group("/api/v1")
 	{
 		// Authentication routes (public)
 		auth := v1.Group("/auth")
 		{
-			authHandler := handlers.NewAuthHandler(db)
+			authHandler := handlers.NewAuthHandler(db, keys, revoked, mail)
+			authHandler.AppURL = os.Getenv("APP_URL")
+			authHandler.RequireEmailConfirmation = os.Getenv("REQUIRE_EMAIL_CONFIRMATION") == "true"
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
+			auth.POST("/refresh", authHandler.Refresh)
+			auth.POST("/logout", authHandler.Logout)
+			auth.POST("/logout-all", middleware.AuthMiddleware(keys, revoked, db), authHandler.LogoutAll)
+			auth.POST("/forgot-password", authHandler.ForgotPassword)
+			auth.POST("/reset-password", authHandler.ResetPassword)
+			auth.POST("/verify-email", authHandler.VerifyEmail)
 		}
 
 		// Protected routes (require authentication)
 		protected := v1.Group("")
-		protected.Use(middleware.AuthMiddleware())
+		protected.Use(middleware.AuthMiddleware(keys, revoked, db))
 		{
 			// Workout routes
 			workouts := protected.Group("/workouts")
@@ -73,27 +143,86 @@ func main() {
 				workouts.POST("", workoutHandler.CreateWorkout)
 				workouts.GET("", workoutHandler.GetWorkouts)
 				workouts.GET("/:id", workoutHandler.GetWorkout)
+				workouts.PUT("/:id", workoutHandler.UpdateWorkout)
+				workouts.PATCH("/:id", workoutHandler.PatchWorkout)
 				workouts.DELETE("/:id", workoutHandler.DeleteWorkout)
 			}
 
-			// Food logging routes (to be implemented in Phase 2)
+			// Exercise routes
+			exercises := protected.Group("/exercises")
+			{
+				exerciseHandler := handlers.NewExerciseHandler(db)
+				exercises.GET("", exerciseHandler.SearchExercises)
+				exercises.POST("", exerciseHandler.CreateExercise)
+				exercises.GET("/:id", exerciseHandler.GetExercise)
+				exercises.PUT("/:id", exerciseHandler.UpdateExercise)
+				exercises.DELETE("/:id", exerciseHandler.DeleteExercise)
+				// Progress and suggestions take a catalog ID or an exercise name as :id
+				exercises.GET("/:id/progress", exerciseHandler.GetProgress)
+				exercises.GET("/:id/suggestion", exerciseHandler.SuggestNext)
+			}
+
+			// Routine routes
+			routines := protected.Group("/routines")
+			{
+				routineHandler := handlers.NewRoutineHandler(db)
+				routines.POST("", routineHandler.CreateRoutine)
+				routines.GET("", routineHandler.GetRoutines)
+				routines.GET("/:id", routineHandler.GetRoutine)
+				routines.PUT("/:id", routineHandler.UpdateRoutine)
+				routines.DELETE("/:id", routineHandler.DeleteRoutine)
+				routines.POST("/:id/start", routineHandler.StartRoutine)
+			}
+
+			// Training analysis routes
+			training := protected.Group("/training")
+			{
+				trainingHandler := handlers.NewTrainingHandler(db)
+				training.GET("/load", trainingHandler.GetTrainingLoad)
+				training.GET("/muscles", trainingHandler.GetMuscleVolume)
+			}
+
+			// Food logging routes
 			food := protected.Group("/food")
 			{
-				food.POST("/parse-text", func(c *gin.Context) {
-					c.JSON(200, gin.H{"message": "Food text parsing - Coming in Phase 2"})
-				})
+				foodHandler := handlers.NewFoodHandler(db, parser)
+				food.POST("/parse-text", foodHandler.ParseText)
 				food.POST("/parse-image", func(c *gin.Context) {
 					c.JSON(200, gin.H{"message": "Food image parsing - Coming in Phase 2"})
 				})
-				food.GET("/logs", func(c *gin.Context) {
-					c.JSON(200, gin.H{"message": "Food logs - Coming in Phase 2"})
-				})
+				food.GET("/logs", foodHandler.GetFoodLogs)
+				food.POST("/logs", foodHandler.CreateFoodLog)
+				food.GET("/logs/:id", foodHandler.GetFoodLog)
+				food.PUT("/logs/:id", foodHandler.UpdateFoodLog)
+				food.DELETE("/logs/:id", foodHandler.DeleteFoodLog)
+				food.GET("/summary", foodHandler.GetDailySummary)
+			}
+
+			// Body metrics routes
+			bodyMetrics := protected.Group("/body-metrics")
+			{
+				bodyMetricHandler := handlers.NewBodyMetricHandler(db)
+				bodyMetrics.POST("", bodyMetricHandler.UpsertBodyMetric)
+				bodyMetrics.GET("", bodyMetricHandler.GetBodyMetrics)
+				bodyMetrics.GET("/trend", bodyMetricHandler.GetWeightTrend)
+				bodyMetrics.DELETE("/:id", bodyMetricHandler.DeleteBodyMetric)
 			}
 
-			// Dashboard routes (to be implemented in Phase 3)
-			protected.GET("/dashboard", func(c *gin.Context) {
-				c.JSON(200, gin.H{"message": "Dashboard insights - Coming in Phase 3"})
-			})
+			// Dashboard routes
+			dashboardHandler := handlers.NewDashboardHandler(db)
+			protected.GET("/dashboard", dashboardHandler.GetDashboard)
+
+			// Account routes
+			me := protected.Group("/me")
+			{
+				accountHandler := handlers.NewAccountHandler(db, revoked)
+				me.DELETE("", accountHandler.DeleteAccount)
+				me.GET("/export", accountHandler.ExportData)
+
+				profileHandler := handlers.NewProfileHandler(db)
+				me.GET("/profile", profileHandler.GetProfile)
+				me.PUT("/profile", profileHandler.UpdateProfile)
+			}
 		}
 	}
 
@@ -105,12 +234,52 @@ func main() {
 
 	fmt.Printf("🚀 FitTrack API server starting on port %s\n", port)
 	fmt.Println("📊 Endpoints available:")
+	fmt.Println("   - GET  /.well-known/jwks.json")
 	fmt.Println("   - POST /api/v1/auth/register")
 	fmt.Println("   - POST /api/v1/auth/login")
+	fmt.Println("   - POST /api/v1/auth/refresh")
+	fmt.Println("   - POST /api/v1/auth/logout")
+	fmt.Println("   - POST /api/v1/auth/logout-all")
+	fmt.Println("   - POST /api/v1/auth/forgot-password")
+	fmt.Println("   - POST /api/v1/auth/reset-password")
+	fmt.Println("   - POST /api/v1/auth/verify-email")
 	fmt.Println("   - POST /api/v1/workouts")
 	fmt.Println("   - GET  /api/v1/workouts")
 	fmt.Println("   - GET  /api/v1/workouts/:id")
+	fmt.Println("   - PUT  /api/v1/workouts/:id")
+	fmt.Println("   - PATCH /api/v1/workouts/:id")
 	fmt.Println("   - DELETE /api/v1/workouts/:id")
+	fmt.Println("   - GET  /api/v1/exercises")
+	fmt.Println("   - POST /api/v1/exercises")
+	fmt.Println("   - GET  /api/v1/exercises/:id")
+	fmt.Println("   - PUT  /api/v1/exercises/:id")
+	fmt.Println("   - DELETE /api/v1/exercises/:id")
+	fmt.Println("   - GET  /api/v1/exercises/:id/progress")
+	fmt.Println("   - GET  /api/v1/exercises/:id/suggestion")
+	fmt.Println("   - POST /api/v1/routines")
+	fmt.Println("   - GET  /api/v1/routines")
+	fmt.Println("   - GET  /api/v1/routines/:id")
+	fmt.Println("   - PUT  /api/v1/routines/:id")
+	fmt.Println("   - DELETE /api/v1/routines/:id")
+	fmt.Println("   - POST /api/v1/routines/:id/start")
+	fmt.Println("   - GET  /api/v1/training/load")
+	fmt.Println("   - GET  /api/v1/training/muscles")
+	fmt.Println("   - POST /api/v1/food/parse-text")
+	fmt.Println("   - POST /api/v1/food/logs")
+	fmt.Println("   - GET  /api/v1/food/logs")
+	fmt.Println("   - GET  /api/v1/food/logs/:id")
+	fmt.Println("   - PUT  /api/v1/food/logs/:id")
+	fmt.Println("   - DELETE /api/v1/food/logs/:id")
+	fmt.Println("   - GET  /api/v1/food/summary")
+	fmt.Println("   - POST /api/v1/body-metrics")
+	fmt.Println("   - GET  /api/v1/body-metrics")
+	fmt.Println("   - GET  /api/v1/body-metrics/trend")
+	fmt.Println("   - DELETE /api/v1/body-metrics/:id")
+	fmt.Println("   - GET  /api/v1/dashboard")
+	fmt.Println("   - DELETE /api/v1/me")
+	fmt.Println("   - GET  /api/v1/me/export")
+	fmt.Println("   - GET  /api/v1/me/profile")
+	fmt.Println("   - PUT  /api/v1/me/profile")
 
 	if err := router.Run(":" + port); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
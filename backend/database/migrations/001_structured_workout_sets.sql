@@ -0,0 +1,91 @@
+-- Converts the free-text weight and reps of workout_sets into numbers
+--
+-- Before this migration both columns were TEXT NOT NULL and held whatever
+-- the client sent ("80", "80 kg", "175lbs", "BW", "8-10", ...). The text is
+-- moved to weight_text and reps_text, parsed into the numeric weight,
+-- weight_unit and reps columns, and kept only for the rows that could not be
+-- parsed. Those rows are flagged with needs_review so the app can ask the
+-- user to fix them; saving the workout again clears the flag.
+--
+-- Run it once in the Supabase SQL Editor, then run schema.sql to update the
+-- database functions. Running it again is harmless.
+
+BEGIN;
+
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = 'public' AND table_name = 'workout_sets'
+          AND column_name = 'weight' AND data_type = 'text'
+    ) THEN
+        ALTER TABLE workout_sets RENAME COLUMN weight TO weight_text;
+        ALTER TABLE workout_sets RENAME COLUMN reps TO reps_text;
+    END IF;
+END $$;
+
+ALTER TABLE workout_sets
+    ALTER COLUMN weight_text DROP NOT NULL,
+    ALTER COLUMN reps_text DROP NOT NULL,
+    ADD COLUMN IF NOT EXISTS weight DECIMAL(7,2) CHECK (weight IS NULL OR weight >= 0),
+    ADD COLUMN IF NOT EXISTS weight_unit TEXT CHECK (weight_unit IN ('kg', 'lb')),
+    ADD COLUMN IF NOT EXISTS reps INTEGER CHECK (reps IS NULL OR reps >= 0),
+    ADD COLUMN IF NOT EXISTS set_type TEXT NOT NULL DEFAULT 'working'
+        CHECK (set_type IN ('warmup', 'working', 'drop', 'failure')),
+    ADD COLUMN IF NOT EXISTS distance_m DECIMAL(10,2) CHECK (distance_m IS NULL OR distance_m >= 0),
+    ADD COLUMN IF NOT EXISTS duration_seconds INTEGER CHECK (duration_seconds IS NULL OR duration_seconds >= 0),
+    ADD COLUMN IF NOT EXISTS needs_review BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Weights: a number with an optional unit; no unit means kilograms. The
+-- pattern only accepts what fits DECIMAL(7,2), so larger values are flagged
+-- for review below instead of aborting the migration.
+UPDATE workout_sets s SET
+    weight = replace(p.m[1], ',', '.')::DECIMAL(7,2),
+    weight_unit = CASE WHEN p.m[2] ~ '^(lb|pound)' THEN 'lb' ELSE 'kg' END
+FROM (
+    SELECT id, regexp_match(lower(btrim(weight_text)), '^(\d{1,5}(?:[.,]\d{1,2})?)\s*(kgs?|kilos?|kilograms?|lbs?|pounds?)?$') AS m
+    FROM workout_sets
+    WHERE weight IS NULL AND weight_text IS NOT NULL
+) p
+WHERE s.id = p.id AND p.m IS NOT NULL;
+
+-- Bodyweight sets carry no added load
+UPDATE workout_sets SET weight = 0, weight_unit = 'kg'
+WHERE weight IS NULL AND lower(btrim(weight_text)) IN ('bw', 'bodyweight', 'body weight');
+
+-- Reps: a whole number of up to six digits, optionally followed by "reps"
+UPDATE workout_sets s SET reps = p.m[1]::INTEGER
+FROM (
+    SELECT id, regexp_match(lower(btrim(reps_text)), '^(\d{1,6})\s*(?:reps?)?$') AS m
+    FROM workout_sets
+    WHERE reps IS NULL AND duration_seconds IS NULL AND reps_text IS NOT NULL
+) p
+WHERE s.id = p.id AND p.m IS NOT NULL;
+
+-- Timed sets logged as reps, such as "30s" or "45 sec", of up to six digits
+UPDATE workout_sets s SET duration_seconds = p.m[1]::INTEGER
+FROM (
+    SELECT id, regexp_match(lower(btrim(reps_text)), '^(\d{1,6})\s*(?:s|secs?|seconds?)$') AS m
+    FROM workout_sets
+    WHERE reps IS NULL AND duration_seconds IS NULL AND reps_text IS NOT NULL
+) p
+WHERE s.id = p.id AND p.m IS NOT NULL;
+
+-- Flag what could not be read ("8-10", "AMRAP", "BW+10", ...); empty text
+-- simply means nothing was recorded
+UPDATE workout_sets SET needs_review = TRUE
+WHERE (weight IS NULL AND btrim(COALESCE(weight_text, '')) NOT IN ('', '-'))
+   OR (reps IS NULL AND duration_seconds IS NULL AND btrim(COALESCE(reps_text, '')) NOT IN ('', '-'));
+
+UPDATE workout_sets SET weight_text = NULL, reps_text = NULL
+WHERE NOT needs_review;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'workout_sets_weight_has_unit') THEN
+        ALTER TABLE workout_sets
+            ADD CONSTRAINT workout_sets_weight_has_unit CHECK (weight IS NULL OR weight_unit IS NOT NULL);
+    END IF;
+END $$;
+
+COMMIT;
@@ -19,6 +19,54 @@ CREATE TABLE IF NOT EXISTS workout_sessions (
     updated_at TIMESTAMPTZ DEFAULT NOW()
 );
 
+-- Exercises Table
+-- The exercise catalog: built-in exercises (user_id NULL), seeded by the API
+-- on startup from internal/catalog, and each user's custom exercises
+CREATE TABLE IF NOT EXISTS exercises (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID REFERENCES auth.users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    aliases TEXT[] NOT NULL DEFAULT '{}',
+    primary_muscles TEXT[] NOT NULL DEFAULT '{}',
+    secondary_muscles TEXT[] NOT NULL DEFAULT '{}',
+    equipment TEXT,
+    movement_pattern TEXT,
+    unilateral BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Routines Table
+-- Workout templates a user starts workouts from
+CREATE TABLE IF NOT EXISTS routines (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    notes TEXT DEFAULT '',
+    activity_type TEXT NOT NULL DEFAULT 'strength' CHECK (activity_type IN ('strength', 'cardio')),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Routine Exercises Table
+-- The exercises a routine prescribes, in order, with their targets
+CREATE TABLE IF NOT EXISTS routine_exercises (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    routine_id UUID NOT NULL REFERENCES routines(id) ON DELETE CASCADE,
+    exercise_id UUID REFERENCES exercises(id) ON DELETE SET NULL,
+    name TEXT NOT NULL,
+    notes TEXT DEFAULT '',
+    "order" INTEGER DEFAULT 0,
+    target_sets INTEGER NOT NULL CHECK (target_sets >= 1),
+    target_reps_min INTEGER CHECK (target_reps_min IS NULL OR target_reps_min >= 1),
+    target_reps_max INTEGER CHECK (target_reps_max IS NULL OR target_reps_max >= target_reps_min),
+    target_weight DECIMAL(7,2) CHECK (target_weight IS NULL OR target_weight >= 0),
+    weight_unit TEXT CHECK (weight_unit IN ('kg', 'lb')),
+    target_rpe DECIMAL(3,1) CHECK (target_rpe IS NULL OR (target_rpe >= 1 AND target_rpe <= 10)),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    CONSTRAINT routine_exercises_weight_has_unit CHECK (target_weight IS NULL OR weight_unit IS NOT NULL)
+);
+
 -- Workout Exercises Table
 CREATE TABLE IF NOT EXISTS workout_exercises (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
@@ -30,15 +78,41 @@ CREATE TABLE IF NOT EXISTS workout_exercises (
 );
 
 -- Workout Sets Table
+-- Databases created before weight and reps were numeric are converted by
+-- migrations/001_structured_workout_sets.sql
 CREATE TABLE IF NOT EXISTS workout_sets (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     exercise_id UUID NOT NULL REFERENCES workout_exercises(id) ON DELETE CASCADE,
-    weight TEXT NOT NULL,
-    reps TEXT NOT NULL,
+    weight DECIMAL(7,2) CHECK (weight IS NULL OR weight >= 0),
+    weight_unit TEXT CHECK (weight_unit IN ('kg', 'lb')),
+    reps INTEGER CHECK (reps IS NULL OR reps >= 0),
+    set_type TEXT NOT NULL DEFAULT 'working' CHECK (set_type IN ('warmup', 'working', 'drop', 'failure')),
+    distance_m DECIMAL(10,2) CHECK (distance_m IS NULL OR distance_m >= 0),
+    duration_seconds INTEGER CHECK (duration_seconds IS NULL OR duration_seconds >= 0),
     rpe DECIMAL(3,1) CHECK (rpe IS NULL OR (rpe >= 1 AND rpe <= 10)),
-    created_at TIMESTAMPTZ DEFAULT NOW()
+    "order" INTEGER DEFAULT 0,
+    -- Free text of migrated sets that could not be parsed
+    weight_text TEXT,
+    reps_text TEXT,
+    needs_review BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    CONSTRAINT workout_sets_weight_has_unit CHECK (weight IS NULL OR weight_unit IS NOT NULL)
 );
 
+-- Catalog exercise a logged exercise refers to; name stays the display name
+ALTER TABLE workout_exercises ADD COLUMN IF NOT EXISTS exercise_id UUID REFERENCES exercises(id) ON DELETE SET NULL;
+
+-- Routine exercise a logged exercise was prescribed by, to compare what was
+-- done with the routine's targets
+ALTER TABLE workout_exercises ADD COLUMN IF NOT EXISTS routine_exercise_id UUID REFERENCES routine_exercises(id) ON DELETE SET NULL;
+
+-- Sets created in one transaction share created_at, so keep their position
+ALTER TABLE workout_sets ADD COLUMN IF NOT EXISTS "order" INTEGER DEFAULT 0;
+
+-- Formula that estimated estimated_calories (see pkg/calories), NULL when
+-- the calories were entered by the user
+ALTER TABLE workout_sessions ADD COLUMN IF NOT EXISTS calorie_formula_version TEXT;
+
 -- Food Logs Table
 CREATE TABLE IF NOT EXISTS food_logs (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
@@ -66,15 +140,85 @@ CREATE TABLE IF NOT EXISTS body_metrics (
     UNIQUE(user_id, log_date)
 );
 
+-- User Profiles Table
+-- One row per user, created on the first profile update
+CREATE TABLE IF NOT EXISTS user_profiles (
+    user_id UUID PRIMARY KEY REFERENCES auth.users(id) ON DELETE CASCADE,
+    height_cm DECIMAL(5,1),
+    birth_date DATE,
+    sex TEXT CHECK (sex IN ('female', 'male', 'other')),
+    unit_system TEXT NOT NULL DEFAULT 'metric' CHECK (unit_system IN ('metric', 'imperial')),
+    time_zone TEXT NOT NULL DEFAULT 'UTC',
+    calorie_target INTEGER,
+    protein_target_g INTEGER,
+    goal_weight_kg DECIMAL(6,2),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Formula estimating one-rep maxes for progress and personal records
+ALTER TABLE user_profiles ADD COLUMN IF NOT EXISTS e1rm_formula TEXT NOT NULL DEFAULT 'epley'
+    CHECK (e1rm_formula IN ('epley', 'brzycki'));
+
+-- Refresh Tokens Table
+-- Only SHA-256 hashes of the opaque tokens are stored. Every token issued by
+-- rotating another belongs to the same family as the login that started it.
+-- The family's Supabase session is kept on its unrevoked token, so access
+-- tokens only name the family (sid) and never carry Supabase credentials.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    family_id UUID NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    supabase_refresh_token TEXT,
+    supabase_access_token TEXT,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ,
+    replaced_by UUID REFERENCES refresh_tokens(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS supabase_access_token TEXT;
+
+-- Revoked Tokens Table
+-- Access tokens rejected before they expire, by their jti claim
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti TEXT PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Token Cutoffs Table
+-- "Sign out all devices": every access token of the user issued before
+-- revoked_before is rejected. Rows can be deleted after expires_at. There is
+-- no foreign key to auth.users: deleting an account sets a cutoff that has to
+-- outlive the user until its tokens have expired.
+CREATE TABLE IF NOT EXISTS token_cutoffs (
+    user_id UUID PRIMARY KEY,
+    revoked_before TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+ALTER TABLE token_cutoffs DROP CONSTRAINT IF EXISTS token_cutoffs_user_id_fkey;
+
 -- Create indexes for better query performance
 CREATE INDEX IF NOT EXISTS idx_workout_sessions_user_id ON workout_sessions(user_id);
 CREATE INDEX IF NOT EXISTS idx_workout_sessions_workout_date ON workout_sessions(workout_date);
 CREATE INDEX IF NOT EXISTS idx_workout_exercises_workout_id ON workout_exercises(workout_id);
 CREATE INDEX IF NOT EXISTS idx_workout_sets_exercise_id ON workout_sets(exercise_id);
+CREATE INDEX IF NOT EXISTS idx_workout_exercises_exercise_id ON workout_exercises(exercise_id);
+CREATE INDEX IF NOT EXISTS idx_exercises_user_id ON exercises(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_exercises_user_name ON exercises(user_id, lower(name));
+CREATE INDEX IF NOT EXISTS idx_routines_user_id ON routines(user_id);
+CREATE INDEX IF NOT EXISTS idx_routine_exercises_routine_id ON routine_exercises(routine_id);
+CREATE INDEX IF NOT EXISTS idx_workout_exercises_routine_exercise_id ON workout_exercises(routine_exercise_id);
 CREATE INDEX IF NOT EXISTS idx_food_logs_user_id ON food_logs(user_id);
 CREATE INDEX IF NOT EXISTS idx_food_logs_log_date ON food_logs(log_date);
 CREATE INDEX IF NOT EXISTS idx_body_metrics_user_id ON body_metrics(user_id);
 CREATE INDEX IF NOT EXISTS idx_body_metrics_log_date ON body_metrics(log_date);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens(expires_at);
 
 -- Enable Row Level Security (RLS)
 ALTER TABLE workout_sessions ENABLE ROW LEVEL SECURITY;
@@ -82,6 +226,14 @@ ALTER TABLE workout_exercises ENABLE ROW LEVEL SECURITY;
 ALTER TABLE workout_sets ENABLE ROW LEVEL SECURITY;
 ALTER TABLE food_logs ENABLE ROW LEVEL SECURITY;
 ALTER TABLE body_metrics ENABLE ROW LEVEL SECURITY;
+ALTER TABLE user_profiles ENABLE ROW LEVEL SECURITY;
+ALTER TABLE exercises ENABLE ROW LEVEL SECURITY;
+ALTER TABLE routines ENABLE ROW LEVEL SECURITY;
+ALTER TABLE routine_exercises ENABLE ROW LEVEL SECURITY;
+-- No policies: token tables are only reachable with the service role key
+ALTER TABLE refresh_tokens ENABLE ROW LEVEL SECURITY;
+ALTER TABLE revoked_tokens ENABLE ROW LEVEL SECURITY;
+ALTER TABLE token_cutoffs ENABLE ROW LEVEL SECURITY;
 
 -- RLS Policies for workout_sessions
 CREATE POLICY "Users can view their own workouts"
@@ -165,6 +317,10 @@ CREATE POLICY "Users can insert their own food logs"
     ON food_logs FOR INSERT
     WITH CHECK (auth.uid() = user_id);
 
+CREATE POLICY "Users can update their own food logs"
+    ON food_logs FOR UPDATE
+    USING (auth.uid() = user_id);
+
 CREATE POLICY "Users can delete their own food logs"
     ON food_logs FOR DELETE
     USING (auth.uid() = user_id);
@@ -186,6 +342,88 @@ CREATE POLICY "Users can delete their own body metrics"
     ON body_metrics FOR DELETE
     USING (auth.uid() = user_id);
 
+-- RLS Policies for user_profiles
+CREATE POLICY "Users can view their own profile"
+    ON user_profiles FOR SELECT
+    USING (auth.uid() = user_id);
+
+CREATE POLICY "Users can insert their own profile"
+    ON user_profiles FOR INSERT
+    WITH CHECK (auth.uid() = user_id);
+
+CREATE POLICY "Users can update their own profile"
+    ON user_profiles FOR UPDATE
+    USING (auth.uid() = user_id);
+
+-- RLS Policies for exercises
+-- Built-in exercises are readable by everyone and only written with the
+-- service role key
+CREATE POLICY "Users can view built-in and their own exercises"
+    ON exercises FOR SELECT
+    USING (user_id IS NULL OR auth.uid() = user_id);
+
+CREATE POLICY "Users can insert their own exercises"
+    ON exercises FOR INSERT
+    WITH CHECK (auth.uid() = user_id);
+
+CREATE POLICY "Users can update their own exercises"
+    ON exercises FOR UPDATE
+    USING (auth.uid() = user_id);
+
+CREATE POLICY "Users can delete their own exercises"
+    ON exercises FOR DELETE
+    USING (auth.uid() = user_id);
+
+-- RLS Policies for routines
+CREATE POLICY "Users can view their own routines"
+    ON routines FOR SELECT
+    USING (auth.uid() = user_id);
+
+CREATE POLICY "Users can insert their own routines"
+    ON routines FOR INSERT
+    WITH CHECK (auth.uid() = user_id);
+
+CREATE POLICY "Users can update their own routines"
+    ON routines FOR UPDATE
+    USING (auth.uid() = user_id);
+
+CREATE POLICY "Users can delete their own routines"
+    ON routines FOR DELETE
+    USING (auth.uid() = user_id);
+
+-- RLS Policies for routine_exercises
+CREATE POLICY "Users can view exercises of their routines"
+    ON routine_exercises FOR SELECT
+    USING (
+        routine_id IN (
+            SELECT id FROM routines WHERE user_id = auth.uid()
+        )
+    );
+
+CREATE POLICY "Users can insert exercises for their routines"
+    ON routine_exercises FOR INSERT
+    WITH CHECK (
+        routine_id IN (
+            SELECT id FROM routines WHERE user_id = auth.uid()
+        )
+    );
+
+CREATE POLICY "Users can update exercises of their routines"
+    ON routine_exercises FOR UPDATE
+    USING (
+        routine_id IN (
+            SELECT id FROM routines WHERE user_id = auth.uid()
+        )
+    );
+
+CREATE POLICY "Users can delete exercises from their routines"
+    ON routine_exercises FOR DELETE
+    USING (
+        routine_id IN (
+            SELECT id FROM routines WHERE user_id = auth.uid()
+        )
+    );
+
 -- Create a function to update updated_at timestamp
 CREATE OR REPLACE FUNCTION update_updated_at_column()
 RETURNS TRIGGER AS $$
@@ -201,3 +439,327 @@ CREATE TRIGGER update_workout_sessions_updated_at
     FOR EACH ROW
     EXECUTE FUNCTION update_updated_at_column();
 
+-- Create trigger for user_profiles
+CREATE TRIGGER update_user_profiles_updated_at
+    BEFORE UPDATE ON user_profiles
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Create trigger for exercises
+CREATE TRIGGER update_exercises_updated_at
+    BEFORE UPDATE ON exercises
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Create trigger for routines
+CREATE TRIGGER update_routines_updated_at
+    BEFORE UPDATE ON routines
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Inserts the exercises and sets of a workout payload, numbering exercises in
+-- array order. Used by create_workout and replace_workout.
+CREATE OR REPLACE FUNCTION insert_workout_exercises(p_workout_id UUID, p_exercises JSONB)
+RETURNS VOID AS $$
+DECLARE
+    v_exercise JSONB;
+    v_position BIGINT;
+    v_exercise_id UUID;
+BEGIN
+    FOR v_exercise, v_position IN
+        SELECT value, ordinality FROM jsonb_array_elements(COALESCE(p_exercises, '[]'::jsonb)) WITH ORDINALITY
+    LOOP
+        INSERT INTO workout_exercises (workout_id, exercise_id, routine_exercise_id, name, notes, "order")
+        VALUES (
+            p_workout_id,
+            (v_exercise->>'exercise_id')::UUID,
+            (v_exercise->>'routine_exercise_id')::UUID,
+            v_exercise->>'name',
+            COALESCE(v_exercise->>'notes', ''),
+            v_position - 1
+        )
+        RETURNING id INTO v_exercise_id;
+
+        INSERT INTO workout_sets (
+            exercise_id, weight, weight_unit, reps, set_type, distance_m, duration_seconds, rpe, "order"
+        )
+        SELECT
+            v_exercise_id,
+            (s.value->>'weight')::DECIMAL,
+            s.value->>'weight_unit',
+            (s.value->>'reps')::INTEGER,
+            COALESCE(s.value->>'set_type', 'working'),
+            (s.value->>'distance_m')::DECIMAL,
+            (s.value->>'duration_seconds')::INTEGER,
+            (s.value->>'rpe')::DECIMAL,
+            s.ordinality - 1
+        FROM jsonb_array_elements(COALESCE(v_exercise->'sets', '[]'::jsonb)) WITH ORDINALITY AS s;
+    END LOOP;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Creates a workout with all of its exercises and sets in one transaction
+-- and returns its id
+CREATE OR REPLACE FUNCTION create_workout(p_workout JSONB)
+RETURNS UUID AS $$
+DECLARE
+    v_workout_id UUID;
+BEGIN
+    INSERT INTO workout_sessions (
+        id, user_id, workout_name, workout_date, duration_hours, duration_minutes,
+        overall_rpe, estimated_calories, calorie_formula_version, activity_type
+    )
+    VALUES (
+        COALESCE((p_workout->>'id')::UUID, uuid_generate_v4()),
+        (p_workout->>'user_id')::UUID,
+        p_workout->>'workout_name',
+        (p_workout->>'workout_date')::TIMESTAMPTZ,
+        COALESCE((p_workout->>'duration_hours')::INTEGER, 0),
+        (p_workout->>'duration_minutes')::INTEGER,
+        (p_workout->>'overall_rpe')::DECIMAL,
+        COALESCE((p_workout->>'estimated_calories')::INTEGER, 0),
+        p_workout->>'calorie_formula_version',
+        p_workout->>'activity_type'
+    )
+    RETURNING id INTO v_workout_id;
+
+    PERFORM insert_workout_exercises(v_workout_id, p_workout->'exercises');
+
+    RETURN v_workout_id;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Replaces a user's workout, including all exercises and sets, in one
+-- transaction. Returns false if the workout does not exist for the user.
+CREATE OR REPLACE FUNCTION replace_workout(p_workout_id UUID, p_user_id UUID, p_workout JSONB)
+RETURNS BOOLEAN AS $$
+BEGIN
+    UPDATE workout_sessions SET
+        workout_name = p_workout->>'workout_name',
+        workout_date = (p_workout->>'workout_date')::TIMESTAMPTZ,
+        duration_hours = COALESCE((p_workout->>'duration_hours')::INTEGER, 0),
+        duration_minutes = (p_workout->>'duration_minutes')::INTEGER,
+        overall_rpe = (p_workout->>'overall_rpe')::DECIMAL,
+        estimated_calories = COALESCE((p_workout->>'estimated_calories')::INTEGER, 0),
+        calorie_formula_version = p_workout->>'calorie_formula_version',
+        activity_type = p_workout->>'activity_type'
+    WHERE id = p_workout_id AND user_id = p_user_id;
+
+    IF NOT FOUND THEN
+        RETURN FALSE;
+    END IF;
+
+    DELETE FROM workout_exercises WHERE workout_id = p_workout_id;
+    PERFORM insert_workout_exercises(p_workout_id, p_workout->'exercises');
+
+    RETURN TRUE;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Deletes a user's workout; exercises and sets are removed by the cascading
+-- foreign keys. Returns false if the workout does not exist for the user.
+CREATE OR REPLACE FUNCTION delete_workout(p_workout_id UUID, p_user_id UUID)
+RETURNS BOOLEAN AS $$
+BEGIN
+    DELETE FROM workout_sessions WHERE id = p_workout_id AND user_id = p_user_id;
+    RETURN FOUND;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Saves the exercises of a routine payload in array order. Exercises with an
+-- id are updated in place so the workouts linked to them stay linked, new
+-- ones are inserted and those missing from the payload are deleted. Used by
+-- create_routine and replace_routine.
+CREATE OR REPLACE FUNCTION save_routine_exercises(p_routine_id UUID, p_exercises JSONB)
+RETURNS VOID AS $$
+DECLARE
+    v_exercise JSONB;
+    v_position BIGINT;
+BEGIN
+    DELETE FROM routine_exercises
+    WHERE routine_id = p_routine_id
+      AND id NOT IN (
+          SELECT (value->>'id')::UUID
+          FROM jsonb_array_elements(COALESCE(p_exercises, '[]'::jsonb))
+          WHERE value->>'id' IS NOT NULL
+      );
+
+    FOR v_exercise, v_position IN
+        SELECT value, ordinality FROM jsonb_array_elements(COALESCE(p_exercises, '[]'::jsonb)) WITH ORDINALITY
+    LOOP
+        IF v_exercise->>'id' IS NOT NULL THEN
+            UPDATE routine_exercises SET
+                exercise_id = (v_exercise->>'exercise_id')::UUID,
+                name = v_exercise->>'name',
+                notes = COALESCE(v_exercise->>'notes', ''),
+                "order" = v_position - 1,
+                target_sets = (v_exercise->>'target_sets')::INTEGER,
+                target_reps_min = (v_exercise->>'target_reps_min')::INTEGER,
+                target_reps_max = (v_exercise->>'target_reps_max')::INTEGER,
+                target_weight = (v_exercise->>'target_weight')::DECIMAL,
+                weight_unit = v_exercise->>'weight_unit',
+                target_rpe = (v_exercise->>'target_rpe')::DECIMAL
+            WHERE id = (v_exercise->>'id')::UUID AND routine_id = p_routine_id;
+
+            IF NOT FOUND THEN
+                RAISE EXCEPTION 'routine exercise % does not belong to routine %', v_exercise->>'id', p_routine_id;
+            END IF;
+        ELSE
+            INSERT INTO routine_exercises (
+                routine_id, exercise_id, name, notes, "order", target_sets,
+                target_reps_min, target_reps_max, target_weight, weight_unit, target_rpe
+            )
+            VALUES (
+                p_routine_id,
+                (v_exercise->>'exercise_id')::UUID,
+                v_exercise->>'name',
+                COALESCE(v_exercise->>'notes', ''),
+                v_position - 1,
+                (v_exercise->>'target_sets')::INTEGER,
+                (v_exercise->>'target_reps_min')::INTEGER,
+                (v_exercise->>'target_reps_max')::INTEGER,
+                (v_exercise->>'target_weight')::DECIMAL,
+                v_exercise->>'weight_unit',
+                (v_exercise->>'target_rpe')::DECIMAL
+            );
+        END IF;
+    END LOOP;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Creates a routine with its exercises in one transaction and returns its id
+CREATE OR REPLACE FUNCTION create_routine(p_routine JSONB)
+RETURNS UUID AS $$
+DECLARE
+    v_routine_id UUID;
+BEGIN
+    INSERT INTO routines (id, user_id, name, notes, activity_type)
+    VALUES (
+        COALESCE((p_routine->>'id')::UUID, uuid_generate_v4()),
+        (p_routine->>'user_id')::UUID,
+        p_routine->>'name',
+        COALESCE(p_routine->>'notes', ''),
+        COALESCE(p_routine->>'activity_type', 'strength')
+    )
+    RETURNING id INTO v_routine_id;
+
+    PERFORM save_routine_exercises(v_routine_id, p_routine->'exercises');
+
+    RETURN v_routine_id;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Replaces a user's routine and its exercises in one transaction. Returns
+-- false if the routine does not exist for the user.
+CREATE OR REPLACE FUNCTION replace_routine(p_routine_id UUID, p_user_id UUID, p_routine JSONB)
+RETURNS BOOLEAN AS $$
+BEGIN
+    UPDATE routines SET
+        name = p_routine->>'name',
+        notes = COALESCE(p_routine->>'notes', ''),
+        activity_type = COALESCE(p_routine->>'activity_type', 'strength')
+    WHERE id = p_routine_id AND user_id = p_user_id;
+
+    IF NOT FOUND THEN
+        RETURN FALSE;
+    END IF;
+
+    PERFORM save_routine_exercises(p_routine_id, p_routine->'exercises');
+
+    RETURN TRUE;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Exchanges a refresh token for a new one in the same family. The old token
+-- is revoked and linked to its replacement. Presenting a token that was
+-- already rotated means it leaked, so the whole family is revoked. Returns
+-- {"status": "rotated" | "reused" | "revoked" | "expired" | "invalid", ...}.
+CREATE OR REPLACE FUNCTION rotate_refresh_token(p_token_hash TEXT, p_new_token_hash TEXT, p_expires_at TIMESTAMPTZ)
+RETURNS JSONB AS $$
+DECLARE
+    v_token refresh_tokens%ROWTYPE;
+    v_new_id UUID;
+BEGIN
+    SELECT * INTO v_token FROM refresh_tokens WHERE token_hash = p_token_hash FOR UPDATE;
+
+    IF NOT FOUND THEN
+        RETURN jsonb_build_object('status', 'invalid');
+    END IF;
+
+    IF v_token.revoked_at IS NOT NULL THEN
+        IF v_token.replaced_by IS NULL THEN
+            RETURN jsonb_build_object('status', 'revoked');
+        END IF;
+
+        UPDATE refresh_tokens SET revoked_at = NOW(), supabase_refresh_token = NULL, supabase_access_token = NULL
+        WHERE family_id = v_token.family_id AND revoked_at IS NULL;
+
+        RETURN jsonb_build_object('status', 'reused', 'user_id', v_token.user_id, 'family_id', v_token.family_id);
+    END IF;
+
+    IF v_token.expires_at <= NOW() THEN
+        RETURN jsonb_build_object('status', 'expired');
+    END IF;
+
+    INSERT INTO refresh_tokens (user_id, family_id, token_hash, supabase_refresh_token, supabase_access_token, expires_at)
+    VALUES (v_token.user_id, v_token.family_id, p_new_token_hash, v_token.supabase_refresh_token, v_token.supabase_access_token, p_expires_at)
+    RETURNING id INTO v_new_id;
+
+    UPDATE refresh_tokens SET revoked_at = NOW(), replaced_by = v_new_id, supabase_refresh_token = NULL, supabase_access_token = NULL
+    WHERE id = v_token.id;
+
+    RETURN jsonb_build_object(
+        'status', 'rotated',
+        'id', v_new_id,
+        'user_id', v_token.user_id,
+        'family_id', v_token.family_id,
+        'supabase_refresh_token', v_token.supabase_refresh_token
+    );
+END;
+$$ LANGUAGE plpgsql;
+
+-- Revokes a single refresh token. Returns false if it was unknown or
+-- already revoked.
+CREATE OR REPLACE FUNCTION revoke_refresh_token(p_token_hash TEXT)
+RETURNS BOOLEAN AS $$
+BEGIN
+    UPDATE refresh_tokens SET revoked_at = NOW(), supabase_refresh_token = NULL, supabase_access_token = NULL
+    WHERE token_hash = p_token_hash AND revoked_at IS NULL;
+    RETURN FOUND;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Revokes every refresh token of a user, returning how many were revoked
+CREATE OR REPLACE FUNCTION revoke_user_refresh_tokens(p_user_id UUID)
+RETURNS INTEGER AS $$
+DECLARE
+    v_count INTEGER;
+BEGIN
+    UPDATE refresh_tokens SET revoked_at = NOW(), supabase_refresh_token = NULL, supabase_access_token = NULL
+    WHERE user_id = p_user_id AND revoked_at IS NULL;
+    GET DIAGNOSTICS v_count = ROW_COUNT;
+    RETURN v_count;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Sets a user's token cutoff, keeping the stored one when it is later so a
+-- delayed or repeated revocation cannot accept tokens again
+CREATE OR REPLACE FUNCTION revoke_user_tokens(p_user_id UUID, p_revoked_before TIMESTAMPTZ, p_expires_at TIMESTAMPTZ)
+RETURNS VOID AS $$
+    INSERT INTO token_cutoffs (user_id, revoked_before, expires_at)
+    VALUES (p_user_id, p_revoked_before, p_expires_at)
+    ON CONFLICT (user_id) DO UPDATE SET
+        revoked_before = GREATEST(token_cutoffs.revoked_before, EXCLUDED.revoked_before),
+        expires_at = GREATEST(token_cutoffs.expires_at, EXCLUDED.expires_at);
+$$ LANGUAGE sql;
+
+-- Reports whether an access token was revoked individually or by a
+-- "sign out all devices" cutoff
+CREATE OR REPLACE FUNCTION is_token_revoked(p_jti TEXT, p_user_id UUID, p_issued_at TIMESTAMPTZ)
+RETURNS BOOLEAN AS $$
+    SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = p_jti)
+        OR EXISTS (
+            SELECT 1 FROM token_cutoffs
+            WHERE user_id = p_user_id AND revoked_before > p_issued_at
+        );
+$$ LANGUAGE sql STABLE;
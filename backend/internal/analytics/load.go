@@ -0,0 +1,155 @@
+package analytics
+
+import (
+	"math"
+	"time"
+
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+)
+
+// Rolling windows of the acute:chronic workload ratio, in days
+const (
+	AcuteLoadDays   = 7
+	ChronicLoadDays = 28
+)
+
+// Upper bounds of the low, optimal and high ACWR zones
+const (
+	acwrLow     = 0.8
+	acwrOptimal = 1.3
+	acwrHigh    = 1.5
+)
+
+// dayLoad is the training load of one calendar day
+type dayLoad struct {
+	load     float64
+	sessions int
+}
+
+// SessionLoad returns the session RPE load of a workout: overall RPE ×
+// duration in minutes
+func SessionLoad(workout models.Workout) float64 {
+	return workout.OverallRPE * float64(workout.DurationHours*60+workout.DurationMinutes)
+}
+
+// dailyLoads totals session loads by calendar date in loc and returns the
+// date of the first workout
+func dailyLoads(workouts []models.Workout, loc *time.Location) (map[string]dayLoad, models.Date, bool) {
+	days := make(map[string]dayLoad)
+	var first models.Date
+	for i, workout := range workouts {
+		date := WorkoutDate(workout, loc)
+		if i == 0 || date.Before(first.Time) {
+			first = date
+		}
+		day := days[date.String()]
+		day.load += SessionLoad(workout)
+		day.sessions++
+		days[date.String()] = day
+	}
+	return days, first, len(workouts) > 0
+}
+
+// LoadZone classifies an acute:chronic workload ratio
+func LoadZone(acwr float64) string {
+	switch {
+	case acwr < acwrLow:
+		return models.LoadZoneLow
+	case acwr <= acwrOptimal:
+		return models.LoadZoneOptimal
+	case acwr <= acwrHigh:
+		return models.LoadZoneHigh
+	default:
+		return models.LoadZoneVeryHigh
+	}
+}
+
+// DailyLoad returns the training load of every day in [from, to] with the
+// acute load (last 7 days) and chronic load (average week of the last 28
+// days) ending on it. Workouts before from feed the rolling loads, so pass
+// the whole history. The ratio is left out until the first workout is 28
+// days old, since a shorter history understates the chronic load.
+func DailyLoad(workouts []models.Workout, loc *time.Location, from, to models.Date) []models.TrainingLoadPoint {
+	days, first, ok := dailyLoads(workouts, loc)
+	loadOn := func(d time.Time, back int) float64 {
+		return days[models.NewDate(d.AddDate(0, 0, -back)).String()].load
+	}
+
+	points := make([]models.TrainingLoadPoint, 0)
+	for d := from; !d.After(to.Time); d = (models.Date{Time: d.AddDate(0, 0, 1)}) {
+		day := days[d.String()]
+		acute, chronic := 0.0, 0.0
+		for back := 0; back < ChronicLoadDays; back++ {
+			load := loadOn(d.Time, back)
+			if back < AcuteLoadDays {
+				acute += load
+			}
+			chronic += load
+		}
+		chronic /= ChronicLoadDays / AcuteLoadDays
+
+		point := models.TrainingLoadPoint{
+			Date:        d,
+			Load:        round1(day.load),
+			Sessions:    day.sessions,
+			AcuteLoad:   round1(acute),
+			ChronicLoad: round1(chronic),
+		}
+		if ok && chronic > 0 && !d.Before(first.AddDate(0, 0, ChronicLoadDays-1)) {
+			acwr := round2(acute / chronic)
+			point.ACWR = &acwr
+			point.Zone = LoadZone(acwr)
+		}
+		points = append(points, point)
+	}
+	return points
+}
+
+// WeeklyLoad totals training load per ISO week for the days in [from, to],
+// with Foster's monotony (mean daily load / its standard deviation, rest
+// days included) and strain (weekly load × monotony). Every week overlapping
+// the range is present, including empty ones.
+func WeeklyLoad(workouts []models.Workout, loc *time.Location, from, to models.Date) []models.WeeklyLoadPoint {
+	days, _, _ := dailyLoads(workouts, loc)
+
+	weeks := make([]models.WeeklyLoadPoint, 0)
+	for w := WeekStart(from); !w.After(to.Time); w = (models.Date{Time: w.AddDate(0, 0, 7)}) {
+		week := models.WeeklyLoadPoint{WeekStart: w}
+		var loads []float64
+		for i := 0; i < 7; i++ {
+			d := models.Date{Time: w.AddDate(0, 0, i)}
+			if !inDateRange(d, from, to) {
+				continue
+			}
+			day := days[d.String()]
+			loads = append(loads, day.load)
+			week.Load += day.load
+			week.Sessions += day.sessions
+		}
+
+		if len(loads) > 1 {
+			mean := week.Load / float64(len(loads))
+			variance := 0.0
+			for _, load := range loads {
+				variance += (load - mean) * (load - mean)
+			}
+			if sd := math.Sqrt(variance / float64(len(loads))); sd > 0 {
+				monotony := round2(mean / sd)
+				strain := round1(week.Load * mean / sd)
+				week.Monotony, week.Strain = &monotony, &strain
+			}
+		}
+		week.Load = round1(week.Load)
+		weeks = append(weeks, week)
+	}
+	return weeks
+}
+
+// inDateRange reports whether d lies within the inclusive range [from, to]
+func inDateRange(d, from, to models.Date) bool {
+	return !d.Before(from.Time) && !d.After(to.Time)
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,105 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+)
+
+// session is a workout on day d of March 2026 with a load of rpe × minutes
+func session(d int, rpe float64, minutes int) models.Workout {
+	return models.Workout{WorkoutDate: day(d), OverallRPE: rpe, DurationMinutes: minutes}
+}
+
+func date(d int) models.Date {
+	return models.NewDate(day(d))
+}
+
+func TestSessionLoad(t *testing.T) {
+	workout := models.Workout{OverallRPE: 7, DurationHours: 1, DurationMinutes: 15}
+	if got := SessionLoad(workout); got != 525 {
+		t.Errorf("SessionLoad = %v, want 525", got)
+	}
+}
+
+func TestLoadZone(t *testing.T) {
+	tests := []struct {
+		acwr float64
+		want string
+	}{
+		{0.79, models.LoadZoneLow},
+		{0.8, models.LoadZoneOptimal},
+		{1.3, models.LoadZoneOptimal},
+		{1.31, models.LoadZoneHigh},
+		{1.5, models.LoadZoneHigh},
+		{1.51, models.LoadZoneVeryHigh},
+	}
+	for _, tt := range tests {
+		if got := LoadZone(tt.acwr); got != tt.want {
+			t.Errorf("LoadZone(%v) = %q, want %q", tt.acwr, got, tt.want)
+		}
+	}
+}
+
+func TestDailyLoad(t *testing.T) {
+	// A light session on day 1, then a hard one on day 28
+	workouts := []models.Workout{session(28, 8, 50), session(1, 5, 20)}
+
+	points := DailyLoad(workouts, time.UTC, date(26), date(28))
+	if len(points) != 3 {
+		t.Fatalf("got %d points, want 3", len(points))
+	}
+
+	// Day 27 is only 27 days after the first workout
+	if points[1].ACWR != nil {
+		t.Errorf("day 27 has an ACWR of %v before 28 days of history", *points[1].ACWR)
+	}
+
+	last := points[2]
+	if last.Load != 400 || last.Sessions != 1 || last.AcuteLoad != 400 || last.ChronicLoad != 125 {
+		t.Errorf("day 28 = %+v, want load 400, acute 400, chronic 125", last)
+	}
+	if last.ACWR == nil || *last.ACWR != 3.2 || last.Zone != models.LoadZoneVeryHigh {
+		t.Errorf("day 28 ACWR = %v %q, want 3.2 very_high", deref(last.ACWR), last.Zone)
+	}
+}
+
+func TestDailyLoadSteady(t *testing.T) {
+	var workouts []models.Workout
+	for d := 1; d <= 28; d++ {
+		workouts = append(workouts, session(d, 5, 20))
+	}
+
+	points := DailyLoad(workouts, time.UTC, date(28), date(28))
+	if got := points[0]; got.ACWR == nil || *got.ACWR != 1 || got.Zone != models.LoadZoneOptimal {
+		t.Errorf("steady training ACWR = %v %q, want 1 optimal", deref(got.ACWR), got.Zone)
+	}
+}
+
+func TestWeeklyLoad(t *testing.T) {
+	// March 2 2026 is a Monday
+	workouts := []models.Workout{session(2, 5, 20)}
+	var steady []models.Workout
+	for d := 2; d <= 8; d++ {
+		steady = append(steady, session(d, 5, 20))
+	}
+
+	weeks := WeeklyLoad(workouts, time.UTC, date(2), date(15))
+	if len(weeks) != 2 {
+		t.Fatalf("got %d weeks, want 2", len(weeks))
+	}
+	week := weeks[0]
+	if week.Load != 100 || week.Sessions != 1 || deref(week.Monotony) != 0.41 || deref(week.Strain) != 40.8 {
+		t.Errorf("week = load %v, sessions %d, monotony %v, strain %v; want 100, 1, 0.41, 40.8",
+			week.Load, week.Sessions, deref(week.Monotony), deref(week.Strain))
+	}
+	if weeks[1].Load != 0 || weeks[1].Monotony != nil {
+		t.Errorf("empty week = %+v", weeks[1])
+	}
+
+	// Identical daily loads have no standard deviation
+	if week := WeeklyLoad(steady, time.UTC, date(2), date(8))[0]; week.Monotony != nil || week.Strain != nil {
+		t.Errorf("steady week monotony = %v, want nil", deref(week.Monotony))
+	}
+}
@@ -0,0 +1,106 @@
+package analytics
+
+import (
+	"sort"
+	"time"
+
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+)
+
+// hardSetMinRPE is the lowest RPE of a hard set; sets logged further from
+// failure do not count
+const hardSetMinRPE = 6
+
+// MuscleMapping returns the primary and secondary muscles trained by a
+// logged exercise, or false if it is not mapped
+type MuscleMapping func(models.WorkoutExercise) (primary, secondary []string, ok bool)
+
+// IsHardSet reports whether a set counts towards volume: any set but a
+// warm-up with reps, distance or duration, unless its RPE shows it was far
+// from failure
+func IsHardSet(set models.WorkoutSet) bool {
+	if set.SetType == models.SetTypeWarmup {
+		return false
+	}
+	if set.Reps == nil && set.DistanceM == nil && set.DurationSeconds == nil {
+		return false
+	}
+	return set.RPE == nil || *set.RPE >= hardSetMinRPE
+}
+
+// WeeklyMuscleVolume counts hard sets and tonnage per muscle group per ISO
+// week for workouts in [from, to]. Primary muscles are credited with each
+// set in full and secondary muscles with secondaryWeight of it. Every week
+// overlapping the range is present, and lists every muscle group. It also
+// returns the names of the exercises muscleOf could not map, sorted.
+func WeeklyMuscleVolume(workouts []models.Workout, loc *time.Location, from, to models.Date, muscleOf MuscleMapping, secondaryWeight float64) ([]models.WeeklyMuscleVolume, []string) {
+	muscleIndex := make(map[string]int, len(models.MuscleGroups))
+	for i, muscle := range models.MuscleGroups {
+		muscleIndex[muscle] = i
+	}
+
+	weeks := make([]models.WeeklyMuscleVolume, 0)
+	byWeek := make(map[string]int)
+	for w := WeekStart(from); !w.After(to.Time); w = (models.Date{Time: w.AddDate(0, 0, 7)}) {
+		byWeek[w.String()] = len(weeks)
+		week := models.WeeklyMuscleVolume{WeekStart: w, Muscles: make([]models.MuscleVolume, len(models.MuscleGroups))}
+		for i, muscle := range models.MuscleGroups {
+			week.Muscles[i].Muscle = muscle
+		}
+		weeks = append(weeks, week)
+	}
+
+	unmapped := make(map[string]string)
+	for _, workout := range workouts {
+		date := WorkoutDate(workout, loc)
+		if !inDateRange(date, from, to) {
+			continue
+		}
+		week := &weeks[byWeek[WeekStart(date).String()]]
+
+		for _, exercise := range workout.Exercises {
+			sets, tonnage := 0, 0.0
+			for _, set := range exercise.Sets {
+				if IsHardSet(set) {
+					sets++
+					tonnage += SetVolume(set)
+				}
+			}
+			if sets == 0 {
+				continue
+			}
+
+			primary, secondary, ok := muscleOf(exercise)
+			if !ok {
+				week.UnmappedSets += sets
+				unmapped[NameKey(exercise.Name)] = exercise.Name
+				continue
+			}
+			credit := func(muscles []string, weight float64) {
+				for _, muscle := range muscles {
+					if i, ok := muscleIndex[muscle]; ok {
+						week.Muscles[i].HardSets += float64(sets) * weight
+						week.Muscles[i].TonnageKg += tonnage * weight
+					}
+				}
+			}
+			credit(primary, 1)
+			credit(secondary, secondaryWeight)
+		}
+	}
+
+	for w := range weeks {
+		for i := range weeks[w].Muscles {
+			muscle := &weeks[w].Muscles[i]
+			muscle.HardSets = round2(muscle.HardSets)
+			muscle.TonnageKg = round1(muscle.TonnageKg)
+		}
+	}
+
+	names := make([]string, 0, len(unmapped))
+	for _, name := range unmapped {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return weeks, names
+}
@@ -0,0 +1,76 @@
+package analytics
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+)
+
+func TestIsHardSet(t *testing.T) {
+	warmup := kgSet(60, 10)
+	warmup.SetType = models.SetTypeWarmup
+
+	tests := []struct {
+		name string
+		set  models.WorkoutSet
+		want bool
+	}{
+		{"working set", kgSet(100, 5), true},
+		{"warm-up", warmup, false},
+		{"at RPE 6", withRPE(kgSet(100, 5), 6), true},
+		{"below RPE 6", withRPE(kgSet(100, 5), 5.5), false},
+		{"cardio interval", models.WorkoutSet{DurationSeconds: ptr(60)}, true},
+		{"nothing logged", models.WorkoutSet{Weight: ptr(100.0)}, false},
+	}
+	for _, tt := range tests {
+		if got := IsHardSet(tt.set); got != tt.want {
+			t.Errorf("%s: IsHardSet = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWeeklyMuscleVolume(t *testing.T) {
+	warmup := kgSet(60, 10)
+	warmup.SetType = models.SetTypeWarmup
+	workouts := []models.Workout{
+		{WorkoutDate: day(3), Exercises: []models.WorkoutExercise{
+			{Name: "Bench Press", Sets: []models.WorkoutSet{warmup, kgSet(100, 5), kgSet(100, 5), withRPE(kgSet(100, 5), 5)}},
+			{Name: "Mystery Machine", Sets: []models.WorkoutSet{kgSet(50, 10)}},
+		}},
+		// Outside the range
+		{WorkoutDate: day(20), Exercises: []models.WorkoutExercise{
+			{Name: "Bench Press", Sets: []models.WorkoutSet{kgSet(100, 5)}},
+		}},
+	}
+	muscleOf := func(e models.WorkoutExercise) ([]string, []string, bool) {
+		if e.Name == "Bench Press" {
+			return []string{"chest"}, []string{"triceps", "front_delts"}, true
+		}
+		return nil, nil, false
+	}
+
+	weeks, unmapped := WeeklyMuscleVolume(workouts, time.UTC, date(2), date(8), muscleOf, 0.5)
+	if len(weeks) != 1 {
+		t.Fatalf("got %d weeks, want 1", len(weeks))
+	}
+	if !reflect.DeepEqual(unmapped, []string{"Mystery Machine"}) || weeks[0].UnmappedSets != 1 {
+		t.Errorf("unmapped = %v with %d sets, want [Mystery Machine] with 1", unmapped, weeks[0].UnmappedSets)
+	}
+
+	want := map[string]models.MuscleVolume{
+		"chest":       {Muscle: "chest", HardSets: 2, TonnageKg: 1000},
+		"triceps":     {Muscle: "triceps", HardSets: 1, TonnageKg: 500},
+		"front_delts": {Muscle: "front_delts", HardSets: 1, TonnageKg: 500},
+		"quads":       {Muscle: "quads"},
+	}
+	if got := len(weeks[0].Muscles); got != len(models.MuscleGroups) {
+		t.Errorf("week lists %d muscles, want every muscle group", got)
+	}
+	for _, muscle := range weeks[0].Muscles {
+		if w, ok := want[muscle.Muscle]; ok && muscle != w {
+			t.Errorf("%s = %+v, want %+v", muscle.Muscle, muscle, w)
+		}
+	}
+}
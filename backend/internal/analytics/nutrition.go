@@ -0,0 +1,44 @@
+package analytics
+
+import (
+	"math"
+
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+)
+
+// DailyNutrition totals logs per day, including empty days in [from, to]
+func DailyNutrition(logs []models.FoodLog, from, to models.Date) []models.DailyNutrition {
+	byDate := make(map[string]*models.DailyNutrition)
+	days := make([]models.DailyNutrition, 0)
+	for d := from.Time; !d.After(to.Time); d = d.AddDate(0, 0, 1) {
+		days = append(days, models.DailyNutrition{Date: models.Date{Time: d}})
+	}
+	for i := range days {
+		byDate[days[i].Date.String()] = &days[i]
+	}
+
+	for _, foodLog := range logs {
+		day, ok := byDate[foodLog.LogDate.String()]
+		if !ok {
+			continue
+		}
+		day.Calories += foodLog.CaloriesEst
+		if foodLog.ProteinG != nil {
+			day.ProteinG += *foodLog.ProteinG
+		}
+		if foodLog.FatG != nil {
+			day.FatG += *foodLog.FatG
+		}
+		if foodLog.CarbsG != nil {
+			day.CarbsG += *foodLog.CarbsG
+		}
+		day.Entries++
+	}
+
+	for i := range days {
+		days[i].ProteinG = math.Round(days[i].ProteinG*10) / 10
+		days[i].FatG = math.Round(days[i].FatG*10) / 10
+		days[i].CarbsG = math.Round(days[i].CarbsG*10) / 10
+	}
+	return days
+}
@@ -0,0 +1,232 @@
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+)
+
+// deloadFactor is the share of the weight kept after missing the target reps
+// twice in a row with linear progression
+const deloadFactor = 0.9
+
+// defaultTargetRPE is used by the rpe scheme when no target is set
+const defaultTargetRPE = 8.0
+
+// loadTolerance is how close two loads in kilograms must be to count as the
+// same weight, since pound loads do not convert exactly
+const loadTolerance = 0.01
+
+// workingWeight is the heaviest weight of a performance and the sets done at it
+type workingWeight struct {
+	loadKg float64             // 0 when the exercise was logged without weight
+	sets   []models.WorkoutSet // Sets at loadKg with reps, in logged order
+}
+
+// topSets returns the working weight of a performance, or false if none of
+// its sets has reps
+func topSets(p Performance) (workingWeight, bool) {
+	var w workingWeight
+	for _, set := range p.Sets {
+		if set.Reps == nil || *set.Reps < 1 {
+			continue
+		}
+		load, _ := SetLoadKg(set)
+		switch {
+		case len(w.sets) == 0 || load > w.loadKg+loadTolerance:
+			w = workingWeight{loadKg: load, sets: []models.WorkoutSet{set}}
+		case math.Abs(load-w.loadKg) <= loadTolerance:
+			w.sets = append(w.sets, set)
+		}
+	}
+	return w, len(w.sets) > 0
+}
+
+// fewestReps returns the fewest reps done in a set at the working weight
+func (w workingWeight) fewestReps() int {
+	fewest := *w.sets[0].Reps
+	for _, set := range w.sets[1:] {
+		fewest = min(fewest, *set.Reps)
+	}
+	return fewest
+}
+
+// mostReps returns the most reps done in a set at the working weight
+func (w workingWeight) mostReps() int {
+	most := 0
+	for _, set := range w.sets {
+		most = max(most, *set.Reps)
+	}
+	return most
+}
+
+// SuggestNext suggests the target of the next session of an exercise from
+// its history, oldest first, following settings. It returns nil if no set
+// was logged with reps. Sets at the heaviest weight of the last session
+// decide the progression; exercises logged without weight progress by reps.
+// The rpe scheme estimates one-rep maxes with formula.
+func SuggestNext(history []Performance, settings models.ProgressionSettings, formula string) *models.ProgressionTarget {
+	var last, previous workingWeight
+	found, hasPrevious := false, false
+	for i := len(history) - 1; i >= 0 && !hasPrevious; i-- {
+		w, ok := topSets(history[i])
+		switch {
+		case !ok:
+		case !found:
+			last, found = w, true
+		default:
+			previous, hasPrevious = w, true
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	target := &models.ProgressionTarget{Sets: len(last.sets)}
+	if last.loadKg == 0 {
+		target.Reps = last.mostReps() + 1
+		target.Reason = fmt.Sprintf("Logged without weight: aim for %d reps, one more than last time", target.Reps)
+		return target
+	}
+
+	// Weights converted from the other unit are rounded to the increment
+	unit, increment := settings.WeightUnit, settings.Increment
+	weight := fromKg(last.loadKg, unit)
+	if last.sets[0].WeightUnit != unit {
+		weight = roundToStep(weight, increment)
+	}
+	target.WeightUnit = unit
+	setWeight := func(w float64) {
+		w = round2(w)
+		target.Weight = &w
+	}
+
+	switch settings.Scheme {
+	case models.SchemeLinear:
+		reps := settings.RepsMin
+		if reps == 0 {
+			reps = last.mostReps()
+		}
+		target.Reps = reps
+		switch {
+		case last.fewestReps() >= reps:
+			setWeight(weight + increment)
+			target.Reason = fmt.Sprintf("Every set reached %d reps at %s: add %s", reps, formatWeight(weight, unit), formatWeight(increment, unit))
+		case hasPrevious && math.Abs(previous.loadKg-last.loadKg) <= loadTolerance && previous.fewestReps() < reps:
+			setWeight(roundToStep(weight*deloadFactor, increment))
+			target.Reason = fmt.Sprintf("Missed %d reps at %s twice in a row: deload by 10%%", reps, formatWeight(weight, unit))
+		default:
+			setWeight(weight)
+			target.Reason = fmt.Sprintf("Missed %d reps at %s: repeat the weight", reps, formatWeight(weight, unit))
+		}
+
+	case models.SchemeRPE:
+		targetRPE := defaultTargetRPE
+		if settings.TargetRPE != nil {
+			targetRPE = *settings.TargetRPE
+		}
+		reps := settings.RepsMin
+		if reps == 0 {
+			reps = last.mostReps()
+		}
+		target.Reps = reps
+		target.RPE = &targetRPE
+
+		// The last set at the working weight with an RPE shows how hard
+		// the weight was once fatigue set in
+		var rated *models.WorkoutSet
+		for i := range last.sets {
+			if last.sets[i].RPE != nil {
+				rated = &last.sets[i]
+			}
+		}
+		var maxKg float64
+		ok := rated != nil
+		if ok {
+			maxKg, ok = estimateMax(formula, last.loadKg, float64(*rated.Reps)+10-*rated.RPE)
+		}
+		next, nextOK := weightForReps(formula, maxKg, float64(reps)+10-targetRPE)
+		if !ok || !nextOK {
+			setWeight(weight)
+			target.Reason = fmt.Sprintf("No usable RPE was logged at %s last time: repeat the weight and log RPE to autoregulate", formatWeight(weight, unit))
+			break
+		}
+		setWeight(roundToStep(fromKg(next, unit), increment))
+		target.Reason = fmt.Sprintf("%s × %d at RPE %s estimates a max of %s: %s × %d should be about RPE %s",
+			formatWeight(weight, unit), *rated.Reps, formatNumber(*rated.RPE), formatWeight(round2(fromKg(maxKg, unit)), unit),
+			formatWeight(*target.Weight, unit), reps, formatNumber(targetRPE))
+
+	default:
+		low, high := settings.RepsMin, settings.RepsMax
+		fewest := last.fewestReps()
+		if fewest >= high {
+			setWeight(weight + increment)
+			target.Reps = low
+			target.Reason = fmt.Sprintf("Every set reached the top of the %d-%d rep range at %s: add %s", low, high, formatWeight(weight, unit), formatWeight(increment, unit))
+			break
+		}
+		setWeight(weight)
+		target.Reps = min(max(fewest+1, low), high)
+		target.Reason = fmt.Sprintf("Add reps at %s until every set reaches %d", formatWeight(weight, unit), high)
+	}
+	return target
+}
+
+// estimateMax estimates a one-rep max from weightKg lifted for repsToFailure,
+// which includes the reps left in reserve and may be fractional
+func estimateMax(formula string, weightKg, repsToFailure float64) (float64, bool) {
+	if repsToFailure <= 1 {
+		return weightKg, true
+	}
+	if formula == models.FormulaBrzycki {
+		if repsToFailure >= 37 {
+			return 0, false
+		}
+		return weightKg * 36 / (37 - repsToFailure), true
+	}
+	return weightKg * (1 + repsToFailure/30), true
+}
+
+// weightForReps inverts estimateMax: the weight in kilograms that can be
+// lifted for repsToFailure given a one-rep max
+func weightForReps(formula string, maxKg, repsToFailure float64) (float64, bool) {
+	if maxKg <= 0 {
+		return 0, false
+	}
+	if repsToFailure <= 1 {
+		return maxKg, true
+	}
+	if formula == models.FormulaBrzycki {
+		if repsToFailure >= 37 {
+			return 0, false
+		}
+		return maxKg * (37 - repsToFailure) / 36, true
+	}
+	return maxKg / (1 + repsToFailure/30), true
+}
+
+// fromKg converts kilograms to unit
+func fromKg(kg float64, unit string) float64 {
+	if unit == models.WeightUnitLb {
+		return kg / poundsToKg
+	}
+	return kg
+}
+
+// roundToStep rounds a weight to the nearest multiple of step
+func roundToStep(weight, step float64) float64 {
+	if step <= 0 {
+		return weight
+	}
+	return max(step, math.Round(weight/step)*step)
+}
+
+func formatWeight(weight float64, unit string) string {
+	return formatNumber(weight) + " " + unit
+}
+
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(round2(v), 'f', -1, 64)
+}
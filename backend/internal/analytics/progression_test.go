@@ -0,0 +1,130 @@
+package analytics
+
+import (
+	"testing"
+
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+)
+
+// performance is one session of the given sets
+func performance(d int, sets ...models.WorkoutSet) Performance {
+	return Performance{WorkoutDate: day(d), Sets: sets}
+}
+
+// withRPE returns set logged at rpe
+func withRPE(set models.WorkoutSet, rpe float64) models.WorkoutSet {
+	set.RPE = &rpe
+	return set
+}
+
+func TestSuggestNext(t *testing.T) {
+	kg := func(scheme string, repsMin, repsMax int) models.ProgressionSettings {
+		return models.ProgressionSettings{Scheme: scheme, WeightUnit: models.WeightUnitKg, Increment: 2.5, RepsMin: repsMin, RepsMax: repsMax}
+	}
+	rpe := kg(models.SchemeRPE, 5, 0)
+	rpe.TargetRPE = ptr(8.0)
+	lb := kg(models.SchemeLinear, 5, 0)
+	lb.WeightUnit, lb.Increment = models.WeightUnitLb, 5
+	bodyweight := models.WorkoutSet{Reps: ptr(12)}
+
+	tests := []struct {
+		name       string
+		history    []Performance
+		settings   models.ProgressionSettings
+		wantWeight *float64
+		wantReps   int
+		wantSets   int
+	}{
+		{
+			name:       "linear: every set hit the target",
+			history:    []Performance{performance(1, kgSet(100, 5), kgSet(100, 5), kgSet(100, 5))},
+			settings:   kg(models.SchemeLinear, 5, 0),
+			wantWeight: ptr(102.5), wantReps: 5, wantSets: 3,
+		},
+		{
+			name:       "linear: a missed set repeats the weight",
+			history:    []Performance{performance(1, kgSet(100, 5), kgSet(100, 5), kgSet(100, 4))},
+			settings:   kg(models.SchemeLinear, 5, 0),
+			wantWeight: ptr(100.0), wantReps: 5, wantSets: 3,
+		},
+		{
+			name: "linear: missing twice in a row deloads",
+			history: []Performance{
+				performance(1, kgSet(100, 5), kgSet(100, 3)),
+				performance(3, kgSet(100, 4), kgSet(100, 4)),
+			},
+			settings:   kg(models.SchemeLinear, 5, 0),
+			wantWeight: ptr(90.0), wantReps: 5, wantSets: 2,
+		},
+		{
+			name:       "linear: lighter back-off sets are ignored",
+			history:    []Performance{performance(1, kgSet(100, 5), kgSet(80, 3))},
+			settings:   kg(models.SchemeLinear, 5, 0),
+			wantWeight: ptr(102.5), wantReps: 5, wantSets: 1,
+		},
+		{
+			name:       "double progression: top of the range adds weight",
+			history:    []Performance{performance(1, kgSet(60, 12), kgSet(60, 12))},
+			settings:   kg(models.SchemeDoubleProgression, 8, 12),
+			wantWeight: ptr(62.5), wantReps: 8, wantSets: 2,
+		},
+		{
+			name:       "double progression: otherwise add a rep",
+			history:    []Performance{performance(1, kgSet(60, 10), kgSet(60, 9))},
+			settings:   kg(models.SchemeDoubleProgression, 8, 12),
+			wantWeight: ptr(60.0), wantReps: 10, wantSets: 2,
+		},
+		{
+			name:       "rpe: an easier set than targeted adds weight",
+			history:    []Performance{performance(1, withRPE(kgSet(100, 5), 7))},
+			settings:   rpe,
+			wantWeight: ptr(102.5), wantReps: 5, wantSets: 1,
+		},
+		{
+			name:       "rpe: without a logged RPE the weight repeats",
+			history:    []Performance{performance(1, kgSet(100, 5))},
+			settings:   rpe,
+			wantWeight: ptr(100.0), wantReps: 5, wantSets: 1,
+		},
+		{
+			name:       "converted weights are rounded to the increment",
+			history:    []Performance{performance(1, kgSet(100, 5))},
+			settings:   lb,
+			wantWeight: ptr(225.0), wantReps: 5, wantSets: 1,
+		},
+		{
+			name:     "bodyweight exercises progress by reps",
+			history:  []Performance{performance(1, bodyweight, bodyweight)},
+			settings: kg(models.SchemeLinear, 5, 0),
+			wantReps: 13, wantSets: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SuggestNext(tt.history, tt.settings, models.FormulaEpley)
+			if got == nil {
+				t.Fatal("SuggestNext = nil")
+			}
+			if (got.Weight == nil) != (tt.wantWeight == nil) || got.Weight != nil && *got.Weight != *tt.wantWeight {
+				t.Errorf("weight = %v, want %v (%s)", deref(got.Weight), deref(tt.wantWeight), got.Reason)
+			}
+			if got.Reps != tt.wantReps || got.Sets != tt.wantSets {
+				t.Errorf("%d × %d, want %d × %d (%s)", got.Sets, got.Reps, tt.wantSets, tt.wantReps, got.Reason)
+			}
+		})
+	}
+}
+
+func TestSuggestNextWithoutReps(t *testing.T) {
+	history := []Performance{performance(1, models.WorkoutSet{Weight: ptr(100.0)})}
+	if got := SuggestNext(history, models.ProgressionSettings{Scheme: models.SchemeLinear}, models.FormulaEpley); got != nil {
+		t.Errorf("SuggestNext = %+v, want nil", got)
+	}
+}
+
+func deref(v *float64) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
@@ -0,0 +1,253 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+)
+
+// OneRepMax estimates the heaviest single repetition possible given a set of
+// reps at weightKg, or returns false if the set cannot be used. A single rep
+// is its own one-rep max. Brzycki is undefined from 37 reps.
+func OneRepMax(formula string, weightKg float64, reps int) (float64, bool) {
+	if weightKg <= 0 || reps < 1 {
+		return 0, false
+	}
+	if reps == 1 {
+		return weightKg, true
+	}
+
+	switch formula {
+	case models.FormulaBrzycki:
+		if reps >= 37 {
+			return 0, false
+		}
+		return weightKg * 36 / float64(37-reps), true
+	default:
+		return weightKg * (1 + float64(reps)/30), true
+	}
+}
+
+// NameKey normalizes an exercise name so that names differing only in case
+// or spacing refer to the same exercise
+func NameKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// Performance is the work done on one exercise in one workout
+type Performance struct {
+	WorkoutID   string
+	WorkoutDate time.Time
+	Sets        []models.WorkoutSet // Non-warm-up sets, in logged order
+}
+
+// ExerciseHistory returns the performances in workouts of the exercises for
+// which keyOf returns key, oldest first. Sets of an exercise logged more than
+// once in a workout are combined.
+func ExerciseHistory(workouts []models.Workout, key string, keyOf func(models.WorkoutExercise) string) []Performance {
+	var history []Performance
+	for _, workout := range workouts {
+		var sets []models.WorkoutSet
+		found := false
+		for _, exercise := range workout.Exercises {
+			if keyOf(exercise) != key {
+				continue
+			}
+			found = true
+			for _, set := range exercise.Sets {
+				if set.SetType != models.SetTypeWarmup {
+					sets = append(sets, set)
+				}
+			}
+		}
+		if found {
+			history = append(history, Performance{WorkoutID: workout.ID, WorkoutDate: workout.WorkoutDate, Sets: sets})
+		}
+	}
+
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].WorkoutDate.Before(history[j].WorkoutDate)
+	})
+	return history
+}
+
+// Summarize returns the best values of a performance
+func Summarize(p Performance, formula string) models.ExerciseSession {
+	session := models.ExerciseSession{
+		WorkoutID:   p.WorkoutID,
+		WorkoutDate: p.WorkoutDate,
+		Sets:        len(p.Sets),
+	}
+	for _, set := range p.Sets {
+		session.VolumeKg += SetVolume(set)
+
+		load, ok := SetLoadKg(set)
+		if !ok || set.Reps == nil {
+			continue
+		}
+		load = round2(load)
+		if session.TopWeightKg == nil || load > *session.TopWeightKg {
+			session.TopWeightKg = &load
+		}
+		if e1rm, ok := OneRepMax(formula, load, *set.Reps); ok {
+			e1rm = round2(e1rm)
+			if session.BestE1RMKg == nil || e1rm > *session.BestE1RMKg {
+				session.BestE1RMKg = &e1rm
+			}
+		}
+	}
+	session.VolumeKg = round2(session.VolumeKg)
+	return session
+}
+
+// Records keeps the personal records of one exercise as performances are
+// added in order
+type Records struct {
+	exercise string
+	formula  string
+	heaviest *models.PersonalRecord
+	e1rm     *models.PersonalRecord
+	volume   *models.PersonalRecord
+	reps     map[float64]*models.PersonalRecord // Most reps by weight in kg
+}
+
+// NewRecords starts tracking the records of exercise, estimating one-rep
+// maxes with formula
+func NewRecords(exercise, formula string) *Records {
+	return &Records{
+		exercise: exercise,
+		formula:  formula,
+		reps:     make(map[float64]*models.PersonalRecord),
+	}
+}
+
+// Add records a performance and returns the records it broke. Setting the
+// first record of a type does not count as breaking one. A most reps record
+// is broken by more reps than were ever done at the same weight or heavier.
+func (r *Records) Add(p Performance) []models.PersonalRecord {
+	var broken []models.PersonalRecord
+	update := func(current **models.PersonalRecord, candidate *models.PersonalRecord) {
+		if candidate == nil || (*current != nil && candidate.Value <= (*current).Value) {
+			return
+		}
+		if *current != nil {
+			previous := (*current).Value
+			candidate.Previous = &previous
+			broken = append(broken, *candidate)
+		}
+		*current = candidate
+	}
+
+	var heaviest, e1rm *models.PersonalRecord
+	reps := make(map[float64]*models.PersonalRecord)
+	volume := 0.0
+	for _, set := range p.Sets {
+		volume += SetVolume(set)
+
+		load, ok := SetLoadKg(set)
+		if !ok || set.Reps == nil || *set.Reps < 1 {
+			continue
+		}
+		load = round2(load)
+
+		if load > 0 && (heaviest == nil || load > heaviest.Value) {
+			heaviest = r.record(models.RecordHeaviestWeight, load, p, load, *set.Reps)
+		}
+		if value, ok := OneRepMax(r.formula, load, *set.Reps); ok && (e1rm == nil || round2(value) > e1rm.Value) {
+			e1rm = r.record(models.RecordBestE1RM, round2(value), p, load, *set.Reps)
+		}
+		if best := reps[load]; best == nil || float64(*set.Reps) > best.Value {
+			reps[load] = r.record(models.RecordMostReps, float64(*set.Reps), p, load, *set.Reps)
+		}
+	}
+
+	update(&r.heaviest, heaviest)
+	update(&r.e1rm, e1rm)
+
+	// Heaviest first, so a set is compared with the heavier sets of the same
+	// workout too
+	loads := make([]float64, 0, len(reps))
+	for load := range reps {
+		loads = append(loads, load)
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(loads)))
+	for _, load := range loads {
+		candidate := reps[load]
+		if best := r.mostReps(load); best != nil && candidate.Value > best.Value {
+			previous := best.Value
+			candidate.Previous = &previous
+			broken = append(broken, *candidate)
+		}
+		if current := r.reps[load]; current == nil || candidate.Value > current.Value {
+			r.reps[load] = candidate
+		}
+	}
+	if volume > 0 {
+		update(&r.volume, &models.PersonalRecord{
+			Exercise:    r.exercise,
+			Type:        models.RecordBestVolume,
+			Value:       round2(volume),
+			WorkoutID:   p.WorkoutID,
+			WorkoutDate: p.WorkoutDate,
+		})
+	}
+	return broken
+}
+
+// List returns the current records: heaviest weight, best e1RM, best volume,
+// then the most reps at each weight from the heaviest down
+func (r *Records) List() []models.PersonalRecord {
+	records := make([]models.PersonalRecord, 0, 3+len(r.reps))
+	for _, record := range []*models.PersonalRecord{r.heaviest, r.e1rm, r.volume} {
+		if record != nil {
+			records = append(records, *record)
+		}
+	}
+
+	// Skip weights where a heavier set had at least as many reps
+	reps := make([]models.PersonalRecord, 0, len(r.reps))
+	for load, record := range r.reps {
+		if r.mostReps(load) == record {
+			reps = append(reps, *record)
+		}
+	}
+	sort.Slice(reps, func(i, j int) bool {
+		return *reps[i].WeightKg > *reps[j].WeightKg
+	})
+	return append(records, reps...)
+}
+
+// mostReps returns the record with the most reps at load or heavier,
+// preferring the heaviest on ties
+func (r *Records) mostReps(load float64) *models.PersonalRecord {
+	var best *models.PersonalRecord
+	for weight, record := range r.reps {
+		if weight < load || (best != nil && (record.Value < best.Value ||
+			record.Value == best.Value && weight < *best.WeightKg)) {
+			continue
+		}
+		best = record
+	}
+	return best
+}
+
+// record builds a record set by a set of reps at load in p
+func (r *Records) record(recordType string, value float64, p Performance, load float64, reps int) *models.PersonalRecord {
+	return &models.PersonalRecord{
+		Exercise:    r.exercise,
+		Type:        recordType,
+		Value:       value,
+		WeightKg:    &load,
+		Reps:        &reps,
+		WorkoutID:   p.WorkoutID,
+		WorkoutDate: p.WorkoutDate,
+	}
+}
+
+// round2 rounds to two decimals, so loads converted from pounds compare equal
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,138 @@
+package analytics
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+// kgSet is a working set of reps at weight kilograms
+func kgSet(weight float64, reps int) models.WorkoutSet {
+	return models.WorkoutSet{Weight: &weight, WeightUnit: models.WeightUnitKg, Reps: &reps, SetType: models.SetTypeWorking}
+}
+
+// day returns noon UTC of the given day of March 2026
+func day(d int) time.Time {
+	return time.Date(2026, 3, d, 12, 0, 0, 0, time.UTC)
+}
+
+func TestOneRepMax(t *testing.T) {
+	tests := []struct {
+		name    string
+		formula string
+		weight  float64
+		reps    int
+		want    float64
+		ok      bool
+	}{
+		{"single rep", models.FormulaEpley, 100, 1, 100, true},
+		{"epley", models.FormulaEpley, 100, 5, 116.67, true},
+		{"brzycki", models.FormulaBrzycki, 100, 5, 112.5, true},
+		{"brzycki at 37 reps", models.FormulaBrzycki, 100, 37, 0, false},
+		{"unknown formula is epley", "", 90, 10, 120, true},
+		{"no weight", models.FormulaEpley, 0, 5, 0, false},
+		{"no reps", models.FormulaEpley, 100, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := OneRepMax(tt.formula, tt.weight, tt.reps)
+			if ok != tt.ok || round2(got) != tt.want {
+				t.Errorf("OneRepMax = %v, %v; want %v, %v", round2(got), ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestSetLoadKg(t *testing.T) {
+	lb := models.WorkoutSet{Weight: ptr(225.0), WeightUnit: models.WeightUnitLb}
+	if got, ok := SetLoadKg(lb); !ok || math.Abs(got-102.06) > 0.01 {
+		t.Errorf("SetLoadKg(225 lb) = %v, %v; want 102.06", got, ok)
+	}
+	if _, ok := SetLoadKg(models.WorkoutSet{Reps: ptr(10)}); ok {
+		t.Error("SetLoadKg of a set without weight is ok")
+	}
+	if got := SetVolume(kgSet(100, 5)); got != 500 {
+		t.Errorf("SetVolume = %v, want 500", got)
+	}
+}
+
+func TestExerciseHistory(t *testing.T) {
+	warmup := kgSet(60, 10)
+	warmup.SetType = models.SetTypeWarmup
+	workouts := []models.Workout{
+		{ID: "later", WorkoutDate: day(5), Exercises: []models.WorkoutExercise{
+			{Name: "Bench Press", Sets: []models.WorkoutSet{warmup, kgSet(100, 5)}},
+			{Name: "Squat", Sets: []models.WorkoutSet{kgSet(140, 5)}},
+			{Name: "bench  press", Sets: []models.WorkoutSet{kgSet(90, 8)}},
+		}},
+		{ID: "earlier", WorkoutDate: day(1), Exercises: []models.WorkoutExercise{
+			{Name: "Bench Press", Sets: []models.WorkoutSet{kgSet(97.5, 5)}},
+		}},
+		{ID: "other", WorkoutDate: day(3), Exercises: []models.WorkoutExercise{
+			{Name: "Deadlift", Sets: []models.WorkoutSet{kgSet(180, 3)}},
+		}},
+	}
+	keyOf := func(e models.WorkoutExercise) string { return NameKey(e.Name) }
+
+	history := ExerciseHistory(workouts, NameKey("Bench Press"), keyOf)
+	if len(history) != 2 || history[0].WorkoutID != "earlier" || history[1].WorkoutID != "later" {
+		t.Fatalf("history = %+v, want earlier then later", history)
+	}
+	if got := len(history[1].Sets); got != 2 {
+		t.Errorf("later performance has %d sets, want the 2 non-warm-up sets of both entries", got)
+	}
+}
+
+func TestRecords(t *testing.T) {
+	performances := []struct {
+		sets []models.WorkoutSet
+		want []string
+	}{
+		{[]models.WorkoutSet{kgSet(100, 5)}, nil},
+		{[]models.WorkoutSet{kgSet(105, 3)}, []string{models.RecordHeaviestWeight}},
+		{[]models.WorkoutSet{kgSet(100, 6)}, []string{models.RecordBestE1RM, models.RecordMostReps, models.RecordBestVolume}},
+		{[]models.WorkoutSet{kgSet(80, 6)}, nil}, // 6 reps were already done heavier
+	}
+
+	records := NewRecords("Bench Press", models.FormulaEpley)
+	for i, p := range performances {
+		broken := records.Add(Performance{WorkoutID: "w", WorkoutDate: day(i + 1), Sets: p.sets})
+		var got []string
+		for _, record := range broken {
+			got = append(got, record.Type)
+		}
+		if !reflect.DeepEqual(got, p.want) {
+			t.Errorf("performance %d broke %v, want %v", i+1, got, p.want)
+		}
+	}
+
+	list := records.List()
+	want := map[string]float64{
+		models.RecordHeaviestWeight: 105,
+		models.RecordBestE1RM:       120,
+		models.RecordBestVolume:     600,
+	}
+	for _, record := range list {
+		if value, ok := want[record.Type]; ok && record.Value != value {
+			t.Errorf("%s = %v, want %v", record.Type, record.Value, value)
+		}
+	}
+	// Most reps at 105 kg (3) and 100 kg (6); 80 kg is shadowed by 100 kg
+	if got := len(list); got != 5 {
+		t.Errorf("List has %d records, want 5: %+v", got, list)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	lb := models.WorkoutSet{Weight: ptr(225.0), WeightUnit: models.WeightUnitLb, Reps: ptr(3)}
+	session := Summarize(Performance{Sets: []models.WorkoutSet{kgSet(100, 5), lb}}, models.FormulaEpley)
+
+	if session.Sets != 2 || *session.TopWeightKg != 102.06 || *session.BestE1RMKg != 116.67 || session.VolumeKg != 806.17 {
+		t.Errorf("Summarize = sets %d, top %v, e1RM %v, volume %v", session.Sets, *session.TopWeightKg, *session.BestE1RMKg, session.VolumeKg)
+	}
+}
@@ -0,0 +1,96 @@
+package analytics
+
+import (
+	"math"
+	"time"
+
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+)
+
+// poundsToKg converts pound loads to kilograms
+const poundsToKg = 0.45359237
+
+// SetLoadKg returns the weight lifted in a set in kilograms, or false if the
+// set has no weight
+func SetLoadKg(set models.WorkoutSet) (float64, bool) {
+	if set.Weight == nil {
+		return 0, false
+	}
+	if set.WeightUnit == models.WeightUnitLb {
+		return *set.Weight * poundsToKg, true
+	}
+	return *set.Weight, true
+}
+
+// SetVolume returns weight × reps in kilograms, or 0 if either is missing
+func SetVolume(set models.WorkoutSet) float64 {
+	load, ok := SetLoadKg(set)
+	if !ok || set.Reps == nil {
+		return 0
+	}
+	return load * float64(*set.Reps)
+}
+
+// WeekStart returns the Monday of the ISO week containing d
+func WeekStart(d models.Date) models.Date {
+	offset := (int(d.Weekday()) + 6) % 7
+	return models.Date{Time: d.AddDate(0, 0, -offset)}
+}
+
+// WorkoutDate returns the calendar date of a workout in loc
+func WorkoutDate(workout models.Workout, loc *time.Location) models.Date {
+	return models.NewDate(workout.WorkoutDate.In(loc))
+}
+
+// WeeklyVolume totals training volume per ISO week for workouts in [from, to].
+// Every week overlapping the range is present, including empty ones.
+func WeeklyVolume(workouts []models.Workout, loc *time.Location, from, to models.Date) []models.WeeklyVolumePoint {
+	weeks := make([]models.WeeklyVolumePoint, 0)
+	byWeek := make(map[string]int)
+	for w := WeekStart(from); !w.After(to.Time); w = (models.Date{Time: w.AddDate(0, 0, 7)}) {
+		byWeek[w.String()] = len(weeks)
+		weeks = append(weeks, models.WeeklyVolumePoint{WeekStart: w})
+	}
+
+	for _, workout := range workouts {
+		date := WorkoutDate(workout, loc)
+		if !date.Before(from.Time) && !date.After(to.Time) {
+			week := &weeks[byWeek[WeekStart(date).String()]]
+			week.Sessions++
+			for _, exercise := range workout.Exercises {
+				for _, set := range exercise.Sets {
+					// Warm-up sets are not training volume
+					if set.SetType == models.SetTypeWarmup {
+						continue
+					}
+					week.VolumeKg += SetVolume(set)
+					week.Sets++
+				}
+			}
+		}
+	}
+
+	for i := range weeks {
+		weeks[i].VolumeKg = math.Round(weeks[i].VolumeKg*10) / 10
+	}
+	return weeks
+}
+
+// SessionStats counts workouts in [from, to] and averages their overall RPE
+func SessionStats(workouts []models.Workout, loc *time.Location, from, to models.Date) (int, *float64) {
+	count, rpeSum := 0, 0.0
+	for _, workout := range workouts {
+		date := WorkoutDate(workout, loc)
+		if date.Before(from.Time) || date.After(to.Time) {
+			continue
+		}
+		count++
+		rpeSum += workout.OverallRPE
+	}
+
+	if count == 0 {
+		return 0, nil
+	}
+	avg := math.Round(rpeSum/float64(count)*10) / 10
+	return count, &avg
+}
@@ -0,0 +1,47 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+)
+
+// WeightWindowDays is the rolling window used to smooth body weight (REQ-BOD-004)
+const WeightWindowDays = 7
+
+// WeightTrend returns every weigh-in together with the average of all
+// weigh-ins in the window days ending on that date. Days without a weigh-in
+// do not produce a point; the average simply covers fewer samples.
+func WeightTrend(metrics []models.BodyMetric, window int) []models.WeightTrendPoint {
+	weighIns := make([]models.BodyMetric, 0, len(metrics))
+	for _, metric := range metrics {
+		if metric.BodyWeightKg != nil {
+			weighIns = append(weighIns, metric)
+		}
+	}
+	sort.Slice(weighIns, func(i, j int) bool {
+		return weighIns[i].LogDate.Before(weighIns[j].LogDate.Time)
+	})
+
+	points := make([]models.WeightTrendPoint, 0, len(weighIns))
+	start, sum := 0, 0.0
+	for i, metric := range weighIns {
+		sum += *metric.BodyWeightKg
+		windowStart := metric.LogDate.AddDate(0, 0, -(window - 1))
+		for weighIns[start].LogDate.Before(windowStart) {
+			sum -= *weighIns[start].BodyWeightKg
+			start++
+		}
+
+		samples := i - start + 1
+		points = append(points, models.WeightTrendPoint{
+			Date:         metric.LogDate,
+			WeightKg:     *metric.BodyWeightKg,
+			AverageKg:    math.Round(sum/float64(samples)*100) / 100,
+			SamplesCount: samples,
+		})
+	}
+
+	return points
+}
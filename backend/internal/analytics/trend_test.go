@@ -0,0 +1,33 @@
+package analytics
+
+import (
+	"testing"
+
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+)
+
+func TestWeightTrend(t *testing.T) {
+	metrics := []models.BodyMetric{
+		{LogDate: date(10), BodyWeightKg: ptr(82.0)},
+		{LogDate: date(1), BodyWeightKg: ptr(80.0)},
+		{LogDate: date(5)}, // No weigh-in
+		{LogDate: date(2), BodyWeightKg: ptr(81.0)},
+		{LogDate: date(7), BodyWeightKg: ptr(81.5)},
+	}
+	want := []models.WeightTrendPoint{
+		{Date: date(1), WeightKg: 80, AverageKg: 80, SamplesCount: 1},
+		{Date: date(2), WeightKg: 81, AverageKg: 80.5, SamplesCount: 2},
+		{Date: date(7), WeightKg: 81.5, AverageKg: 80.83, SamplesCount: 3},
+		{Date: date(10), WeightKg: 82, AverageKg: 81.75, SamplesCount: 2}, // Days 4 to 10
+	}
+
+	got := WeightTrend(metrics, WeightWindowDays)
+	if len(got) != len(want) {
+		t.Fatalf("got %d points, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("point %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
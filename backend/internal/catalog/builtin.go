@@ -0,0 +1,164 @@
+package catalog
+
+import (
+	"github.com/google/uuid"
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+	"github.com/hadiabbas/fittrack-backend/pkg/database"
+)
+
+// builtinNamespace derives the IDs of built-in exercises from their slugs,
+// so every deployment and every restart uses the same IDs
+var builtinNamespace = uuid.MustParse("5b0c6a53-3f0e-4c8e-9a34-1f6a3b2d8e71")
+
+// builtinExercise is a built-in catalog entry. Its slug must never change
+// once released, as logged workouts reference the ID derived from it.
+type builtinExercise struct {
+	Slug       string
+	Name       string
+	Aliases    []string
+	Primary    []string
+	Secondary  []string
+	Equipment  string
+	Pattern    string
+	Unilateral bool
+}
+
+var builtinExercises = []builtinExercise{
+	// Chest
+	{Slug: "barbell-bench-press", Name: "Barbell Bench Press", Aliases: []string{"bench", "bench press", "flat bench", "bb bench"}, Primary: []string{"chest"}, Secondary: []string{"triceps", "front_delts"}, Equipment: "barbell", Pattern: "horizontal_push"},
+	{Slug: "incline-barbell-bench-press", Name: "Incline Barbell Bench Press", Aliases: []string{"incline bench", "incline bench press"}, Primary: []string{"chest"}, Secondary: []string{"front_delts", "triceps"}, Equipment: "barbell", Pattern: "horizontal_push"},
+	{Slug: "dumbbell-bench-press", Name: "Dumbbell Bench Press", Aliases: []string{"db bench", "db press"}, Primary: []string{"chest"}, Secondary: []string{"triceps", "front_delts"}, Equipment: "dumbbell", Pattern: "horizontal_push"},
+	{Slug: "incline-dumbbell-press", Name: "Incline Dumbbell Press", Aliases: []string{"incline db press", "incline dumbbell bench press"}, Primary: []string{"chest"}, Secondary: []string{"front_delts", "triceps"}, Equipment: "dumbbell", Pattern: "horizontal_push"},
+	{Slug: "push-up", Name: "Push-Up", Aliases: []string{"pushup", "press up"}, Primary: []string{"chest"}, Secondary: []string{"triceps", "front_delts", "abs"}, Equipment: "bodyweight", Pattern: "horizontal_push"},
+	{Slug: "chest-dip", Name: "Dip", Aliases: []string{"dips", "chest dip", "parallel bar dip"}, Primary: []string{"chest", "triceps"}, Secondary: []string{"front_delts"}, Equipment: "bodyweight", Pattern: "vertical_push"},
+	{Slug: "cable-fly", Name: "Cable Fly", Aliases: []string{"cable crossover", "cable flye"}, Primary: []string{"chest"}, Secondary: []string{"front_delts"}, Equipment: "cable", Pattern: "isolation"},
+	{Slug: "dumbbell-fly", Name: "Dumbbell Fly", Aliases: []string{"db fly", "dumbbell flye"}, Primary: []string{"chest"}, Secondary: []string{"front_delts"}, Equipment: "dumbbell", Pattern: "isolation"},
+	{Slug: "machine-chest-press", Name: "Machine Chest Press", Aliases: []string{"chest press"}, Primary: []string{"chest"}, Secondary: []string{"triceps", "front_delts"}, Equipment: "machine", Pattern: "horizontal_push"},
+	{Slug: "pec-deck", Name: "Pec Deck", Aliases: []string{"machine fly", "pec fly"}, Primary: []string{"chest"}, Equipment: "machine", Pattern: "isolation"},
+
+	// Back
+	{Slug: "deadlift", Name: "Deadlift", Aliases: []string{"conventional deadlift", "barbell deadlift"}, Primary: []string{"hamstrings", "glutes", "lower_back"}, Secondary: []string{"quads", "traps", "forearms", "lats"}, Equipment: "barbell", Pattern: "hinge"},
+	{Slug: "sumo-deadlift", Name: "Sumo Deadlift", Primary: []string{"glutes", "quads", "adductors"}, Secondary: []string{"hamstrings", "lower_back", "traps", "forearms"}, Equipment: "barbell", Pattern: "hinge"},
+	{Slug: "trap-bar-deadlift", Name: "Trap Bar Deadlift", Aliases: []string{"hex bar deadlift"}, Primary: []string{"quads", "glutes"}, Secondary: []string{"hamstrings", "lower_back", "traps", "forearms"}, Equipment: "trap_bar", Pattern: "hinge"},
+	{Slug: "pull-up", Name: "Pull-Up", Aliases: []string{"pullup", "pull ups"}, Primary: []string{"lats"}, Secondary: []string{"biceps", "upper_back", "forearms"}, Equipment: "bodyweight", Pattern: "vertical_pull"},
+	{Slug: "chin-up", Name: "Chin-Up", Aliases: []string{"chinup"}, Primary: []string{"lats", "biceps"}, Secondary: []string{"upper_back", "forearms"}, Equipment: "bodyweight", Pattern: "vertical_pull"},
+	{Slug: "lat-pulldown", Name: "Lat Pulldown", Aliases: []string{"pulldown", "lat pull down"}, Primary: []string{"lats"}, Secondary: []string{"biceps", "upper_back"}, Equipment: "cable", Pattern: "vertical_pull"},
+	{Slug: "barbell-row", Name: "Barbell Row", Aliases: []string{"bent over row", "bb row", "pendlay row"}, Primary: []string{"upper_back", "lats"}, Secondary: []string{"biceps", "rear_delts", "lower_back"}, Equipment: "barbell", Pattern: "horizontal_pull"},
+	{Slug: "dumbbell-row", Name: "Dumbbell Row", Aliases: []string{"db row", "one arm row", "single arm dumbbell row"}, Primary: []string{"lats", "upper_back"}, Secondary: []string{"biceps", "rear_delts"}, Equipment: "dumbbell", Pattern: "horizontal_pull", Unilateral: true},
+	{Slug: "seated-cable-row", Name: "Seated Cable Row", Aliases: []string{"cable row", "seated row"}, Primary: []string{"upper_back", "lats"}, Secondary: []string{"biceps", "rear_delts"}, Equipment: "cable", Pattern: "horizontal_pull"},
+	{Slug: "t-bar-row", Name: "T-Bar Row", Primary: []string{"upper_back", "lats"}, Secondary: []string{"biceps", "rear_delts", "lower_back"}, Equipment: "barbell", Pattern: "horizontal_pull"},
+	{Slug: "face-pull", Name: "Face Pull", Primary: []string{"rear_delts"}, Secondary: []string{"upper_back", "traps"}, Equipment: "cable", Pattern: "horizontal_pull"},
+	{Slug: "barbell-shrug", Name: "Barbell Shrug", Aliases: []string{"shrug", "shrugs"}, Primary: []string{"traps"}, Secondary: []string{"forearms"}, Equipment: "barbell", Pattern: "isolation"},
+	{Slug: "back-extension", Name: "Back Extension", Aliases: []string{"hyperextension", "45 degree back extension"}, Primary: []string{"lower_back"}, Secondary: []string{"glutes", "hamstrings"}, Equipment: "bodyweight", Pattern: "hinge"},
+
+	// Shoulders
+	{Slug: "overhead-press", Name: "Overhead Press", Aliases: []string{"ohp", "military press", "standing press", "barbell overhead press"}, Primary: []string{"front_delts"}, Secondary: []string{"triceps", "side_delts", "upper_back"}, Equipment: "barbell", Pattern: "vertical_push"},
+	{Slug: "dumbbell-shoulder-press", Name: "Dumbbell Shoulder Press", Aliases: []string{"db shoulder press", "seated dumbbell press"}, Primary: []string{"front_delts"}, Secondary: []string{"triceps", "side_delts"}, Equipment: "dumbbell", Pattern: "vertical_push"},
+	{Slug: "lateral-raise", Name: "Lateral Raise", Aliases: []string{"side raise", "dumbbell lateral raise", "side lateral raise"}, Primary: []string{"side_delts"}, Equipment: "dumbbell", Pattern: "isolation"},
+	{Slug: "rear-delt-fly", Name: "Rear Delt Fly", Aliases: []string{"reverse fly", "rear delt raise", "reverse pec deck"}, Primary: []string{"rear_delts"}, Secondary: []string{"upper_back"}, Equipment: "dumbbell", Pattern: "isolation"},
+	{Slug: "front-raise", Name: "Front Raise", Primary: []string{"front_delts"}, Equipment: "dumbbell", Pattern: "isolation"},
+
+	// Arms
+	{Slug: "barbell-curl", Name: "Barbell Curl", Aliases: []string{"bb curl", "standing barbell curl"}, Primary: []string{"biceps"}, Secondary: []string{"forearms"}, Equipment: "barbell", Pattern: "isolation"},
+	{Slug: "dumbbell-curl", Name: "Dumbbell Curl", Aliases: []string{"db curl", "bicep curl", "biceps curl", "curl"}, Primary: []string{"biceps"}, Secondary: []string{"forearms"}, Equipment: "dumbbell", Pattern: "isolation"},
+	{Slug: "hammer-curl", Name: "Hammer Curl", Primary: []string{"biceps", "forearms"}, Equipment: "dumbbell", Pattern: "isolation"},
+	{Slug: "ez-bar-curl", Name: "EZ Bar Curl", Aliases: []string{"ez curl"}, Primary: []string{"biceps"}, Secondary: []string{"forearms"}, Equipment: "ez_bar", Pattern: "isolation"},
+	{Slug: "preacher-curl", Name: "Preacher Curl", Primary: []string{"biceps"}, Equipment: "ez_bar", Pattern: "isolation"},
+	{Slug: "cable-curl", Name: "Cable Curl", Primary: []string{"biceps"}, Secondary: []string{"forearms"}, Equipment: "cable", Pattern: "isolation"},
+	{Slug: "triceps-pushdown", Name: "Triceps Pushdown", Aliases: []string{"tricep pushdown", "cable pushdown", "rope pushdown", "tricep pressdown"}, Primary: []string{"triceps"}, Equipment: "cable", Pattern: "isolation"},
+	{Slug: "skull-crusher", Name: "Skull Crusher", Aliases: []string{"lying triceps extension", "ez bar skull crusher"}, Primary: []string{"triceps"}, Equipment: "ez_bar", Pattern: "isolation"},
+	{Slug: "overhead-triceps-extension", Name: "Overhead Triceps Extension", Aliases: []string{"overhead tricep extension", "french press"}, Primary: []string{"triceps"}, Equipment: "dumbbell", Pattern: "isolation"},
+	{Slug: "close-grip-bench-press", Name: "Close-Grip Bench Press", Aliases: []string{"close grip bench", "cgbp"}, Primary: []string{"triceps", "chest"}, Secondary: []string{"front_delts"}, Equipment: "barbell", Pattern: "horizontal_push"},
+	{Slug: "wrist-curl", Name: "Wrist Curl", Primary: []string{"forearms"}, Equipment: "dumbbell", Pattern: "isolation"},
+
+	// Legs
+	{Slug: "barbell-back-squat", Name: "Barbell Back Squat", Aliases: []string{"squat", "back squat", "barbell squat", "high bar squat", "low bar squat"}, Primary: []string{"quads", "glutes"}, Secondary: []string{"adductors", "hamstrings", "lower_back"}, Equipment: "barbell", Pattern: "squat"},
+	{Slug: "front-squat", Name: "Front Squat", Aliases: []string{"barbell front squat"}, Primary: []string{"quads"}, Secondary: []string{"glutes", "upper_back", "abs"}, Equipment: "barbell", Pattern: "squat"},
+	{Slug: "goblet-squat", Name: "Goblet Squat", Primary: []string{"quads", "glutes"}, Secondary: []string{"adductors", "abs"}, Equipment: "dumbbell", Pattern: "squat"},
+	{Slug: "leg-press", Name: "Leg Press", Aliases: []string{"45 degree leg press"}, Primary: []string{"quads", "glutes"}, Secondary: []string{"adductors", "hamstrings"}, Equipment: "machine", Pattern: "squat"},
+	{Slug: "hack-squat", Name: "Hack Squat", Aliases: []string{"machine hack squat"}, Primary: []string{"quads"}, Secondary: []string{"glutes"}, Equipment: "machine", Pattern: "squat"},
+	{Slug: "romanian-deadlift", Name: "Romanian Deadlift", Aliases: []string{"rdl", "stiff leg deadlift"}, Primary: []string{"hamstrings", "glutes"}, Secondary: []string{"lower_back", "forearms"}, Equipment: "barbell", Pattern: "hinge"},
+	{Slug: "hip-thrust", Name: "Hip Thrust", Aliases: []string{"barbell hip thrust", "glute bridge"}, Primary: []string{"glutes"}, Secondary: []string{"hamstrings"}, Equipment: "barbell", Pattern: "hinge"},
+	{Slug: "bulgarian-split-squat", Name: "Bulgarian Split Squat", Aliases: []string{"bss", "rear foot elevated split squat"}, Primary: []string{"quads", "glutes"}, Secondary: []string{"adductors", "hamstrings"}, Equipment: "dumbbell", Pattern: "lunge", Unilateral: true},
+	{Slug: "walking-lunge", Name: "Walking Lunge", Aliases: []string{"lunge", "lunges", "dumbbell lunge"}, Primary: []string{"quads", "glutes"}, Secondary: []string{"adductors", "hamstrings"}, Equipment: "dumbbell", Pattern: "lunge", Unilateral: true},
+	{Slug: "step-up", Name: "Step-Up", Aliases: []string{"box step up", "dumbbell step up"}, Primary: []string{"quads", "glutes"}, Secondary: []string{"hamstrings"}, Equipment: "dumbbell", Pattern: "lunge", Unilateral: true},
+	{Slug: "leg-extension", Name: "Leg Extension", Aliases: []string{"quad extension"}, Primary: []string{"quads"}, Equipment: "machine", Pattern: "isolation"},
+	{Slug: "lying-leg-curl", Name: "Lying Leg Curl", Aliases: []string{"leg curl", "hamstring curl"}, Primary: []string{"hamstrings"}, Secondary: []string{"calves"}, Equipment: "machine", Pattern: "isolation"},
+	{Slug: "seated-leg-curl", Name: "Seated Leg Curl", Primary: []string{"hamstrings"}, Equipment: "machine", Pattern: "isolation"},
+	{Slug: "hip-adduction", Name: "Hip Adduction", Aliases: []string{"adductor machine", "adduction"}, Primary: []string{"adductors"}, Equipment: "machine", Pattern: "isolation"},
+	{Slug: "hip-abduction", Name: "Hip Abduction", Aliases: []string{"abductor machine", "abduction"}, Primary: []string{"abductors"}, Secondary: []string{"glutes"}, Equipment: "machine", Pattern: "isolation"},
+	{Slug: "standing-calf-raise", Name: "Standing Calf Raise", Aliases: []string{"calf raise", "calf raises"}, Primary: []string{"calves"}, Equipment: "machine", Pattern: "isolation"},
+	{Slug: "seated-calf-raise", Name: "Seated Calf Raise", Primary: []string{"calves"}, Equipment: "machine", Pattern: "isolation"},
+
+	// Core and carries
+	{Slug: "plank", Name: "Plank", Aliases: []string{"front plank"}, Primary: []string{"abs"}, Secondary: []string{"obliques"}, Equipment: "bodyweight", Pattern: "core"},
+	{Slug: "hanging-leg-raise", Name: "Hanging Leg Raise", Aliases: []string{"leg raise", "hanging knee raise"}, Primary: []string{"abs"}, Secondary: []string{"obliques", "forearms"}, Equipment: "bodyweight", Pattern: "core"},
+	{Slug: "cable-crunch", Name: "Cable Crunch", Aliases: []string{"kneeling cable crunch"}, Primary: []string{"abs"}, Equipment: "cable", Pattern: "core"},
+	{Slug: "crunch", Name: "Crunch", Aliases: []string{"crunches", "sit up"}, Primary: []string{"abs"}, Equipment: "bodyweight", Pattern: "core"},
+	{Slug: "russian-twist", Name: "Russian Twist", Primary: []string{"obliques"}, Secondary: []string{"abs"}, Equipment: "bodyweight", Pattern: "core"},
+	{Slug: "ab-wheel-rollout", Name: "Ab Wheel Rollout", Aliases: []string{"ab wheel", "rollout"}, Primary: []string{"abs"}, Secondary: []string{"lats"}, Equipment: "other", Pattern: "core"},
+	{Slug: "farmers-carry", Name: "Farmer's Carry", Aliases: []string{"farmers walk", "farmer carry"}, Primary: []string{"forearms", "traps"}, Secondary: []string{"abs", "obliques"}, Equipment: "dumbbell", Pattern: "carry"},
+	{Slug: "kettlebell-swing", Name: "Kettlebell Swing", Aliases: []string{"kb swing", "swings"}, Primary: []string{"glutes", "hamstrings"}, Secondary: []string{"lower_back", "abs"}, Equipment: "kettlebell", Pattern: "hinge"},
+
+	// Cardio
+	{Slug: "running", Name: "Running", Aliases: []string{"run", "jog", "jogging", "treadmill run"}, Primary: []string{"quads", "calves"}, Secondary: []string{"hamstrings", "glutes"}, Equipment: "bodyweight", Pattern: "cardio"},
+	{Slug: "cycling", Name: "Cycling", Aliases: []string{"bike", "stationary bike", "spin"}, Primary: []string{"quads"}, Secondary: []string{"glutes", "hamstrings", "calves"}, Equipment: "cardio_machine", Pattern: "cardio"},
+	{Slug: "rowing-machine", Name: "Rowing Machine", Aliases: []string{"rower", "erg", "indoor rowing"}, Primary: []string{"upper_back", "quads"}, Secondary: []string{"lats", "biceps", "glutes", "hamstrings"}, Equipment: "cardio_machine", Pattern: "cardio"},
+	{Slug: "elliptical", Name: "Elliptical", Aliases: []string{"cross trainer"}, Primary: []string{"quads"}, Secondary: []string{"glutes", "hamstrings", "calves"}, Equipment: "cardio_machine", Pattern: "cardio"},
+	{Slug: "stair-climber", Name: "Stair Climber", Aliases: []string{"stairmaster", "stair machine"}, Primary: []string{"quads", "glutes"}, Secondary: []string{"calves", "hamstrings"}, Equipment: "cardio_machine", Pattern: "cardio"},
+	{Slug: "walking", Name: "Walking", Aliases: []string{"walk", "incline walk"}, Primary: []string{"calves"}, Secondary: []string{"quads", "glutes", "hamstrings"}, Equipment: "bodyweight", Pattern: "cardio"},
+	{Slug: "jump-rope", Name: "Jump Rope", Aliases: []string{"skipping", "skipping rope"}, Primary: []string{"calves"}, Secondary: []string{"quads", "forearms"}, Equipment: "other", Pattern: "cardio"},
+	{Slug: "swimming", Name: "Swimming", Aliases: []string{"swim"}, Primary: []string{"lats", "upper_back"}, Secondary: []string{"front_delts", "triceps", "quads"}, Equipment: "bodyweight", Pattern: "cardio"},
+}
+
+// BuiltinID returns the ID of the built-in exercise with the given slug
+func BuiltinID(slug string) string {
+	return uuid.NewSHA1(builtinNamespace, []byte(slug)).String()
+}
+
+// Builtin returns the built-in exercises
+func Builtin() []models.Exercise {
+	exercises := make([]models.Exercise, 0, len(builtinExercises))
+	for _, b := range builtinExercises {
+		exercises = append(exercises, models.Exercise{
+			ID:               BuiltinID(b.Slug),
+			Name:             b.Name,
+			Aliases:          nonNil(b.Aliases),
+			PrimaryMuscles:   nonNil(b.Primary),
+			SecondaryMuscles: nonNil(b.Secondary),
+			Equipment:        b.Equipment,
+			MovementPattern:  b.Pattern,
+			Unilateral:       b.Unilateral,
+		})
+	}
+	return exercises
+}
+
+// Seed inserts the built-in exercises, updating the ones already stored so
+// changes to their aliases or muscles reach existing databases
+func Seed(db database.Store) error {
+	rows := make([]map[string]interface{}, 0, len(builtinExercises))
+	for _, e := range Builtin() {
+		rows = append(rows, map[string]interface{}{
+			"id":                e.ID,
+			"user_id":           nil,
+			"name":              e.Name,
+			"aliases":           e.Aliases,
+			"primary_muscles":   e.PrimaryMuscles,
+			"secondary_muscles": e.SecondaryMuscles,
+			"equipment":         e.Equipment,
+			"movement_pattern":  e.MovementPattern,
+			"unilateral":        e.Unilateral,
+		})
+	}
+
+	_, err := db.Upsert("exercises", rows, "id", true)
+	return err
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
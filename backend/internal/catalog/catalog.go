@@ -0,0 +1,286 @@
+// Package catalog matches the free-text exercise names users log against the
+// exercise catalog, tolerating abbreviations, plurals and typos
+package catalog
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+)
+
+// Minimum scores for a name to resolve to an exercise on its own, and for an
+// exercise to appear in search results
+const (
+	resolveScore = 0.85
+	searchScore  = 0.5
+)
+
+// resolveMargin is how far the best match must lead the runner-up for an
+// inexact name to resolve, so ambiguous names stay unlinked
+const resolveMargin = 0.05
+
+// abbreviations are expanded before matching
+var abbreviations = map[string]string{
+	"bb":  "barbell",
+	"db":  "dumbbell",
+	"dbs": "dumbbell",
+	"kb":  "kettlebell",
+	"dl":  "deadlift",
+}
+
+// Catalog is the set of exercises visible to one user: the built-in ones and
+// the user's custom exercises
+type Catalog struct {
+	exercises []models.Exercise
+	byID      map[string]int
+	resolved  map[string]int // Resolved names by normalized name; -1 if unresolved
+}
+
+// New creates a catalog of exercises
+func New(exercises []models.Exercise) *Catalog {
+	c := &Catalog{
+		exercises: exercises,
+		byID:      make(map[string]int, len(exercises)),
+		resolved:  make(map[string]int),
+	}
+	for i, e := range exercises {
+		c.byID[e.ID] = i
+	}
+	return c
+}
+
+// Get returns the exercise with the given ID
+func (c *Catalog) Get(id string) (*models.Exercise, bool) {
+	i, ok := c.byID[id]
+	if !ok {
+		return nil, false
+	}
+	return &c.exercises[i], true
+}
+
+// Resolve returns the exercise a free-text name refers to. An exact match of
+// the name or an alias wins, preferring custom exercises; otherwise the best
+// fuzzy match is used if it is close and clearly ahead of the others.
+func (c *Catalog) Resolve(name string) (*models.Exercise, bool) {
+	query := normalize(name)
+	if query == "" {
+		return nil, false
+	}
+	if i, ok := c.resolved[query]; ok {
+		if i < 0 {
+			return nil, false
+		}
+		return &c.exercises[i], true
+	}
+
+	best, bestScore, runnerUp := -1, 0.0, 0.0
+	for i, e := range c.exercises {
+		score := exerciseScore(query, e)
+		switch {
+		case score > bestScore, score == bestScore && best >= 0 && e.UserID != nil && c.exercises[best].UserID == nil:
+			runnerUp = max(runnerUp, bestScore)
+			best, bestScore = i, score
+		case score > runnerUp:
+			runnerUp = score
+		}
+	}
+
+	if bestScore < 1 && (bestScore < resolveScore || bestScore-runnerUp < resolveMargin) {
+		best = -1
+	}
+	c.resolved[query] = best
+	if best < 0 {
+		return nil, false
+	}
+	return &c.exercises[best], true
+}
+
+// ExerciseOf returns the catalog exercise a logged exercise refers to: the
+// one it is linked to, or else the one its name resolves to
+func (c *Catalog) ExerciseOf(exercise models.WorkoutExercise) (*models.Exercise, bool) {
+	if exercise.ExerciseID != nil {
+		if e, ok := c.Get(*exercise.ExerciseID); ok {
+			return e, true
+		}
+	}
+	return c.Resolve(exercise.Name)
+}
+
+// Filter narrows a search to exercises with every non-empty field
+type Filter struct {
+	Muscle          string // Primary or secondary
+	Equipment       string
+	MovementPattern string
+	CustomOnly      bool
+}
+
+func (f Filter) matches(e models.Exercise) bool {
+	if f.Muscle != "" && !contains(e.PrimaryMuscles, f.Muscle) && !contains(e.SecondaryMuscles, f.Muscle) {
+		return false
+	}
+	if f.Equipment != "" && e.Equipment != f.Equipment {
+		return false
+	}
+	if f.MovementPattern != "" && e.MovementPattern != f.MovementPattern {
+		return false
+	}
+	return !f.CustomOnly || e.UserID != nil
+}
+
+// Search returns the exercises matching filter whose name or aliases are
+// close to query, best first. An empty query lists them by name.
+func (c *Catalog) Search(query string, filter Filter) []models.ExerciseMatch {
+	normalized := normalize(query)
+
+	matches := make([]models.ExerciseMatch, 0)
+	for _, e := range c.exercises {
+		if !filter.matches(e) {
+			continue
+		}
+		score := 1.0
+		if normalized != "" {
+			score = exerciseScore(normalized, e)
+			if score < searchScore {
+				continue
+			}
+		}
+		matches = append(matches, models.ExerciseMatch{Exercise: e, Score: math.Round(score*100) / 100})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return strings.ToLower(matches[i].Name) < strings.ToLower(matches[j].Name)
+	})
+	return matches
+}
+
+// exerciseScore scores a normalized query against the name and aliases of e
+func exerciseScore(query string, e models.Exercise) float64 {
+	best := similarity(query, normalize(e.Name))
+	for _, alias := range e.Aliases {
+		if best == 1 {
+			break
+		}
+		if score := similarity(query, normalize(alias)); score > best {
+			best = score
+		}
+	}
+	return best
+}
+
+// similarity scores two normalized names from 0 to 1. It is the better of a
+// word-level score, which favours candidates containing every word of the
+// query, and the edit distance between the names without spaces.
+func similarity(query, candidate string) float64 {
+	if candidate == "" {
+		return 0
+	}
+	compactQuery := strings.ReplaceAll(query, " ", "")
+	compactCandidate := strings.ReplaceAll(candidate, " ", "")
+	if compactQuery == compactCandidate {
+		return 1
+	}
+
+	queryWords, candidateWords := strings.Fields(query), strings.Fields(candidate)
+	found, covered := 0.0, make([]bool, len(candidateWords))
+	for _, qw := range queryWords {
+		bestWord, bestIndex := 0.0, -1
+		for i, cw := range candidateWords {
+			if score := wordSimilarity(qw, cw); score > bestWord {
+				bestWord, bestIndex = score, i
+			}
+		}
+		found += bestWord
+		if bestIndex >= 0 {
+			covered[bestIndex] = true
+		}
+	}
+	coveredCount := 0
+	for _, c := range covered {
+		if c {
+			coveredCount++
+		}
+	}
+	// Every query word found, but the candidate may have more words
+	wordScore := 0.7*found/float64(len(queryWords)) + 0.3*float64(coveredCount)/float64(len(candidateWords))
+
+	distance := levenshtein(compactQuery, compactCandidate)
+	longest := max(len([]rune(compactQuery)), len([]rune(compactCandidate)))
+	editScore := 1 - float64(distance)/float64(longest)
+
+	return max(wordScore, editScore)
+}
+
+// wordSimilarity scores two words: equal, a prefix, or a typo away
+func wordSimilarity(a, b string) float64 {
+	switch {
+	case a == b:
+		return 1
+	case len(a) >= 3 && strings.HasPrefix(b, a):
+		return 0.9
+	}
+	distance := levenshtein(a, b)
+	switch {
+	case distance == 1 && len(a) >= 4:
+		return 0.8
+	case distance == 2 && len(a) >= 7:
+		return 0.7
+	}
+	return 0
+}
+
+// normalize lower-cases a name, keeps only words of letters and digits, expands
+// abbreviations and drops plural endings
+func normalize(name string) string {
+	name = strings.NewReplacer("'", "", "’", "").Replace(strings.ToLower(name))
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		if expanded, ok := abbreviations[w]; ok {
+			w = expanded
+		}
+		// "rows", "lunges", "dips", but not "press" or "abs"
+		if len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") {
+			w = strings.TrimSuffix(w, "s")
+		}
+		words[i] = w
+	}
+	return strings.Join(words, " ")
+}
+
+// levenshtein returns the edit distance between two strings
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
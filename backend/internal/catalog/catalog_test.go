@@ -0,0 +1,108 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+)
+
+func TestResolve(t *testing.T) {
+	userID := "user-1"
+	custom := models.Exercise{ID: "custom-bench", UserID: &userID, Name: "Bench", PrimaryMuscles: []string{"chest"}}
+	c := New(append([]models.Exercise{custom}, Builtin()...))
+
+	tests := []struct {
+		name  string
+		query string
+		want  string // Empty when the name must stay unresolved
+	}{
+		{"exact name", "Barbell Row", BuiltinID("barbell-row")},
+		{"case and punctuation", "pull up", BuiltinID("pull-up")},
+		{"alias", "OHP", BuiltinID("overhead-press")},
+		{"abbreviation", "BB Curl", BuiltinID("barbell-curl")},
+		{"plural", "Dumbbell Rows", BuiltinID("dumbbell-row")},
+		{"abbreviated alias", "db shoulder press", BuiltinID("dumbbell-shoulder-press")},
+		{"apostrophe", "Farmers Carry", BuiltinID("farmers-carry")},
+		{"one typo", "Deadlit", BuiltinID("deadlift")},
+		{"custom exercise wins an exact tie", "bench", "custom-bench"},
+		{"ambiguous", "press", ""},
+		{"unrelated", "Zercher Carry", ""},
+		{"blank", "  ", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := c.Resolve(tt.query)
+			switch {
+			case tt.want == "" && ok:
+				t.Errorf("Resolve(%q) = %s, want no match", tt.query, got.Name)
+			case tt.want != "" && !ok:
+				t.Errorf("Resolve(%q) found no match, want %s", tt.query, tt.want)
+			case ok && got.ID != tt.want:
+				t.Errorf("Resolve(%q) = %s (%s), want %s", tt.query, got.Name, got.ID, tt.want)
+			}
+		})
+	}
+}
+
+func TestExerciseOfPrefersTheLink(t *testing.T) {
+	c := New(Builtin())
+	squat := BuiltinID("front-squat")
+
+	linked, ok := c.ExerciseOf(models.WorkoutExercise{ExerciseID: &squat, Name: "Bench Press"})
+	if !ok || linked.ID != squat {
+		t.Errorf("linked exercise = %v, want Front Squat", linked)
+	}
+
+	unknown := "missing"
+	byName, ok := c.ExerciseOf(models.WorkoutExercise{ExerciseID: &unknown, Name: "Bench Press"})
+	if !ok || byName.ID != BuiltinID("barbell-bench-press") {
+		t.Errorf("exercise with an unknown link = %v, want the one its name resolves to", byName)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	c := New(Builtin())
+
+	matches := c.Search("curl", Filter{})
+	if len(matches) == 0 || matches[0].Score < searchScore {
+		t.Fatalf("Search(curl) = %v, want curls", matches)
+	}
+	for i, m := range matches {
+		if m.Score < searchScore {
+			t.Errorf("%s scored %v, below the search threshold", m.Name, m.Score)
+		}
+		if i > 0 && m.Score > matches[i-1].Score {
+			t.Errorf("%s (%v) is listed after a lower score", m.Name, m.Score)
+		}
+	}
+
+	for _, m := range c.Search("", Filter{Muscle: "calves"}) {
+		if !contains(m.PrimaryMuscles, "calves") && !contains(m.SecondaryMuscles, "calves") {
+			t.Errorf("%s does not work the calves", m.Name)
+		}
+	}
+
+	if matches := c.Search("zercher carry", Filter{}); len(matches) > 0 && matches[0].Score >= resolveScore {
+		t.Errorf("Search(zercher carry) = %v, want no close match", matches)
+	}
+}
+
+func TestWordSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"curl", "curl", 1},
+		{"ext", "extension", 0.9},
+		{"ex", "extension", 0},
+		{"squt", "squat", 0.8},
+		{"sqt", "squat", 0},
+		{"deadlfit", "deadlift", 0.7},
+		{"rwo", "row", 0},
+	}
+	for _, tt := range tests {
+		if got := wordSimilarity(tt.a, tt.b); got != tt.want {
+			t.Errorf("wordSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
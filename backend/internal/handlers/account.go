@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+	"github.com/hadiabbas/fittrack-backend/pkg/database"
+	"github.com/hadiabbas/fittrack-backend/pkg/revocation"
+	"github.com/hadiabbas/fittrack-backend/pkg/utils"
+)
+
+// exportPageSize stays within PostgREST's default max-rows limit, so large
+// histories are exported in full
+const exportPageSize = 1000
+
+type AccountHandler struct {
+	DB      database.Store
+	Revoked revocation.Store
+}
+
+func NewAccountHandler(db database.Store, revoked revocation.Store) *AccountHandler {
+	return &AccountHandler{DB: db, Revoked: revoked}
+}
+
+// DeleteAccount permanently deletes the user's account once its password is
+// confirmed, so a stolen access token alone cannot delete it. The profile,
+// workouts, routines, custom exercises, food logs, body metrics and sessions
+// are removed with it by the database's cascading foreign keys.
+func (h *AccountHandler) DeleteAccount(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req models.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// Signing in checks the password; the session it starts is ended with
+	// the others when the user is deleted
+	if _, err := h.DB.AuthSignIn(c.GetString("email"), req.Password); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Password is incorrect"})
+		return
+	}
+
+	// Deleting the user ends its Supabase sessions and deletes its refresh
+	// tokens, which also ends the sessions its access tokens name
+	if err := h.DB.AuthDeleteUser(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account: " + err.Error()})
+		return
+	}
+
+	// Reject the access tokens already issued, including the one used for
+	// this request. iat has whole seconds and no token can be issued for the
+	// deleted user any more, so the cutoff is rounded up to the next second.
+	// The account is gone either way, so a failure is only logged.
+	cutoff := time.Now().Truncate(time.Second).Add(time.Second)
+	if err := h.Revoked.RevokeUser(userID, cutoff, cutoff.Add(utils.AccessTokenTTL())); err != nil {
+		log.Printf("revoking access tokens of deleted user %s: %v", userID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account deleted successfully"})
+}
+
+// exportRecord is one record of an export, kept as the JSON object returned
+// by the database so every column is included
+type exportRecord = map[string]interface{}
+
+// exportTable is a table of the export, read a page at a time from the query
+// returned by rows. records picks the table's records out of each page when
+// they are embedded in the rows of another table.
+type exportTable struct {
+	name    string
+	rows    func() *database.QueryBuilder
+	records func(page []exportRecord) []exportRecord
+}
+
+// ExportData streams a ZIP archive of every record the user owns, with each
+// table as a JSON file and as a CSV file. Workouts are also exported as one
+// JSON document with their exercises and sets nested. Rows are read a page
+// at a time and written straight to the response, so only one page is held
+// in memory; each table is read once for its JSON file and once for its CSV.
+func (h *AccountHandler) ExportData(c *gin.Context) {
+	userID := c.GetString("user_id")
+	db := userStore(c, h.DB)
+
+	owned := func(table, order string) func() *database.QueryBuilder {
+		return func() *database.QueryBuilder {
+			q := database.From(db, table).Eq("user_id", userID)
+			if order != "" {
+				q.Order(order, false)
+			}
+			return q
+		}
+	}
+	workouts := func() *database.QueryBuilder {
+		return workoutTree(db, userID).Order("workout_date", false)
+	}
+	routines := func() *database.QueryBuilder {
+		return routineTree(db, userID).Order("name", false)
+	}
+
+	tables := []exportTable{
+		{"workout_sessions", owned("workout_sessions", "workout_date"), nil},
+		{"workout_exercises", workouts, func(page []exportRecord) []exportRecord {
+			_, exercises := unnest(page, "exercises")
+			exercises, _ = unnest(exercises, "sets")
+			return exercises
+		}},
+		{"workout_sets", workouts, func(page []exportRecord) []exportRecord {
+			_, exercises := unnest(page, "exercises")
+			_, sets := unnest(exercises, "sets")
+			return sets
+		}},
+		{"food_logs", owned("food_logs", "log_date"), nil},
+		{"body_metrics", owned("body_metrics", "log_date"), nil},
+		{"user_profiles", owned("user_profiles", ""), nil},
+		{"exercises", owned("exercises", ""), nil},
+		{"routines", owned("routines", "name"), nil},
+		{"routine_exercises", routines, func(page []exportRecord) []exportRecord {
+			_, exercises := unnest(page, "exercises")
+			return exercises
+		}},
+	}
+
+	now := time.Now().UTC()
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="fittrack-export-%s.zip"`, now.Format("2006-01-02")))
+	c.Status(http.StatusOK)
+
+	zw := zip.NewWriter(c.Writer)
+	err := writeJSONFile(zw, now, "account.json", gin.H{
+		"id":          userID,
+		"email":       c.GetString("email"),
+		"exported_at": now,
+	})
+	if err == nil {
+		err = writeJSONRows(zw, now, "workouts.json", exportTable{rows: workouts})
+	}
+	for _, table := range tables {
+		if err != nil {
+			break
+		}
+		if err = writeJSONRows(zw, now, table.name+".json", table); err == nil {
+			err = writeCSVRows(zw, now, table.name+".csv", table)
+		}
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		log.Printf("streaming data export for user %s: %v", userID, err)
+		// Once the response has started the client sees a truncated archive
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Type")
+			c.Writer.Header().Del("Content-Disposition")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export data"})
+		}
+	}
+}
+
+// eachPage calls fn with every page of the records of table, reading its
+// rows in their own order and then by id
+func eachPage(table exportTable, fn func(records []exportRecord) error) error {
+	q := table.rows().Order("id", false)
+	for offset := 0; ; offset += exportPageSize {
+		page, err := database.All[exportRecord](q.Limit(exportPageSize).Offset(offset))
+		if err != nil {
+			return err
+		}
+		records := page
+		if table.records != nil {
+			records = table.records(page)
+		}
+		if err := fn(records); err != nil {
+			return err
+		}
+		if len(page) < exportPageSize {
+			return nil
+		}
+	}
+}
+
+// unnest splits the records embedded under key out of their parents,
+// returning the parents without the embedded records and the embedded
+// records themselves
+func unnest(records []exportRecord, key string) (parents, children []exportRecord) {
+	parents = make([]exportRecord, 0, len(records))
+	children = []exportRecord{}
+	for _, record := range records {
+		parent := make(exportRecord, len(record))
+		for k, v := range record {
+			if k != key {
+				parent[k] = v
+			}
+		}
+		parents = append(parents, parent)
+
+		nested, _ := record[key].([]interface{})
+		for _, n := range nested {
+			if child, ok := n.(map[string]interface{}); ok {
+				children = append(children, child)
+			}
+		}
+	}
+	return parents, children
+}
+
+// createFile adds a compressed file to the archive
+func createFile(zw *zip.Writer, modified time.Time, name string) (io.Writer, error) {
+	return zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+}
+
+func writeJSONFile(zw *zip.Writer, modified time.Time, name string, v interface{}) error {
+	w, err := createFile(zw, modified, name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeJSONRows writes the records of table as a JSON array, formatted like
+// writeJSONFile
+func writeJSONRows(zw *zip.Writer, modified time.Time, name string, table exportTable) error {
+	w, err := createFile(zw, modified, name)
+	if err != nil {
+		return err
+	}
+
+	empty := true
+	err = eachPage(table, func(records []exportRecord) error {
+		for _, record := range records {
+			data, err := json.MarshalIndent(record, "  ", "  ")
+			if err != nil {
+				return err
+			}
+			separator := ",\n  "
+			if empty {
+				separator, empty = "[\n  ", false
+			}
+			if _, err := io.WriteString(w, separator); err != nil {
+				return err
+			}
+			if _, err := w.Write(data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	end := "\n]\n"
+	if empty {
+		end = "[]\n"
+	}
+	_, err = io.WriteString(w, end)
+	return err
+}
+
+// writeCSVRows writes the records of table as CSV with one column per field,
+// id first and the rest in alphabetical order. The columns are those of the
+// first page, as every row of a table has the same ones. Nested values are
+// written as JSON.
+func writeCSVRows(zw *zip.Writer, modified time.Time, name string, table exportTable) error {
+	w, err := createFile(zw, modified, name)
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	var columns []string
+	var line []string
+	err = eachPage(table, func(records []exportRecord) error {
+		if columns == nil && len(records) > 0 {
+			columns = csvColumns(records)
+			line = make([]string, len(columns))
+			if err := cw.Write(columns); err != nil {
+				return err
+			}
+		}
+		for _, record := range records {
+			for i, column := range columns {
+				line[i] = csvValue(record[column])
+			}
+			if err := cw.Write(line); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	})
+	return err
+}
+
+// csvColumns returns the fields of records, id first and the rest in
+// alphabetical order
+func csvColumns(records []exportRecord) []string {
+	seen := map[string]bool{}
+	var columns []string
+	for _, record := range records {
+		for k := range record {
+			if !seen[k] {
+				seen[k] = true
+				columns = append(columns, k)
+			}
+		}
+	}
+	sort.Slice(columns, func(i, j int) bool {
+		if (columns[i] == "id") != (columns[j] == "id") {
+			return columns[i] == "id"
+		}
+		return columns[i] < columns[j]
+	})
+	return columns
+}
+
+func csvValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
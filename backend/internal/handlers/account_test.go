@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hadiabbas/fittrack-backend/internal/middleware"
+	"github.com/hadiabbas/fittrack-backend/pkg/database"
+)
+
+// failingDeleteStore is a memory store whose account deletion fails
+type failingDeleteStore struct {
+	*database.MemoryStore
+}
+
+func (failingDeleteStore) AuthDeleteUser(userID string) error {
+	return errors.New("delete user error: unavailable")
+}
+
+func TestDeleteAccount(t *testing.T) {
+	s := newTestServer(t)
+	session := s.register("lifter@example.com", "password123")
+
+	s.expect(s.do(http.MethodDelete, "/me", session.Token, nil), http.StatusBadRequest, nil)
+	wrong := map[string]string{"password": "wrong-password"}
+	s.expect(s.do(http.MethodDelete, "/me", session.Token, wrong), http.StatusUnauthorized, nil)
+	s.expect(s.do(http.MethodGet, "/workouts", session.Token, nil), http.StatusOK, nil)
+
+	confirm := map[string]string{"password": "password123"}
+	s.expect(s.do(http.MethodDelete, "/me", session.Token, confirm), http.StatusOK, nil)
+
+	s.expect(s.do(http.MethodGet, "/workouts", session.Token, nil), http.StatusUnauthorized, nil)
+	body := map[string]string{"email": "lifter@example.com", "password": "password123"}
+	s.expect(s.do(http.MethodPost, "/auth/login", "", body), http.StatusUnauthorized, nil)
+}
+
+func TestDeleteAccountFailureKeepsTokens(t *testing.T) {
+	s := newTestServer(t)
+	session := s.register("lifter@example.com", "password123")
+
+	accounts := NewAccountHandler(failingDeleteStore{s.db}, s.revoked)
+	s.router.DELETE("/failing/me", middleware.AuthMiddleware(s.keys, s.revoked, s.db), accounts.DeleteAccount)
+	confirm := map[string]string{"password": "password123"}
+	s.expect(s.do(http.MethodDelete, "/failing/me", session.Token, confirm), http.StatusInternalServerError, nil)
+
+	revoked, err := s.revoked.IsRevoked("jti", session.User.ID, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("IsRevoked: %v", err)
+	}
+	if revoked {
+		t.Error("access tokens were revoked although the account was not deleted")
+	}
+	s.expect(s.do(http.MethodGet, "/workouts", session.Token, nil), http.StatusOK, nil)
+}
+
+func TestExportData(t *testing.T) {
+	s := newTestServer(t)
+	session := s.register("lifter@example.com", "password123")
+	workout := benchWorkout("Push", time.Date(2024, 3, 4, 18, 0, 0, 0, time.UTC))
+	s.expect(s.do(http.MethodPost, "/workouts", session.Token, workout), http.StatusCreated, nil)
+
+	w := s.do(http.MethodGet, "/me/export", session.Token, nil)
+	s.expect(w, http.StatusOK, nil)
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("reading archive: %v", err)
+	}
+	files := map[string]string{}
+	for _, f := range archive.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("opening %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(r)
+		r.Close()
+		files[f.Name] = string(data)
+	}
+
+	var workouts []struct {
+		Exercises []struct {
+			Sets []json.RawMessage `json:"sets"`
+		} `json:"exercises"`
+	}
+	if err := json.Unmarshal([]byte(files["workouts.json"]), &workouts); err != nil {
+		t.Fatalf("decoding workouts.json: %v", err)
+	}
+	if len(workouts) != 1 || len(workouts[0].Exercises) != 1 || len(workouts[0].Exercises[0].Sets) != 2 {
+		t.Errorf("workouts.json = %s, want one workout with two nested sets", files["workouts.json"])
+	}
+
+	var sessions []map[string]interface{}
+	if err := json.Unmarshal([]byte(files["workout_sessions.json"]), &sessions); err != nil {
+		t.Fatalf("decoding workout_sessions.json: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0]["exercises"] != nil {
+		t.Errorf("workout_sessions.json = %s, want one session without its exercises", files["workout_sessions.json"])
+	}
+
+	sets, err := csv.NewReader(strings.NewReader(files["workout_sets.csv"])).ReadAll()
+	if err != nil {
+		t.Fatalf("reading workout_sets.csv: %v", err)
+	}
+	if len(sets) != 3 || sets[0][0] != "id" {
+		t.Errorf("workout_sets.csv = %q, want a header starting with id and two sets", sets)
+	}
+
+	if files["food_logs.json"] != "[]\n" || files["food_logs.csv"] != "" {
+		t.Errorf("food logs = %q and %q, want an empty array and an empty CSV", files["food_logs.json"], files["food_logs.csv"])
+	}
+}
@@ -1,21 +1,64 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/hadiabbas/fittrack-backend/internal/models"
 	"github.com/hadiabbas/fittrack-backend/pkg/database"
+	"github.com/hadiabbas/fittrack-backend/pkg/mailer"
+	"github.com/hadiabbas/fittrack-backend/pkg/ratelimit"
+	"github.com/hadiabbas/fittrack-backend/pkg/revocation"
 	"github.com/hadiabbas/fittrack-backend/pkg/utils"
 )
 
+// mailTimeout bounds how long sending a single email may take
+const mailTimeout = 30 * time.Second
+
+// Limits of the unauthenticated forgot-password endpoint: each address gets
+// at most one reset email per interval, each client IP a few requests per
+// hour, and the emails are sent by a fixed number of background workers
+const (
+	passwordResetInterval = 5 * time.Minute
+	passwordResetsPerIP   = 10
+	mailWorkers           = 4
+	mailQueueSize         = 100
+)
+
 type AuthHandler struct {
-	DB *database.SupabaseClient
+	DB      database.Store
+	Keys    *utils.KeyManager
+	Revoked revocation.Store
+	Mailer  mailer.Mailer
+	// AppURL is the base URL of the app; when set, emails link to its
+	// reset-password and verify-email pages
+	AppURL string
+	// RequireEmailConfirmation makes new accounts confirm their email address
+	// with an emailed code before they can log in
+	RequireEmailConfirmation bool
+
+	resetsByEmail *ratelimit.Limiter
+	resetsByIP    *ratelimit.Limiter
+	mailQueue     *taskQueue
 }
 
-func NewAuthHandler(db *database.SupabaseClient) *AuthHandler {
-	return &AuthHandler{DB: db}
+func NewAuthHandler(db database.Store, keys *utils.KeyManager, revoked revocation.Store, mail mailer.Mailer) *AuthHandler {
+	return &AuthHandler{
+		DB:            db,
+		Keys:          keys,
+		Revoked:       revoked,
+		Mailer:        mail,
+		resetsByEmail: ratelimit.New(1, passwordResetInterval),
+		resetsByIP:    ratelimit.New(passwordResetsPerIP, time.Hour),
+		mailQueue:     newTaskQueue(mailWorkers, mailQueueSize),
+	}
 }
 
 // Register creates a new user account
@@ -26,6 +69,11 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	if h.RequireEmailConfirmation {
+		h.registerUnconfirmed(c, req)
+		return
+	}
+
 	// Create user with Supabase Auth
 	authResp, err := h.DB.AuthSignUp(req.Email, req.Password)
 	if err != nil {
@@ -33,40 +81,26 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	// Parse response
-	var authData map[string]interface{}
-	if err := json.Unmarshal(authResp, &authData); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse auth response"})
-		return
+	// Supabase projects that confirm emails themselves return the new user
+	// without a session and send their own confirmation email
+	var signup struct {
+		AccessToken string `json:"access_token"`
 	}
-
-	// Extract user data
-	userData, ok := authData["user"].(map[string]interface{})
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid auth response"})
+	if err := json.Unmarshal(authResp, &signup); err == nil && signup.AccessToken == "" {
+		c.JSON(http.StatusCreated, gin.H{
+			"message":               "Check your email to confirm your account",
+			"confirmation_required": true,
+		})
 		return
 	}
 
-	userID := userData["id"].(string)
-	email := userData["email"].(string)
-
-	// Generate JWT token
-	token, err := utils.GenerateJWT(userID, email)
+	resp, err := h.startSession(authResp)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Return response
-	user := models.User{
-		ID:    userID,
-		Email: email,
-	}
-
-	c.JSON(http.StatusCreated, models.AuthResponse{
-		Token: token,
-		User:  user,
-	})
+	c.JSON(http.StatusCreated, resp)
 }
 
 // Login authenticates a user
@@ -79,44 +113,477 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	// Sign in with Supabase Auth
 	authResp, err := h.DB.AuthSignIn(req.Email, req.Password)
+	if err != nil && strings.Contains(err.Error(), "Email not confirmed") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Email address has not been confirmed"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
 
-	// Parse response
-	var authData map[string]interface{}
-	if err := json.Unmarshal(authResp, &authData); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse auth response"})
+	resp, err := h.startSession(authResp)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// registerUnconfirmed creates an account that has to be confirmed with an
+// emailed code before the user can log in
+func (h *AuthHandler) registerUnconfirmed(c *gin.Context, req models.RegisterRequest) {
+	linkResp, err := h.DB.AuthGenerateLink(database.LinkTypeSignup, req.Email, req.Password)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create account: " + err.Error()})
+		return
+	}
+
+	code, err := emailCode(linkResp)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), mailTimeout)
+	defer cancel()
+	if err := h.Mailer.Send(ctx, mailer.EmailConfirmation(req.Email, code, h.AppURL)); err != nil {
+		log.Printf("sending confirmation email: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send confirmation email"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":               "Check your email to confirm your account",
+		"confirmation_required": true,
+	})
+}
+
+// VerifyEmail confirms an email address with the emailed code and logs the
+// user in
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req models.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	authResp, err := h.DB.AuthVerify(database.LinkTypeSignup, req.Email, req.Token)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired confirmation code"})
+		return
+	}
+
+	resp, err := h.startSession(authResp)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// ForgotPassword emails a password reset code. It answers the same way
+// whether or not the account exists, and sends the email in the background
+// so the response time does not tell either. Further requests for the same
+// address within passwordResetInterval get the same answer without another
+// email, and clients sending too many requests are refused.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.resetsByIP.Allow(c.ClientIP()) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many password reset requests; please try again later"})
+		return
+	}
+
+	email := req.Email
+	if h.resetsByEmail.Allow(strings.ToLower(strings.TrimSpace(email))) {
+		if !h.mailQueue.submit(func() { h.sendPasswordReset(email) }) {
+			log.Printf("mail queue is full, dropped a password reset email")
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If an account exists for this email, a password reset code has been sent"})
+}
+
+// sendPasswordReset generates a recovery code for email and mails it. Unknown
+// addresses are silently ignored.
+func (h *AuthHandler) sendPasswordReset(email string) {
+	linkResp, err := h.DB.AuthGenerateLink(database.LinkTypeRecovery, email, "")
+	if err != nil {
+		log.Printf("generating password reset code: %v", err)
 		return
 	}
 
-	// Extract user data
-	userData, ok := authData["user"].(map[string]interface{})
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid auth response"})
+	code, err := emailCode(linkResp)
+	if err != nil {
+		log.Printf("generating password reset code: %v", err)
 		return
 	}
 
-	userID := userData["id"].(string)
-	email := userData["email"].(string)
+	ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+	defer cancel()
+	if err := h.Mailer.Send(ctx, mailer.PasswordReset(email, code, h.AppURL)); err != nil {
+		log.Printf("sending password reset email: %v", err)
+	}
+}
 
-	// Generate JWT token
-	token, err := utils.GenerateJWT(userID, email)
+// ResetPassword sets a new password using an emailed reset code. Every
+// existing session of the user is signed out.
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	authResp, err := h.DB.AuthVerify(database.LinkTypeRecovery, req.Email, req.Token)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset code"})
+		return
+	}
+
+	session, err := parseSession(authResp)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	attributes := map[string]interface{}{
+		"password": req.Password,
+	}
+	if _, err := h.DB.AuthUpdateUser(session.AccessToken, attributes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to reset password: " + err.Error()})
+		return
+	}
+	h.signOutSupabase(session.AccessToken, database.SignOutGlobal)
+
+	params := map[string]interface{}{
+		"p_user_id": session.User.ID,
+	}
+	if _, err := h.DB.RPC("revoke_user_refresh_tokens", params, true); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions: " + err.Error()})
+		return
+	}
+	// Truncated like iat, so logging in with the new password right away works
+	now := time.Now().Truncate(time.Second)
+	if err := h.Revoked.RevokeUser(session.User.ID, now, now.Add(utils.AccessTokenTTL())); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke access tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset; please log in with your new password"})
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh
+// token. Each refresh token works once; presenting one that was already
+// exchanged revokes every token descended from the same login.
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	refreshToken, err := utils.GenerateRefreshToken()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	// Return response
-	user := models.User{
-		ID:    userID,
-		Email: email,
+	params := map[string]interface{}{
+		"p_token_hash":     utils.HashToken(req.RefreshToken),
+		"p_new_token_hash": utils.HashToken(refreshToken),
+		"p_expires_at":     time.Now().Add(utils.RefreshTokenTTL()).UTC(),
+	}
+	result, err := h.DB.RPC("rotate_refresh_token", params, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session: " + err.Error()})
+		return
 	}
 
-	c.JSON(http.StatusOK, models.AuthResponse{
-		Token: token,
-		User:  user,
-	})
+	var rotation struct {
+		Status               string `json:"status"`
+		ID                   string `json:"id"`
+		UserID               string `json:"user_id"`
+		FamilyID             string `json:"family_id"`
+		SupabaseRefreshToken string `json:"supabase_refresh_token"`
+	}
+	if err := json.Unmarshal(result, &rotation); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse refresh result"})
+		return
+	}
+
+	switch rotation.Status {
+	case "rotated":
+	case "reused":
+		log.Printf("refresh token reuse detected for user %s, revoked token family %s", rotation.UserID, rotation.FamilyID)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token has already been used; please log in again"})
+		return
+	default:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		return
+	}
+
+	// Renew the family's Supabase session
+	authResp, err := h.DB.AuthRefresh(rotation.SupabaseRefreshToken)
+	if err != nil {
+		h.DB.RPC("revoke_refresh_token", map[string]interface{}{"p_token_hash": utils.HashToken(refreshToken)}, true)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session expired; please log in again"})
+		return
+	}
+
+	session, err := parseSession(authResp)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	update := map[string]interface{}{
+		"supabase_refresh_token": session.RefreshToken,
+		"supabase_access_token":  session.AccessToken,
+	}
+	if _, err := h.DB.Update("refresh_tokens", rotation.ID, update, true); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store session"})
+		return
+	}
+
+	resp, err := h.authResponse(session, rotation.FamilyID, refreshToken)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
 }
 
+// Logout invalidates a refresh token together with its Supabase session and,
+// when the request carries a valid access token in the Authorization header,
+// revokes that access token too
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokenHash := utils.HashToken(req.RefreshToken)
+	stored, err := database.One[storedRefreshToken](database.From(h.DB, "refresh_tokens").
+		Select("supabase_access_token").
+		Eq("token_hash", tokenHash).
+		Is("revoked_at", nil).
+		ServiceKey())
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out: " + err.Error()})
+		return
+	}
+
+	params := map[string]interface{}{
+		"p_token_hash": tokenHash,
+	}
+	if _, err := h.DB.RPC("revoke_refresh_token", params, true); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out: " + err.Error()})
+		return
+	}
+	if stored != nil && stored.SupabaseAccessToken != nil {
+		h.signOutSupabase(*stored.SupabaseAccessToken, database.SignOutLocal)
+	}
+
+	if tokenString, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+		if claims, err := h.Keys.ParseJWT(tokenString); err == nil {
+			jti, _ := claims["jti"].(string)
+			userID, _ := claims["user_id"].(string)
+			expiresAt, _ := claims.GetExpirationTime()
+			if jti != "" && expiresAt != nil {
+				if err := h.Revoked.Revoke(jti, userID, expiresAt.Time); err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke access token"})
+					return
+				}
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// LogoutAll signs the user out on every device: all refresh tokens and
+// Supabase sessions are revoked and every access token issued until now is
+// rejected
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	params := map[string]interface{}{
+		"p_user_id": userID,
+	}
+	if _, err := h.DB.RPC("revoke_user_refresh_tokens", params, true); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions: " + err.Error()})
+		return
+	}
+
+	// Access tokens issued before now expire within one access token
+	// lifetime. iat has whole seconds, so the cutoff is truncated to let a
+	// login later in the same second through, and the caller's own token is
+	// revoked by jti in case it was issued earlier in that second.
+	now := time.Now().Truncate(time.Second)
+	if err := h.Revoked.RevokeUser(userID, now, now.Add(utils.AccessTokenTTL())); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke access tokens"})
+		return
+	}
+	if err := h.Revoked.Revoke(c.GetString("jti"), userID, now.Add(utils.AccessTokenTTL())); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke access token"})
+		return
+	}
+	h.signOutSupabase(c.GetString("supabase_token"), database.SignOutGlobal)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Signed out of all devices"})
+}
+
+// storedRefreshToken is the part of a refresh_tokens row read when logging out
+type storedRefreshToken struct {
+	SupabaseAccessToken *string `json:"supabase_access_token"`
+}
+
+// signOutSupabase ends Supabase sessions of the user owning accessToken once
+// the API's own tokens have been revoked. The API no longer hands out those
+// sessions' tokens, so a failure is only logged.
+func (h *AuthHandler) signOutSupabase(accessToken, scope string) {
+	if accessToken == "" {
+		return
+	}
+	if err := h.DB.AuthSignOut(accessToken, scope); err != nil {
+		log.Printf("signing out of Supabase: %v", err)
+	}
+}
+
+// taskQueue runs tasks in the background on a fixed number of goroutines
+type taskQueue struct {
+	tasks chan func()
+}
+
+// newTaskQueue starts workers goroutines running the tasks submitted to a
+// queue holding up to size waiting tasks
+func newTaskQueue(workers, size int) *taskQueue {
+	q := &taskQueue{tasks: make(chan func(), size)}
+	for i := 0; i < workers; i++ {
+		go func() {
+			for task := range q.tasks {
+				task()
+			}
+		}()
+	}
+	return q
+}
+
+// submit queues task, returning false without running it when the queue is full
+func (q *taskQueue) submit(task func()) bool {
+	select {
+	case q.tasks <- task:
+		return true
+	default:
+		return false
+	}
+}
+
+// authSession is the part of a GoTrue session response used by the API
+type authSession struct {
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	User         struct {
+		ID    string `json:"id"`
+		Email string `json:"email"`
+	} `json:"user"`
+}
+
+// parseSession decodes and checks a GoTrue session response
+func parseSession(authResp []byte) (*authSession, error) {
+	var session authSession
+	if err := json.Unmarshal(authResp, &session); err != nil {
+		return nil, errors.New("Failed to parse auth response")
+	}
+	if session.User.ID == "" || session.AccessToken == "" || session.RefreshToken == "" {
+		return nil, errors.New("Invalid auth response")
+	}
+	return &session, nil
+}
+
+// emailCode extracts the one-time code from an AuthGenerateLink response
+func emailCode(linkResp []byte) (string, error) {
+	var link struct {
+		EmailOTP string `json:"email_otp"`
+	}
+	if err := json.Unmarshal(linkResp, &link); err != nil || link.EmailOTP == "" {
+		return "", errors.New("Failed to generate email code")
+	}
+	return link.EmailOTP, nil
+}
+
+// startSession begins a new refresh token family for a freshly signed-in
+// GoTrue session and returns the tokens and profile for the client
+func (h *AuthHandler) startSession(authResp []byte) (*models.AuthResponse, error) {
+	session, err := parseSession(authResp)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, errors.New("Failed to generate token")
+	}
+
+	familyID := uuid.New().String()
+	tokenData := map[string]interface{}{
+		"user_id":                session.User.ID,
+		"family_id":              familyID,
+		"token_hash":             utils.HashToken(refreshToken),
+		"supabase_refresh_token": session.RefreshToken,
+		"supabase_access_token":  session.AccessToken,
+		"expires_at":             time.Now().Add(utils.RefreshTokenTTL()).UTC(),
+	}
+	if _, err := h.DB.Insert("refresh_tokens", tokenData, true); err != nil {
+		return nil, errors.New("Failed to store session")
+	}
+
+	resp, err := h.authResponse(session, familyID, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// Include the profile so the client can show the user's targets at once
+	resp.Profile, err = loadProfile(h.DB.WithAccessToken(session.AccessToken), session.User.ID)
+	if err != nil {
+		return nil, errors.New("Failed to fetch profile")
+	}
+	return resp, nil
+}
+
+// authResponse issues a short-lived access token for the refresh token
+// family familyID. The family's row keeps the Supabase access token that
+// later requests run under, so row level security applies, and the access
+// token never outlives it.
+func (h *AuthHandler) authResponse(session *authSession, familyID, refreshToken string) (*models.AuthResponse, error) {
+	expiresIn := utils.AccessTokenTTL()
+	if supabaseTTL := time.Duration(session.ExpiresIn) * time.Second; supabaseTTL > 0 && supabaseTTL < expiresIn {
+		expiresIn = supabaseTTL
+	}
+
+	token, err := h.Keys.GenerateJWT(session.User.ID, session.User.Email, familyID, expiresIn)
+	if err != nil {
+		return nil, errors.New("Failed to generate token")
+	}
+
+	return &models.AuthResponse{
+		Token:        token,
+		ExpiresIn:    int(expiresIn.Seconds()),
+		RefreshToken: refreshToken,
+		User: models.User{
+			ID:    session.User.ID,
+			Email: session.User.Email,
+		},
+	}, nil
+}
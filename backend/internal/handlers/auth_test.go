@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+	"github.com/hadiabbas/fittrack-backend/pkg/database"
+)
+
+func TestRefreshRotation(t *testing.T) {
+	s := newTestServer(t)
+	first := s.register("lifter@example.com", "password123")
+
+	refresh := func(token string) *httptest.ResponseRecorder {
+		return s.do(http.MethodPost, "/auth/refresh", "", models.RefreshRequest{RefreshToken: token})
+	}
+
+	var second, third models.AuthResponse
+	s.expect(refresh(first.RefreshToken), http.StatusOK, &second)
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("refresh returned the same refresh token")
+	}
+	s.expect(s.do(http.MethodGet, "/workouts", second.Token, nil), http.StatusOK, nil)
+	s.expect(refresh(second.RefreshToken), http.StatusOK, &third)
+
+	// Replaying a used token revokes the whole family, including the
+	// token the legitimate client holds now
+	s.expect(refresh(first.RefreshToken), http.StatusUnauthorized, nil)
+	s.expect(refresh(third.RefreshToken), http.StatusUnauthorized, nil)
+
+	// Other logins are separate families
+	other := s.login("lifter@example.com", "password123")
+	s.expect(refresh(other.RefreshToken), http.StatusOK, nil)
+
+	s.expect(refresh("not-a-refresh-token"), http.StatusUnauthorized, nil)
+}
+
+func TestLogout(t *testing.T) {
+	s := newTestServer(t)
+	session := s.register("lifter@example.com", "password123")
+	other := s.login("lifter@example.com", "password123")
+
+	body := models.RefreshRequest{RefreshToken: session.RefreshToken}
+	s.expect(s.do(http.MethodPost, "/auth/logout", session.Token, body), http.StatusOK, nil)
+	s.expect(s.do(http.MethodGet, "/workouts", session.Token, nil), http.StatusUnauthorized, nil)
+	s.expect(s.do(http.MethodPost, "/auth/refresh", "", body), http.StatusUnauthorized, nil)
+
+	// The other device stays signed in
+	body = models.RefreshRequest{RefreshToken: other.RefreshToken}
+	s.expect(s.do(http.MethodPost, "/auth/refresh", "", body), http.StatusOK, nil)
+}
+
+func TestLogoutAllThenLogin(t *testing.T) {
+	s := newTestServer(t)
+	old := s.register("lifter@example.com", "password123")
+
+	startOfNextSecond()
+	s.expect(s.do(http.MethodPost, "/auth/logout-all", old.Token, nil), http.StatusOK, nil)
+	fresh := s.login("lifter@example.com", "password123")
+
+	s.expect(s.do(http.MethodGet, "/workouts", old.Token, nil), http.StatusUnauthorized, nil)
+	s.expect(s.do(http.MethodGet, "/workouts", fresh.Token, nil), http.StatusOK, nil)
+}
+
+func TestResetPasswordThenLogin(t *testing.T) {
+	s := newTestServer(t)
+	old := s.register("lifter@example.com", "password123")
+
+	link, err := s.db.AuthGenerateLink(database.LinkTypeRecovery, "lifter@example.com", "")
+	if err != nil {
+		t.Fatalf("AuthGenerateLink: %v", err)
+	}
+	code, err := emailCode(link)
+	if err != nil {
+		t.Fatalf("emailCode: %v", err)
+	}
+
+	startOfNextSecond()
+	body := models.ResetPasswordRequest{Email: "lifter@example.com", Token: code, Password: "new-password"}
+	s.expect(s.do(http.MethodPost, "/auth/reset-password", "", body), http.StatusOK, nil)
+	fresh := s.login("lifter@example.com", "new-password")
+
+	s.expect(s.do(http.MethodGet, "/workouts", old.Token, nil), http.StatusUnauthorized, nil)
+	s.expect(s.do(http.MethodGet, "/workouts", fresh.Token, nil), http.StatusOK, nil)
+}
+
+// supabaseToken returns the Supabase access token the server keeps for the
+// session of an access token
+func (s *testServer) supabaseToken(token string) string {
+	s.t.Helper()
+	claims, err := s.keys.ParseJWT(token)
+	if err != nil {
+		s.t.Fatalf("ParseJWT: %v", err)
+	}
+	sessionID, _ := claims["sid"].(string)
+	stored, err := database.One[storedRefreshToken](database.From(s.db, "refresh_tokens").Eq("family_id", sessionID).Is("revoked_at", nil))
+	if err != nil || stored.SupabaseAccessToken == nil {
+		s.t.Fatalf("no Supabase session for sid %q: %v", sessionID, err)
+	}
+	return *stored.SupabaseAccessToken
+}
+
+func TestAccessTokenHasNoSupabaseCredentials(t *testing.T) {
+	s := newTestServer(t)
+	session := s.register("lifter@example.com", "password123")
+	supabaseToken := s.supabaseToken(session.Token)
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.Split(session.Token, ".")[1])
+	if err != nil {
+		t.Fatalf("decoding token payload: %v", err)
+	}
+	if strings.Contains(string(payload), supabaseToken) {
+		t.Errorf("access token payload %s contains the Supabase access token", payload)
+	}
+}
+
+func TestLogoutEndsSupabaseSession(t *testing.T) {
+	s := newTestServer(t)
+	session := s.register("lifter@example.com", "password123")
+	other := s.login("lifter@example.com", "password123")
+	supabaseToken, otherSupabaseToken := s.supabaseToken(session.Token), s.supabaseToken(other.Token)
+
+	body := models.RefreshRequest{RefreshToken: session.RefreshToken}
+	s.expect(s.do(http.MethodPost, "/auth/logout", "", body), http.StatusOK, nil)
+
+	// Without the access token in the request only the session ends
+	s.expect(s.do(http.MethodGet, "/workouts", session.Token, nil), http.StatusUnauthorized, nil)
+	if _, err := s.db.AuthUpdateUser(supabaseToken, nil); err == nil {
+		t.Error("the logged out Supabase session still works")
+	}
+	if _, err := s.db.AuthUpdateUser(otherSupabaseToken, nil); err != nil {
+		t.Errorf("the other device's Supabase session was ended: %v", err)
+	}
+}
+
+func TestLogoutAllEndsSupabaseSessions(t *testing.T) {
+	s := newTestServer(t)
+	session := s.register("lifter@example.com", "password123")
+	other := s.login("lifter@example.com", "password123")
+	otherSupabaseToken := s.supabaseToken(other.Token)
+
+	s.expect(s.do(http.MethodPost, "/auth/logout-all", session.Token, nil), http.StatusOK, nil)
+
+	if _, err := s.db.AuthUpdateUser(otherSupabaseToken, nil); err == nil {
+		t.Error("the other device's Supabase session still works")
+	}
+	s.expect(s.do(http.MethodGet, "/workouts", other.Token, nil), http.StatusUnauthorized, nil)
+}
+
+func TestForgotPasswordLimits(t *testing.T) {
+	s := newTestServer(t)
+	s.register("lifter@example.com", "password123")
+	s.register("runner@example.com", "password123")
+
+	forgot := func(email string) *httptest.ResponseRecorder {
+		return s.do(http.MethodPost, "/auth/forgot-password", "", models.ForgotPasswordRequest{Email: email})
+	}
+
+	// A repeated request for the same address gets the same answer but no
+	// second email
+	s.expect(forgot("lifter@example.com"), http.StatusOK, nil)
+	s.expect(forgot("Lifter@example.com"), http.StatusOK, nil)
+	s.expect(forgot("runner@example.com"), http.StatusOK, nil)
+	sent := s.mail.waitForMail(t, 2)
+	if len(sent) != 2 || sent[0].To == sent[1].To {
+		t.Errorf("sent %d emails to %v, want one to each address", len(sent), sent)
+	}
+
+	// Each client IP is allowed passwordResetsPerIP requests an hour
+	for i := 3; i < passwordResetsPerIP; i++ {
+		s.expect(forgot("nobody@example.com"), http.StatusOK, nil)
+	}
+	s.expect(forgot("nobody@example.com"), http.StatusTooManyRequests, nil)
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hadiabbas/fittrack-backend/internal/analytics"
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+	"github.com/hadiabbas/fittrack-backend/pkg/database"
+)
+
+type BodyMetricHandler struct {
+	DB database.Store
+}
+
+func NewBodyMetricHandler(db database.Store) *BodyMetricHandler {
+	return &BodyMetricHandler{DB: db}
+}
+
+// UpsertBodyMetric records the measurements for a day, merging with any
+// entry already logged for that date
+func (h *BodyMetricHandler) UpsertBodyMetric(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req models.BodyMetricRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.BodyWeightKg == nil && req.BodyFatPercent == nil && req.MuscleMassKg == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one of body_weight_kg, body_fat_percent or muscle_mass_kg is required"})
+		return
+	}
+
+	loc, ok := userTimeZone(c, h.DB)
+	if !ok {
+		return
+	}
+
+	logDate := today(loc)
+	if req.LogDate != nil {
+		logDate = *req.LogDate
+	}
+
+	// Only send the measurements provided so the upsert keeps the others
+	metricData := map[string]interface{}{
+		"user_id":  userID,
+		"log_date": logDate,
+	}
+	if req.BodyWeightKg != nil {
+		metricData["body_weight_kg"] = *req.BodyWeightKg
+	}
+	if req.BodyFatPercent != nil {
+		metricData["body_fat_percent"] = *req.BodyFatPercent
+	}
+	if req.MuscleMassKg != nil {
+		metricData["muscle_mass_kg"] = *req.MuscleMassKg
+	}
+
+	metricResp, err := userStore(c, h.DB).Upsert("body_metrics", metricData, "user_id,log_date", false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save body metrics: " + err.Error()})
+		return
+	}
+
+	var metrics []models.BodyMetric
+	if err := json.Unmarshal(metricResp, &metrics); err != nil || len(metrics) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse body metrics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, metrics[0])
+}
+
+// GetBodyMetrics lists body metric entries within a date range
+func (h *BodyMetricHandler) GetBodyMetrics(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	loc, ok := userTimeZone(c, h.DB)
+	if !ok {
+		return
+	}
+
+	from, to, err := dateRange(c, loc, 30)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	metrics, err := database.All[models.BodyMetric](h.bodyMetrics(c, userID).
+		Gte("log_date", from).
+		Lte("log_date", to))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch body metrics: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, metrics)
+}
+
+// GetWeightTrend returns raw weigh-ins alongside the 7-day rolling average
+// (REQ-BOD-004)
+func (h *BodyMetricHandler) GetWeightTrend(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	loc, ok := userTimeZone(c, h.DB)
+	if !ok {
+		return
+	}
+
+	from, to, err := dateRange(c, loc, 90)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	metrics, err := database.All[models.BodyMetric](h.bodyMetrics(c, userID).
+		Gte("log_date", models.NewDate(from.AddDate(0, 0, -(analytics.WeightWindowDays-1)))).
+		Lte("log_date", to))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch body metrics: " + err.Error()})
+		return
+	}
+
+	// Weigh-ins before the range still feed the averages at its start
+	trend := make([]models.WeightTrendPoint, 0)
+	for _, point := range analytics.WeightTrend(metrics, analytics.WeightWindowDays) {
+		if inRange(point.Date, from, to) {
+			trend = append(trend, point)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":        from,
+		"to":          to,
+		"window_days": analytics.WeightWindowDays,
+		"trend":       trend,
+	})
+}
+
+// DeleteBodyMetric deletes a body metric entry
+func (h *BodyMetricHandler) DeleteBodyMetric(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	metricID := c.Param("id")
+
+	// First verify the entry belongs to this user
+	_, err := database.One[models.BodyMetric](database.From(userStore(c, h.DB), "body_metrics").
+		Select("id").
+		Eq("id", metricID).
+		Eq("user_id", userID))
+	if errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Body metric entry not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify body metrics"})
+		return
+	}
+
+	if err := userStore(c, h.DB).Delete("body_metrics", metricID, false); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete body metrics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Body metric entry deleted successfully"})
+}
+
+// bodyMetrics starts a query for a user's body metrics ordered by date
+func (h *BodyMetricHandler) bodyMetrics(c *gin.Context, userID interface{}) *database.QueryBuilder {
+	return database.From(userStore(c, h.DB), "body_metrics").
+		Eq("user_id", userID).
+		Order("log_date", false)
+}
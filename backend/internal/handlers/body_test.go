@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+)
+
+func TestProfileTimeZoneSetsToday(t *testing.T) {
+	s := newTestServer(t)
+	session := s.register("lifter@example.com", "password123")
+
+	// UTC+14, so today differs from UTC for ten hours of every day
+	profile := models.ProfileRequest{TimeZone: ptr("Pacific/Kiritimati")}
+	s.expect(s.do(http.MethodPut, "/me/profile", session.Token, profile), http.StatusOK, nil)
+
+	var metric models.BodyMetric
+	body := map[string]interface{}{"body_weight_kg": 80}
+	s.expect(s.do(http.MethodPost, "/body-metrics", session.Token, body), http.StatusOK, &metric)
+
+	loc, _ := time.LoadLocation("Pacific/Kiritimati")
+	if want := models.NewDate(time.Now().In(loc)); !metric.LogDate.Equal(want.Time) {
+		t.Errorf("log_date = %s, want %s", metric.LogDate, want)
+	}
+}
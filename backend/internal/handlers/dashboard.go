@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hadiabbas/fittrack-backend/internal/analytics"
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+	"github.com/hadiabbas/fittrack-backend/pkg/database"
+)
+
+// defaultCalorieTarget is used when neither the client nor the profile
+// supplies a target
+const defaultCalorieTarget = 2000
+
+// dashboardRanges are the ranges returned by GetDashboard, in days (0 = all time)
+var dashboardRanges = []struct {
+	Name string
+	Days int
+}{
+	{Name: "7d", Days: 7},
+	{Name: "30d", Days: 30},
+	{Name: "all", Days: 0},
+}
+
+type DashboardHandler struct {
+	DB database.Store
+}
+
+func NewDashboardHandler(db database.Store) *DashboardHandler {
+	return &DashboardHandler{DB: db}
+}
+
+// GetDashboard cross-references workouts, food logs and body weight into the
+// series charted by the app for the last 7 days, 30 days and all time
+func (h *DashboardHandler) GetDashboard(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	// The profile's time zone and calorie target apply unless the request
+	// overrides them
+	profile, err := loadProfile(userStore(c, h.DB), c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profile: " + err.Error()})
+		return
+	}
+	loc, err := profileLocation(c, profile)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	target := defaultCalorieTarget
+	if profile.CalorieTarget != nil {
+		target = *profile.CalorieTarget
+	}
+	if s := c.Query("calorie_target"); s != "" {
+		target, err = strconv.Atoi(s)
+		if err != nil || target < 500 || target > 10000 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "calorie_target must be a whole number between 500 and 10000"})
+			return
+		}
+	}
+
+	selected := c.Query("range")
+	if selected != "" && selected != "7d" && selected != "30d" && selected != "all" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "range must be one of 7d, 30d, all"})
+		return
+	}
+
+	foodLogs, err := database.All[models.FoodLog](database.From(userStore(c, h.DB), "food_logs").Eq("user_id", userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch food logs: " + err.Error()})
+		return
+	}
+
+	metrics, err := database.All[models.BodyMetric](database.From(userStore(c, h.DB), "body_metrics").Eq("user_id", userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch body metrics: " + err.Error()})
+		return
+	}
+
+	workouts, err := database.All[models.Workout](workoutTree(userStore(c, h.DB), userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch workouts: " + err.Error()})
+		return
+	}
+
+	now := today(loc)
+	weight := analytics.WeightTrend(metrics, analytics.WeightWindowDays)
+
+	dashboard := models.Dashboard{
+		Timezone:      loc.String(),
+		CalorieTarget: target,
+		TrainingLoad:  analytics.DailyLoad(workouts, loc, now, now)[0],
+		Ranges:        make([]models.DashboardRange, 0, len(dashboardRanges)),
+	}
+	for _, r := range dashboardRanges {
+		if selected != "" && selected != r.Name {
+			continue
+		}
+
+		from := models.Date{Time: now.AddDate(0, 0, -(r.Days - 1))}
+		if r.Days == 0 {
+			from = earliestDate(now, foodLogs, metrics, workouts, loc)
+		}
+
+		dashboard.Ranges = append(dashboard.Ranges, dashboardRange(r.Name, from, now, target, foodLogs, weight, workouts, loc))
+	}
+
+	c.JSON(http.StatusOK, dashboard)
+}
+
+// dashboardRange computes every series for the inclusive range [from, to]
+func dashboardRange(name string, from, to models.Date, target int, foodLogs []models.FoodLog, weight []models.WeightTrendPoint, workouts []models.Workout, loc *time.Location) models.DashboardRange {
+	result := models.DashboardRange{
+		Range:        name,
+		From:         from,
+		To:           to,
+		Calories:     make([]models.CaloriePoint, 0),
+		Weight:       make([]models.WeightTrendPoint, 0),
+		WeeklyVolume: analytics.WeeklyVolume(workouts, loc, from, to),
+		WeeklyLoad:   analytics.WeeklyLoad(workouts, loc, from, to),
+	}
+
+	// Daily calorie intake vs. target
+	loggedDays, loggedCalories := 0, 0
+	for _, day := range analytics.DailyNutrition(foodLogs, from, to) {
+		result.Calories = append(result.Calories, models.CaloriePoint{
+			Date:     day.Date,
+			Calories: day.Calories,
+			Target:   target,
+			Delta:    day.Calories - target,
+			Logged:   day.Entries > 0,
+		})
+		if day.Entries > 0 {
+			loggedDays++
+			loggedCalories += day.Calories
+		}
+	}
+	if loggedDays > 0 {
+		avg := math.Round(float64(loggedCalories) / float64(loggedDays))
+		result.AverageCalories = &avg
+	}
+
+	// Smoothed body weight
+	for _, point := range weight {
+		if inRange(point.Date, from, to) {
+			result.Weight = append(result.Weight, point)
+		}
+	}
+	if n := len(result.Weight); n > 1 {
+		change := math.Round((result.Weight[n-1].AverageKg-result.Weight[0].AverageKg)*100) / 100
+		result.WeightChangeKg = &change
+	}
+
+	result.SessionCount, result.AverageRPE = analytics.SessionStats(workouts, loc, from, to)
+	return result
+}
+
+// earliestDate returns the first date with any logged data, or fallback
+func earliestDate(fallback models.Date, foodLogs []models.FoodLog, metrics []models.BodyMetric, workouts []models.Workout, loc *time.Location) models.Date {
+	earliest := fallback
+	for _, foodLog := range foodLogs {
+		if foodLog.LogDate.Before(earliest.Time) {
+			earliest = foodLog.LogDate
+		}
+	}
+	for _, metric := range metrics {
+		if metric.LogDate.Before(earliest.Time) {
+			earliest = metric.LogDate
+		}
+	}
+	for _, workout := range workouts {
+		if date := analytics.WorkoutDate(workout, loc); date.Before(earliest.Time) {
+			earliest = date
+		}
+	}
+	return earliest
+}
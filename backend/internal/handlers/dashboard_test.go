@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+)
+
+func TestDashboardProfileDefaults(t *testing.T) {
+	s := newTestServer(t)
+	session := s.register("lifter@example.com", "password123")
+
+	var dashboard models.Dashboard
+	s.expect(s.do(http.MethodGet, "/dashboard?range=7d", session.Token, nil), http.StatusOK, &dashboard)
+	if dashboard.Timezone != "UTC" || dashboard.CalorieTarget != defaultCalorieTarget {
+		t.Errorf("without a profile: %s, %d kcal; want UTC, %d kcal", dashboard.Timezone, dashboard.CalorieTarget, defaultCalorieTarget)
+	}
+
+	profile := models.ProfileRequest{TimeZone: ptr("Pacific/Kiritimati"), CalorieTarget: ptr(2600)}
+	s.expect(s.do(http.MethodPut, "/me/profile", session.Token, profile), http.StatusOK, nil)
+
+	s.expect(s.do(http.MethodGet, "/dashboard?range=7d", session.Token, nil), http.StatusOK, &dashboard)
+	if dashboard.Timezone != "Pacific/Kiritimati" || dashboard.CalorieTarget != 2600 {
+		t.Errorf("with a profile: %s, %d kcal; want Pacific/Kiritimati, 2600 kcal", dashboard.Timezone, dashboard.CalorieTarget)
+	}
+
+	// The request overrides the profile
+	s.expect(s.do(http.MethodGet, "/dashboard?range=7d&tz=UTC&calorie_target=1800", session.Token, nil), http.StatusOK, &dashboard)
+	if dashboard.Timezone != "UTC" || dashboard.CalorieTarget != 1800 {
+		t.Errorf("with overrides: %s, %d kcal; want UTC, 1800 kcal", dashboard.Timezone, dashboard.CalorieTarget)
+	}
+}
@@ -0,0 +1,668 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hadiabbas/fittrack-backend/internal/analytics"
+	"github.com/hadiabbas/fittrack-backend/internal/catalog"
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+	"github.com/hadiabbas/fittrack-backend/pkg/database"
+)
+
+// Page size limits of SearchExercises
+const (
+	defaultExerciseSearchLimit = 50
+	maxExerciseSearchLimit     = 200
+)
+
+// Limits of the sessions query parameter of SuggestNext
+const (
+	defaultSuggestionSessions = 3
+	maxSuggestionSessions     = 20
+)
+
+// exerciseWorkoutsBatch is how many workouts exerciseWorkouts loads per
+// query, keeping the id filter short enough for a URL
+const exerciseWorkoutsBatch = 100
+
+// Default progression settings of SuggestNext
+const (
+	defaultProgressionRepsMin = 8
+	defaultProgressionRepsMax = 12
+	defaultIncrementKg        = 2.5
+	defaultIncrementLb        = 5
+	defaultProgressionRPE     = 8
+)
+
+type ExerciseHandler struct {
+	DB database.Store
+}
+
+func NewExerciseHandler(db database.Store) *ExerciseHandler {
+	return &ExerciseHandler{DB: db}
+}
+
+// SearchExercises lists the catalog exercises matching the q, muscle,
+// equipment, movement_pattern and custom query parameters, best match first
+func (h *ExerciseHandler) SearchExercises(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	filter := catalog.Filter{
+		Muscle:          c.Query("muscle"),
+		Equipment:       c.Query("equipment"),
+		MovementPattern: c.Query("movement_pattern"),
+		CustomOnly:      c.Query("custom") == "true",
+	}
+	if filter.Muscle != "" && !contains(models.MuscleGroups, filter.Muscle) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "muscle must be one of " + strings.Join(models.MuscleGroups, ", ")})
+		return
+	}
+	if filter.Equipment != "" && !contains(models.Equipment, filter.Equipment) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "equipment must be one of " + strings.Join(models.Equipment, ", ")})
+		return
+	}
+	if filter.MovementPattern != "" && !contains(models.MovementPatterns, filter.MovementPattern) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "movement_pattern must be one of " + strings.Join(models.MovementPatterns, ", ")})
+		return
+	}
+
+	limit := defaultExerciseSearchLimit
+	if s := c.Query("limit"); s != "" {
+		var err error
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit < 1 || limit > maxExerciseSearchLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxExerciseSearchLimit)})
+			return
+		}
+	}
+
+	exercises, err := loadCatalog(userStore(c, h.DB), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exercises: " + err.Error()})
+		return
+	}
+
+	matches := exercises.Search(c.Query("q"), filter)
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	c.JSON(http.StatusOK, matches)
+}
+
+// GetExercise returns a built-in exercise or one of the user's custom exercises
+func (h *ExerciseHandler) GetExercise(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	exercises, err := loadCatalog(userStore(c, h.DB), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exercises: " + err.Error()})
+		return
+	}
+
+	exercise, ok := exercises.Get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Exercise not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, exercise)
+}
+
+// CreateExercise adds a custom exercise to the user's catalog
+func (h *ExerciseHandler) CreateExercise(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req models.ExerciseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	exerciseData, err := customExerciseData(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.checkNameAvailable(c, userID, exerciseData["name"].(string), "") {
+		return
+	}
+	exerciseData["user_id"] = userID
+
+	exerciseResp, err := userStore(c, h.DB).Insert("exercises", exerciseData, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create exercise: " + err.Error()})
+		return
+	}
+
+	var exercises []models.Exercise
+	if err := json.Unmarshal(exerciseResp, &exercises); err != nil || len(exercises) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse exercise"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, exercises[0])
+}
+
+// UpdateExercise replaces the details of one of the user's custom exercises
+func (h *ExerciseHandler) UpdateExercise(c *gin.Context) {
+	userID := c.GetString("user_id")
+	exerciseID := c.Param("id")
+
+	var req models.ExerciseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	exerciseData, err := customExerciseData(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.findCustomExercise(c, userID, exerciseID) {
+		return
+	}
+	if !h.checkNameAvailable(c, userID, exerciseData["name"].(string), exerciseID) {
+		return
+	}
+	exerciseData["updated_at"] = time.Now()
+
+	exerciseResp, err := userStore(c, h.DB).Update("exercises", exerciseID, exerciseData, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update exercise: " + err.Error()})
+		return
+	}
+
+	var exercises []models.Exercise
+	if err := json.Unmarshal(exerciseResp, &exercises); err != nil || len(exercises) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse exercise"})
+		return
+	}
+
+	c.JSON(http.StatusOK, exercises[0])
+}
+
+// DeleteExercise deletes one of the user's custom exercises. Workouts that
+// logged it keep their display name but are no longer linked to it.
+func (h *ExerciseHandler) DeleteExercise(c *gin.Context) {
+	userID := c.GetString("user_id")
+	exerciseID := c.Param("id")
+
+	if !h.findCustomExercise(c, userID, exerciseID) {
+		return
+	}
+
+	if err := userStore(c, h.DB).Delete("exercises", exerciseID, false); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete exercise"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Exercise deleted successfully"})
+}
+
+// GetProgress returns the best values of an exercise in every workout it was
+// logged in, and the user's current personal records on it. The :id path
+// parameter accepts either a catalog ID or an exercise name, and logged
+// exercises count when they are linked to it or their name resolves to it. Names matching no catalog exercise are
+// compared as written. The e1RM formula is the one in the profile unless the
+// formula query parameter overrides it.
+func (h *ExerciseHandler) GetProgress(c *gin.Context) {
+	userID := c.GetString("user_id")
+	db := userStore(c, h.DB)
+
+	name := strings.TrimSpace(c.Param("id"))
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Exercise name is required"})
+		return
+	}
+
+	profile, err := loadProfile(db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profile"})
+		return
+	}
+	formula := c.DefaultQuery("formula", profile.E1RMFormula)
+	if formula != models.FormulaEpley && formula != models.FormulaBrzycki {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "formula must be one of epley, brzycki"})
+		return
+	}
+
+	exercises, err := loadCatalog(db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exercises: " + err.Error()})
+		return
+	}
+
+	progress := models.ExerciseProgress{
+		Exercise: name,
+		Formula:  formula,
+		Sessions: make([]models.ExerciseSession, 0),
+	}
+	keyOf := exerciseKeys(exercises)
+	key, exercise := exerciseKey(exercises, keyOf, name)
+	if exercise != nil {
+		progress.ExerciseID = &exercise.ID
+		progress.Exercise = exercise.Name
+	}
+
+	workouts, err := exerciseWorkouts(db, userID, keyOf, key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch workouts: " + err.Error()})
+		return
+	}
+
+	records := analytics.NewRecords(progress.Exercise, formula)
+	for _, performance := range analytics.ExerciseHistory(workouts, key, keyOf) {
+		session := analytics.Summarize(performance, formula)
+		session.NewRecords = make([]string, 0)
+		for _, record := range records.Add(performance) {
+			session.NewRecords = append(session.NewRecords, record.Type)
+		}
+		progress.Sessions = append(progress.Sessions, session)
+	}
+	progress.Records = records.List()
+
+	c.JSON(http.StatusOK, progress)
+}
+
+// SuggestNext returns the last performances of an exercise and the weight
+// and reps suggested for its next session. The exercise is a catalog ID or a
+// name, as for GetProgress. The progression is configured by the scheme
+// (linear, double_progression or rpe), unit, increment, reps_min, reps_max
+// and rpe query parameters, defaulting to the targets of the routine
+// exercise given by routine_exercise_id, if any. sessions sets how many
+// performances are returned.
+func (h *ExerciseHandler) SuggestNext(c *gin.Context) {
+	userID := c.GetString("user_id")
+	db := userStore(c, h.DB)
+
+	name := strings.TrimSpace(c.Param("id"))
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Exercise name is required"})
+		return
+	}
+	sessions, err := intQuery(c, "sessions", defaultSuggestionSessions, 1, maxSuggestionSessions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile, err := loadProfile(db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profile"})
+		return
+	}
+	settings, ok := h.progressionSettings(c, profile)
+	if !ok {
+		return
+	}
+
+	exercises, err := loadCatalog(db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exercises: " + err.Error()})
+		return
+	}
+
+	suggestion := models.ProgressionSuggestion{
+		Exercise:     name,
+		Settings:     settings,
+		Performances: make([]models.ExercisePerformance, 0, sessions),
+	}
+	keyOf := exerciseKeys(exercises)
+	key, exercise := exerciseKey(exercises, keyOf, name)
+	if exercise != nil {
+		suggestion.ExerciseID = &exercise.ID
+		suggestion.Exercise = exercise.Name
+	}
+
+	workouts, err := exerciseWorkouts(db, userID, keyOf, key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch workouts: " + err.Error()})
+		return
+	}
+
+	history := analytics.ExerciseHistory(workouts, key, keyOf)
+	for i := len(history) - 1; i >= 0 && len(suggestion.Performances) < sessions; i-- {
+		suggestion.Performances = append(suggestion.Performances, models.ExercisePerformance{
+			WorkoutID:   history[i].WorkoutID,
+			WorkoutDate: history[i].WorkoutDate,
+			Sets:        history[i].Sets,
+		})
+	}
+	suggestion.Next = analytics.SuggestNext(history, settings, profile.E1RMFormula)
+
+	c.JSON(http.StatusOK, suggestion)
+}
+
+// progressionSettings reads the progression settings of SuggestNext from the
+// query, writing the error response and returning false if they are invalid
+func (h *ExerciseHandler) progressionSettings(c *gin.Context, profile *models.Profile) (models.ProgressionSettings, bool) {
+	settings := models.ProgressionSettings{
+		Scheme:     c.DefaultQuery("scheme", models.SchemeDoubleProgression),
+		WeightUnit: c.DefaultQuery("unit", weightUnitOf(profile)),
+	}
+	if settings.Scheme != models.SchemeLinear && settings.Scheme != models.SchemeDoubleProgression && settings.Scheme != models.SchemeRPE {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scheme must be one of linear, double_progression, rpe"})
+		return settings, false
+	}
+	if settings.WeightUnit != models.WeightUnitKg && settings.WeightUnit != models.WeightUnitLb {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unit must be one of kg, lb"})
+		return settings, false
+	}
+
+	// Targets of the routine exercise replace the defaults
+	repsMin, repsMax, rpe := 0, 0, float64(defaultProgressionRPE)
+	if settings.Scheme == models.SchemeDoubleProgression {
+		repsMin, repsMax = defaultProgressionRepsMin, defaultProgressionRepsMax
+	}
+	if id := c.Query("routine_exercise_id"); id != "" {
+		prescribed, err := loadRoutineExercises(userStore(c, h.DB), c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch routines"})
+			return settings, false
+		}
+		routineExercise, ok := prescribed[id]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "routine_exercise_id is not in one of your routines"})
+			return settings, false
+		}
+		if routineExercise.TargetRepsMin != nil {
+			repsMin, repsMax = *routineExercise.TargetRepsMin, *routineExercise.TargetRepsMin
+			if routineExercise.TargetRepsMax != nil {
+				repsMax = *routineExercise.TargetRepsMax
+			}
+		}
+		if routineExercise.TargetRPE != nil {
+			rpe = *routineExercise.TargetRPE
+		}
+		if routineExercise.WeightUnit != "" && c.Query("unit") == "" {
+			settings.WeightUnit = routineExercise.WeightUnit
+		}
+	}
+
+	increment := defaultIncrementKg
+	if settings.WeightUnit == models.WeightUnitLb {
+		increment = defaultIncrementLb
+	}
+	var err error
+	if settings.Increment, err = floatQuery(c, "increment", increment, 0.1, 100); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return settings, false
+	}
+	if settings.RepsMin, err = intQuery(c, "reps_min", repsMin, 1, 100); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return settings, false
+	}
+	if settings.RepsMax, err = intQuery(c, "reps_max", max(repsMax, settings.RepsMin), 1, 100); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return settings, false
+	}
+	if settings.RepsMax < settings.RepsMin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reps_max must be at least reps_min"})
+		return settings, false
+	}
+	if rpe, err = floatQuery(c, "rpe", rpe, 1, 10); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return settings, false
+	}
+
+	switch settings.Scheme {
+	case models.SchemeRPE:
+		settings.TargetRPE = &rpe
+		settings.RepsMax = settings.RepsMin
+	case models.SchemeLinear:
+		settings.RepsMax = settings.RepsMin
+	}
+	return settings, true
+}
+
+// findCustomExercise checks that exerciseID is one of the user's custom
+// exercises, writing the error response and returning false otherwise
+func (h *ExerciseHandler) findCustomExercise(c *gin.Context, userID, exerciseID string) bool {
+	exercise, err := database.One[models.Exercise](database.From(userStore(c, h.DB), "exercises").Eq("id", exerciseID))
+	if errors.Is(err, database.ErrNotFound) || (err == nil && exercise.UserID != nil && *exercise.UserID != userID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Exercise not found"})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify exercise"})
+		return false
+	}
+	if exercise.UserID == nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Built-in exercises cannot be changed"})
+		return false
+	}
+	return true
+}
+
+// checkNameAvailable checks that none of the user's other custom exercises is
+// called name, writing the error response and returning false otherwise
+func (h *ExerciseHandler) checkNameAvailable(c *gin.Context, userID, name, exerciseID string) bool {
+	custom, err := database.All[models.Exercise](database.From(userStore(c, h.DB), "exercises").
+		Select("id, name").
+		Eq("user_id", userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check exercise name"})
+		return false
+	}
+	for _, e := range custom {
+		if e.ID != exerciseID && strings.EqualFold(e.Name, name) {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("You already have an exercise called %q", e.Name)})
+			return false
+		}
+	}
+	return true
+}
+
+// customExerciseData validates a custom exercise request and converts it into
+// the exercises row
+func customExerciseData(req models.ExerciseRequest) (map[string]interface{}, error) {
+	name := strings.Join(strings.Fields(req.Name), " ")
+	if name == "" {
+		return nil, fmt.Errorf("name must not be blank")
+	}
+
+	aliases := make([]string, 0, len(req.Aliases))
+	for _, alias := range req.Aliases {
+		alias = strings.Join(strings.Fields(alias), " ")
+		if alias != "" && !strings.EqualFold(alias, name) && !containsFold(aliases, alias) {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	for _, muscle := range append(append([]string{}, req.PrimaryMuscles...), req.SecondaryMuscles...) {
+		if !contains(models.MuscleGroups, muscle) {
+			return nil, fmt.Errorf("unknown muscle group %q, expected one of %s", muscle, strings.Join(models.MuscleGroups, ", "))
+		}
+	}
+	secondary := make([]string, 0, len(req.SecondaryMuscles))
+	for _, muscle := range req.SecondaryMuscles {
+		if !contains(req.PrimaryMuscles, muscle) && !contains(secondary, muscle) {
+			secondary = append(secondary, muscle)
+		}
+	}
+	if req.Equipment != "" && !contains(models.Equipment, req.Equipment) {
+		return nil, fmt.Errorf("equipment must be one of %s", strings.Join(models.Equipment, ", "))
+	}
+	if req.MovementPattern != "" && !contains(models.MovementPatterns, req.MovementPattern) {
+		return nil, fmt.Errorf("movement_pattern must be one of %s", strings.Join(models.MovementPatterns, ", "))
+	}
+
+	return map[string]interface{}{
+		"name":              name,
+		"aliases":           aliases,
+		"primary_muscles":   req.PrimaryMuscles,
+		"secondary_muscles": secondary,
+		"equipment":         nullIfEmpty(req.Equipment),
+		"movement_pattern":  nullIfEmpty(req.MovementPattern),
+		"unilateral":        req.Unilateral,
+	}, nil
+}
+
+// loadCatalog returns the built-in exercises together with the user's
+// custom exercises
+func loadCatalog(db database.Store, userID interface{}) (*catalog.Catalog, error) {
+	builtin, err := database.All[models.Exercise](database.From(db, "exercises").Is("user_id", nil))
+	if err != nil {
+		return nil, err
+	}
+	custom, err := database.All[models.Exercise](database.From(db, "exercises").Eq("user_id", userID))
+	if err != nil {
+		return nil, err
+	}
+	return catalog.New(append(custom, builtin...)), nil
+}
+
+// catalogLink returns the catalog link and display name of the exercise at
+// position, counting from 1. A given exercise_id must be in the catalog and
+// provides the name when there is none; otherwise the name is required and
+// links the exercise it resolves to, if any.
+func catalogLink(exercises *catalog.Catalog, position int, exerciseID *string, name string) (*string, string, error) {
+	name = strings.TrimSpace(name)
+	if exerciseID != nil {
+		linked, ok := exercises.Get(*exerciseID)
+		if !ok {
+			return nil, "", fmt.Errorf("exercise %d: exercise_id %s is not in the exercise catalog", position, *exerciseID)
+		}
+		if name == "" {
+			name = linked.Name
+		}
+		return exerciseID, name, nil
+	}
+
+	if name == "" {
+		return nil, "", fmt.Errorf("exercise %d needs a name or an exercise_id", position)
+	}
+	if resolved, ok := exercises.Resolve(name); ok {
+		return &resolved.ID, name, nil
+	}
+	return nil, name, nil
+}
+
+// exerciseKey returns the key, as returned by keyOf, grouping the logged
+// exercises idOrName refers to, and their catalog exercise if there is one
+func exerciseKey(exercises *catalog.Catalog, keyOf func(models.WorkoutExercise) string, idOrName string) (string, *models.Exercise) {
+	if exercise, ok := exercises.Get(idOrName); ok {
+		return exercise.ID, exercise
+	}
+	key := keyOf(models.WorkoutExercise{Name: idOrName})
+	exercise, _ := exercises.Get(key)
+	return key, exercise
+}
+
+// exerciseKeys returns a function grouping logged exercises by the catalog
+// exercise they refer to, or by name for exercises missing from the catalog
+func exerciseKeys(exercises *catalog.Catalog) func(models.WorkoutExercise) string {
+	return func(exercise models.WorkoutExercise) string {
+		if e, ok := exercises.ExerciseOf(exercise); ok {
+			return e.ID
+		}
+		return "name:" + analytics.NameKey(exercise.Name)
+	}
+}
+
+// exerciseWorkouts loads the user's workouts containing an exercise for which
+// keyOf returns one of keys, with their exercises and sets. Names resolve to
+// catalog exercises in Go, so the workouts are found from the exercises'
+// links and names alone before loading their sets.
+func exerciseWorkouts(db database.Store, userID interface{}, keyOf func(models.WorkoutExercise) string, keys ...string) ([]models.Workout, error) {
+	logged, err := database.All[models.Workout](database.From(db, "workout_sessions").
+		Select("id, exercises:workout_exercises(exercise_id, name)").
+		Eq("user_id", userID))
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []interface{}
+	for _, workout := range logged {
+		for _, exercise := range workout.Exercises {
+			if contains(keys, keyOf(exercise)) {
+				ids = append(ids, workout.ID)
+				break
+			}
+		}
+	}
+
+	workouts := make([]models.Workout, 0, len(ids))
+	for start := 0; start < len(ids); start += exerciseWorkoutsBatch {
+		end := min(start+exerciseWorkoutsBatch, len(ids))
+		batch, err := database.All[models.Workout](workoutTree(db, userID).In("id", ids[start:end]...))
+		if err != nil {
+			return nil, err
+		}
+		workouts = append(workouts, batch...)
+	}
+	return workouts, nil
+}
+
+// newPersonalRecords returns the personal records broken by workout, compared
+// with the user's other workouts of the same exercises
+func newPersonalRecords(db database.Store, userID interface{}, workout models.Workout, formula string) ([]models.PersonalRecord, error) {
+	exercises, err := loadCatalog(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	keyOf := exerciseKeys(exercises)
+	var keys []string
+	for _, exercise := range workout.Exercises {
+		keys = append(keys, keyOf(exercise))
+	}
+	workouts, err := exerciseWorkouts(db, userID, keyOf, keys...)
+	if err != nil {
+		return nil, err
+	}
+	history := make([]models.Workout, 0, len(workouts))
+	for _, w := range workouts {
+		if w.ID != workout.ID {
+			history = append(history, w)
+		}
+	}
+
+	broken := make([]models.PersonalRecord, 0)
+	seen := make(map[string]bool)
+	for _, exercise := range workout.Exercises {
+		key := keyOf(exercise)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		name := exercise.Name
+		if e, ok := exercises.Get(key); ok {
+			name = e.Name
+		}
+		records := analytics.NewRecords(name, formula)
+		for _, performance := range analytics.ExerciseHistory(history, key, keyOf) {
+			records.Add(performance)
+		}
+		for _, performance := range analytics.ExerciseHistory([]models.Workout{workout}, key, keyOf) {
+			broken = append(broken, records.Add(performance)...)
+		}
+	}
+	return broken, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hadiabbas/fittrack-backend/internal/catalog"
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+)
+
+func TestExerciseProgress(t *testing.T) {
+	s := newTestServer(t)
+	if err := catalog.Seed(s.db); err != nil {
+		t.Fatalf("seeding catalog: %v", err)
+	}
+	session := s.register("lifter@example.com", "password123")
+	start := time.Date(2024, 3, 4, 18, 0, 0, 0, time.UTC)
+
+	s.expect(s.do(http.MethodPost, "/workouts", session.Token, benchWorkout("Push", start)), http.StatusCreated, nil)
+	legs := benchWorkout("Legs", start.AddDate(0, 0, 1))
+	legs.Exercises[0].Name = "Squat"
+	s.expect(s.do(http.MethodPost, "/workouts", session.Token, legs), http.StatusCreated, nil)
+
+	// "bench" is an alias of the same catalog exercise as "Bench Press"
+	heavier := benchWorkout("Push", start.AddDate(0, 0, 2))
+	heavier.Exercises[0].Name = "bench"
+	heavier.Exercises[0].Sets[1].Weight = ptr(70.0)
+	var created struct {
+		PersonalRecords []models.PersonalRecord `json:"personal_records"`
+	}
+	s.expect(s.do(http.MethodPost, "/workouts", session.Token, heavier), http.StatusCreated, &created)
+	broke := false
+	for _, record := range created.PersonalRecords {
+		if record.Type == models.RecordHeaviestWeight && record.Previous != nil && *record.Previous == 62.5 {
+			broke = true
+		}
+	}
+	if !broke {
+		t.Errorf("personal_records = %+v, want the heaviest weight of 62.5 kg broken", created.PersonalRecords)
+	}
+
+	benchID := catalog.BuiltinID("barbell-bench-press")
+	for _, idOrName := range []string{"Bench%20Press", benchID} {
+		var progress models.ExerciseProgress
+		s.expect(s.do(http.MethodGet, "/exercises/"+idOrName+"/progress", session.Token, nil), http.StatusOK, &progress)
+		if progress.ExerciseID == nil || *progress.ExerciseID != benchID {
+			t.Errorf("%s: exercise_id = %v, want %s", idOrName, progress.ExerciseID, benchID)
+		}
+		if len(progress.Sessions) != 2 {
+			t.Errorf("%s: %d sessions, want the 2 bench workouts", idOrName, len(progress.Sessions))
+		}
+	}
+}
+
+func TestCustomExerciseNamesAreUnique(t *testing.T) {
+	s := newTestServer(t)
+	session := s.register("lifter@example.com", "password123")
+	other := s.register("other@example.com", "password123")
+	exercise := func(name string) models.ExerciseRequest {
+		return models.ExerciseRequest{Name: name, PrimaryMuscles: []string{"forearms"}}
+	}
+
+	var carry, yoke models.Exercise
+	s.expect(s.do(http.MethodPost, "/exercises", session.Token, exercise("Zercher Carry")), http.StatusCreated, &carry)
+	s.expect(s.do(http.MethodPost, "/exercises", session.Token, exercise("Yoke Walk")), http.StatusCreated, &yoke)
+
+	// Names compare case-insensitively after collapsing spaces
+	s.expect(s.do(http.MethodPost, "/exercises", session.Token, exercise("zercher  carry")), http.StatusConflict, nil)
+	s.expect(s.do(http.MethodPut, "/exercises/"+yoke.ID, session.Token, exercise("ZERCHER CARRY")), http.StatusConflict, nil)
+
+	// Keeping its own name, or taking a name another user has, is allowed
+	s.expect(s.do(http.MethodPut, "/exercises/"+carry.ID, session.Token, exercise("Zercher carry")), http.StatusOK, nil)
+	s.expect(s.do(http.MethodPost, "/exercises", other.Token, exercise("Zercher Carry")), http.StatusCreated, nil)
+}
@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hadiabbas/fittrack-backend/internal/analytics"
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+	"github.com/hadiabbas/fittrack-backend/pkg/database"
+	"github.com/hadiabbas/fittrack-backend/pkg/nutrition"
+)
+
+type FoodHandler struct {
+	DB     database.Store
+	Parser nutrition.Parser
+}
+
+func NewFoodHandler(db database.Store, parser nutrition.Parser) *FoodHandler {
+	return &FoodHandler{DB: db, Parser: parser}
+}
+
+// ParseText estimates nutrition for a free-text meal description and logs it
+func (h *FoodHandler) ParseText(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req models.ParseTextRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loc, ok := userTimeZone(c, h.DB)
+	if !ok {
+		return
+	}
+
+	// Parse the description into foods
+	result, err := h.Parser.Parse(c.Request.Context(), req.Query)
+	if errors.Is(err, nutrition.ErrNothingRecognised) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Could not recognise any food in the description"})
+		return
+	}
+	if err != nil {
+		log.Printf("parsing food description: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to parse food"})
+		return
+	}
+
+	logDate := today(loc)
+	if req.LogDate != nil {
+		logDate = *req.LogDate
+	}
+
+	foodData := map[string]interface{}{
+		"id":                  uuid.New().String(),
+		"user_id":             userID,
+		"log_date":            logDate,
+		"meal_type":           req.MealType,
+		"source_text":         req.Query,
+		"calories_estimated":  result.Calories,
+		"protein_g":           result.Protein,
+		"fat_g":               result.Fat,
+		"carbs_g":             result.Carbs,
+		"ai_confidence_score": result.Confidence,
+		"created_at":          time.Now(),
+	}
+
+	// Insert food log
+	foodResp, err := userStore(c, h.DB).Insert("food_logs", foodData, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save food log: " + err.Error()})
+		return
+	}
+
+	var logs []models.FoodLog
+	if err := json.Unmarshal(foodResp, &logs); err != nil || len(logs) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse food log"})
+		return
+	}
+
+	items := make([]models.ParsedFoodItem, 0, len(result.Items))
+	for _, item := range result.Items {
+		items = append(items, models.ParsedFoodItem(item))
+	}
+
+	c.JSON(http.StatusCreated, models.ParseTextResponse{
+		FoodLog: logs[0],
+		Items:   items,
+		Nutrition: models.NutritionInfo{
+			Calories:   result.Calories,
+			Protein:    result.Protein,
+			Fat:        result.Fat,
+			Carbs:      result.Carbs,
+			Confidence: result.Confidence,
+		},
+		Parser: result.Parser,
+	})
+}
+
+// CreateFoodLog logs a manually entered food item
+func (h *FoodHandler) CreateFoodLog(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req models.FoodLogRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loc, ok := userTimeZone(c, h.DB)
+	if !ok {
+		return
+	}
+
+	foodData := foodLogData(req, loc)
+	foodData["id"] = uuid.New().String()
+	foodData["user_id"] = userID
+	foodData["ai_confidence_score"] = 1.0 // Entered by the user, not estimated
+	foodData["created_at"] = time.Now()
+
+	foodResp, err := userStore(c, h.DB).Insert("food_logs", foodData, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save food log: " + err.Error()})
+		return
+	}
+
+	var logs []models.FoodLog
+	if err := json.Unmarshal(foodResp, &logs); err != nil || len(logs) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse food log"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, logs[0])
+}
+
+// GetFoodLogs lists food logs within a date range, optionally for one meal type
+func (h *FoodHandler) GetFoodLogs(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	loc, ok := userTimeZone(c, h.DB)
+	if !ok {
+		return
+	}
+
+	from, to, err := dateRange(c, loc, 30)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := h.foodLogs(c, userID, from, to)
+	if mealType := c.Query("meal_type"); mealType != "" {
+		if !validMealType(mealType) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "meal_type must be one of breakfast, lunch, dinner, snack"})
+			return
+		}
+		query.Eq("meal_type", mealType)
+	}
+
+	logs, err := database.All[models.FoodLog](query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch food logs: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, logs)
+}
+
+// GetFoodLog retrieves a specific food log
+func (h *FoodHandler) GetFoodLog(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	foodLog, err := h.findFoodLog(c, userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch food log: " + err.Error()})
+		return
+	}
+	if foodLog == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Food log not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, foodLog)
+}
+
+// UpdateFoodLog replaces the editable fields of a food log
+func (h *FoodHandler) UpdateFoodLog(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	foodLogID := c.Param("id")
+
+	var req models.FoodLogRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loc, ok := userTimeZone(c, h.DB)
+	if !ok {
+		return
+	}
+
+	// First verify the food log belongs to this user
+	existing, err := h.findFoodLog(c, userID, foodLogID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify food log"})
+		return
+	}
+	if existing == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Food log not found"})
+		return
+	}
+
+	foodData := foodLogData(req, loc)
+	if req.LogDate == nil {
+		foodData["log_date"] = existing.LogDate
+	}
+
+	foodResp, err := userStore(c, h.DB).Update("food_logs", foodLogID, foodData, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update food log: " + err.Error()})
+		return
+	}
+
+	var logs []models.FoodLog
+	if err := json.Unmarshal(foodResp, &logs); err != nil || len(logs) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse food log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, logs[0])
+}
+
+// DeleteFoodLog deletes a food log
+func (h *FoodHandler) DeleteFoodLog(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	foodLogID := c.Param("id")
+
+	// First verify the food log belongs to this user
+	existing, err := h.findFoodLog(c, userID, foodLogID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify food log"})
+		return
+	}
+	if existing == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Food log not found"})
+		return
+	}
+
+	if err := userStore(c, h.DB).Delete("food_logs", foodLogID, false); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete food log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Food log deleted successfully"})
+}
+
+// GetDailySummary totals calories and macros per calendar day (REQ-NUT-004)
+func (h *FoodHandler) GetDailySummary(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	loc, ok := userTimeZone(c, h.DB)
+	if !ok {
+		return
+	}
+
+	from, to, err := dateRange(c, loc, 7)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	logs, err := database.All[models.FoodLog](h.foodLogs(c, userID, from, to))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch food logs: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":     from,
+		"to":       to,
+		"timezone": loc.String(),
+		"days":     analytics.DailyNutrition(logs, from, to),
+	})
+}
+
+// foodLogs starts a query for the user's food logs within [from, to],
+// ordered by date and creation time
+func (h *FoodHandler) foodLogs(c *gin.Context, userID interface{}, from, to models.Date) *database.QueryBuilder {
+	return database.From(userStore(c, h.DB), "food_logs").
+		Eq("user_id", userID).
+		Gte("log_date", from).
+		Lte("log_date", to).
+		Order("log_date", false).
+		Order("created_at", false)
+}
+
+// findFoodLog returns the user's food log with the given id, or nil
+func (h *FoodHandler) findFoodLog(c *gin.Context, userID interface{}, id string) (*models.FoodLog, error) {
+	foodLog, err := database.One[models.FoodLog](database.From(userStore(c, h.DB), "food_logs").
+		Eq("id", id).
+		Eq("user_id", userID))
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, nil
+	}
+	return foodLog, err
+}
+
+// foodLogData converts a request into the editable food_logs columns
+func foodLogData(req models.FoodLogRequest, loc *time.Location) map[string]interface{} {
+	logDate := today(loc)
+	if req.LogDate != nil {
+		logDate = *req.LogDate
+	}
+
+	return map[string]interface{}{
+		"log_date":           logDate,
+		"meal_type":          req.MealType,
+		"source_text":        req.SourceText,
+		"calories_estimated": *req.Calories,
+		"protein_g":          req.ProteinG,
+		"fat_g":              req.FatG,
+		"carbs_g":            req.CarbsG,
+	}
+}
+
+// validMealType reports whether mealType is accepted by the food_logs table
+func validMealType(mealType string) bool {
+	switch mealType {
+	case "breakfast", "lunch", "dinner", "snack":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hadiabbas/fittrack-backend/internal/middleware"
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+	"github.com/hadiabbas/fittrack-backend/pkg/database"
+	"github.com/hadiabbas/fittrack-backend/pkg/mailer"
+	"github.com/hadiabbas/fittrack-backend/pkg/revocation"
+	"github.com/hadiabbas/fittrack-backend/pkg/utils"
+)
+
+// testServer is the API wired to the in-memory store, like main does with
+// DATABASE_BACKEND=memory
+type testServer struct {
+	t       *testing.T
+	router  *gin.Engine
+	db      *database.MemoryStore
+	keys    *utils.KeyManager
+	revoked revocation.Store
+	mail    *recordingMailer
+}
+
+// recordingMailer keeps the messages sent through it
+type recordingMailer struct {
+	mu   sync.Mutex
+	sent []mailer.Message
+}
+
+func (m *recordingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// waitForMail waits until n messages have been sent and returns them
+func (m *recordingMailer) waitForMail(t *testing.T, n int) []mailer.Message {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		m.mu.Lock()
+		sent := append([]mailer.Message(nil), m.sent...)
+		m.mu.Unlock()
+		if len(sent) >= n {
+			return sent
+		}
+	}
+	t.Fatalf("fewer than %d emails were sent", n)
+	return nil
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db := database.NewMemoryStore()
+	keys, err := utils.NewKeyManager("", utils.AlgorithmEdDSA, 0)
+	if err != nil {
+		t.Fatalf("creating key manager: %v", err)
+	}
+	revoked := revocation.NewMemoryStore()
+
+	router := gin.New()
+	auth := router.Group("/auth")
+	mail := &recordingMailer{}
+	authHandler := NewAuthHandler(db, keys, revoked, mail)
+	auth.POST("/register", authHandler.Register)
+	auth.POST("/login", authHandler.Login)
+	auth.POST("/refresh", authHandler.Refresh)
+	auth.POST("/logout", authHandler.Logout)
+	auth.POST("/logout-all", middleware.AuthMiddleware(keys, revoked, db), authHandler.LogoutAll)
+	auth.POST("/forgot-password", authHandler.ForgotPassword)
+	auth.POST("/reset-password", authHandler.ResetPassword)
+
+	protected := router.Group("")
+	protected.Use(middleware.AuthMiddleware(keys, revoked, db))
+	accountHandler := NewAccountHandler(db, revoked)
+	protected.DELETE("/me", accountHandler.DeleteAccount)
+	protected.GET("/me/export", accountHandler.ExportData)
+	profileHandler := NewProfileHandler(db)
+	protected.GET("/me/profile", profileHandler.GetProfile)
+	protected.PUT("/me/profile", profileHandler.UpdateProfile)
+	bodyMetricHandler := NewBodyMetricHandler(db)
+	protected.POST("/body-metrics", bodyMetricHandler.UpsertBodyMetric)
+	dashboardHandler := NewDashboardHandler(db)
+	protected.GET("/dashboard", dashboardHandler.GetDashboard)
+	exerciseHandler := NewExerciseHandler(db)
+	protected.POST("/exercises", exerciseHandler.CreateExercise)
+	protected.PUT("/exercises/:id", exerciseHandler.UpdateExercise)
+	protected.GET("/exercises/:id/progress", exerciseHandler.GetProgress)
+	workoutHandler := NewWorkoutHandler(db)
+	protected.POST("/workouts", workoutHandler.CreateWorkout)
+	protected.GET("/workouts", workoutHandler.GetWorkouts)
+	protected.GET("/workouts/:id", workoutHandler.GetWorkout)
+	protected.PUT("/workouts/:id", workoutHandler.UpdateWorkout)
+	protected.DELETE("/workouts/:id", workoutHandler.DeleteWorkout)
+
+	return &testServer{t: t, router: router, db: db, keys: keys, revoked: revoked, mail: mail}
+}
+
+// do sends a request with an optional bearer token and JSON body
+func (s *testServer) do(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	s.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatalf("encoding request body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// expect fails the test unless the response has the given status and decodes
+// its body into out when out is not nil
+func (s *testServer) expect(w *httptest.ResponseRecorder, status int, out interface{}) {
+	s.t.Helper()
+	if w.Code != status {
+		s.t.Fatalf("status = %d, want %d; body: %s", w.Code, status, w.Body.String())
+	}
+	if out != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			s.t.Fatalf("decoding response: %v; body: %s", err, w.Body.String())
+		}
+	}
+}
+
+// register creates an account and returns its first session
+func (s *testServer) register(email, password string) models.AuthResponse {
+	s.t.Helper()
+	var resp models.AuthResponse
+	body := models.RegisterRequest{Email: email, Password: password}
+	s.expect(s.do(http.MethodPost, "/auth/register", "", body), http.StatusCreated, &resp)
+	return resp
+}
+
+// login signs in and returns the new session
+func (s *testServer) login(email, password string) models.AuthResponse {
+	s.t.Helper()
+	var resp models.AuthResponse
+	body := models.LoginRequest{Email: email, Password: password}
+	s.expect(s.do(http.MethodPost, "/auth/login", "", body), http.StatusOK, &resp)
+	return resp
+}
+
+// startOfNextSecond waits until just after a wall-clock second begins, so the
+// steps that follow run within the same second as tokens' iat claims
+func startOfNextSecond() {
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+}
+
+func ptr[T any](v T) *T { return &v }
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hadiabbas/fittrack-backend/pkg/utils"
+)
+
+type KeysHandler struct {
+	Keys *utils.KeyManager
+}
+
+func NewKeysHandler(keys *utils.KeyManager) *KeysHandler {
+	return &KeysHandler{Keys: keys}
+}
+
+// GetJWKS publishes the public keys that verify FitTrack access tokens, so
+// other services can check tokens without sharing a secret
+func (h *KeysHandler) GetJWKS(c *gin.Context) {
+	// New keys are published KeyActivationDelay before they sign, which
+	// outlasts this cache
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(utils.JWKSMaxAge.Seconds())))
+	c.JSON(http.StatusOK, h.Keys.JWKS())
+}
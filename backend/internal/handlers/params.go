@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+	"github.com/hadiabbas/fittrack-backend/pkg/database"
+)
+
+// maxRangeDays caps date ranges so a single request cannot scan years of data
+const maxRangeDays = 366
+
+// userStore returns db acting as the authenticated user, using the Supabase
+// access token the auth middleware put in the context. Row level security
+// then rejects any row the user does not own, even if a handler forgets to
+// filter by user_id.
+func userStore(c *gin.Context, db database.Store) database.Store {
+	return db.WithAccessToken(c.GetString("supabase_token"))
+}
+
+// userTimeZone returns the time zone from the tz query parameter or the
+// X-Timezone header, else the time zone of the user's profile. It responds
+// with an error and returns false if the zone is invalid or the profile
+// cannot be fetched.
+func userTimeZone(c *gin.Context, db database.Store) (*time.Location, bool) {
+	var profile *models.Profile
+	if requestedTimeZone(c) == "" {
+		var err error
+		profile, err = loadProfile(userStore(c, db), c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profile: " + err.Error()})
+			return nil, false
+		}
+	}
+
+	loc, err := profileLocation(c, profile)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return loc, true
+}
+
+// profileLocation returns the time zone from the tz query parameter or the
+// X-Timezone header, else the time zone of profile, defaulting to UTC
+func profileLocation(c *gin.Context, profile *models.Profile) (*time.Location, error) {
+	name := requestedTimeZone(c)
+	if name == "" && profile != nil {
+		name = profile.TimeZone
+	}
+	if name == "" {
+		return time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q", name)
+	}
+	return loc, nil
+}
+
+// requestedTimeZone returns the time zone named by the request, if any
+func requestedTimeZone(c *gin.Context) string {
+	if name := c.Query("tz"); name != "" {
+		return name
+	}
+	return c.GetHeader("X-Timezone")
+}
+
+// today returns the current calendar date in loc
+func today(loc *time.Location) models.Date {
+	return models.NewDate(time.Now().In(loc))
+}
+
+// dateRange reads the inclusive from/to query parameters (YYYY-MM-DD). Missing
+// bounds default to the last days days ending today in loc.
+func dateRange(c *gin.Context, loc *time.Location, days int) (from, to models.Date, err error) {
+	to = today(loc)
+	if s := c.Query("to"); s != "" {
+		if to, err = models.ParseDate(s); err != nil {
+			return from, to, fmt.Errorf("invalid to date, expected YYYY-MM-DD")
+		}
+	}
+
+	from = models.Date{Time: to.AddDate(0, 0, -(days - 1))}
+	if s := c.Query("from"); s != "" {
+		if from, err = models.ParseDate(s); err != nil {
+			return from, to, fmt.Errorf("invalid from date, expected YYYY-MM-DD")
+		}
+	}
+
+	if from.After(to.Time) {
+		return from, to, fmt.Errorf("from must not be after to")
+	}
+	if to.Sub(from.Time) > maxRangeDays*24*time.Hour {
+		return from, to, fmt.Errorf("date range must not exceed %d days", maxRangeDays)
+	}
+	return from, to, nil
+}
+
+// inRange reports whether d lies within the inclusive range [from, to]
+func inRange(d, from, to models.Date) bool {
+	return !d.Before(from.Time) && !d.After(to.Time)
+}
+
+// intQuery reads an integer query parameter between lo and hi, returning def
+// when it is missing
+func intQuery(c *gin.Context, name string, def, lo, hi int) (int, error) {
+	s := c.Query(name)
+	if s == "" {
+		return def, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < lo || v > hi {
+		return 0, fmt.Errorf("%s must be an integer between %d and %d", name, lo, hi)
+	}
+	return v, nil
+}
+
+// floatQuery reads a number query parameter between lo and hi, returning def
+// when it is missing
+func floatQuery(c *gin.Context, name string, def, lo, hi float64) (float64, error) {
+	s := c.Query(name)
+	if s == "" {
+		return def, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < lo || v > hi {
+		return 0, fmt.Errorf("%s must be a number between %g and %g", name, lo, hi)
+	}
+	return v, nil
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+	"github.com/hadiabbas/fittrack-backend/pkg/database"
+)
+
+// clearableProfileColumns are the optional profile fields a request can
+// clear by sending null
+var clearableProfileColumns = []string{
+	"height_cm", "birth_date", "sex", "calorie_target", "protein_target_g", "goal_weight_kg",
+}
+
+type ProfileHandler struct {
+	DB database.Store
+}
+
+func NewProfileHandler(db database.Store) *ProfileHandler {
+	return &ProfileHandler{DB: db}
+}
+
+// GetProfile returns the user's profile, with defaults if it was never saved
+func (h *ProfileHandler) GetProfile(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	profile, err := loadProfile(userStore(c, h.DB), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profile"})
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// UpdateProfile saves the fields given in the request, keeping the others.
+// Optional fields sent as null are cleared.
+func (h *ProfileHandler) UpdateProfile(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req models.ProfileRequest
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// Both an omitted field and a null one decode to nil, so look at the
+	// body itself to tell them apart
+	var sent map[string]json.RawMessage
+	if body, ok := c.Get(gin.BodyBytesKey); ok {
+		json.Unmarshal(body.([]byte), &sent)
+	}
+
+	profileData := map[string]interface{}{
+		"user_id":    userID,
+		"updated_at": time.Now().UTC(),
+	}
+	if req.HeightCm != nil {
+		profileData["height_cm"] = *req.HeightCm
+	}
+	if req.BirthDate != nil {
+		if req.BirthDate.Year() < 1900 || req.BirthDate.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "birth_date must be between 1900 and today"})
+			return
+		}
+		profileData["birth_date"] = *req.BirthDate
+	}
+	if req.Sex != nil {
+		profileData["sex"] = *req.Sex
+	}
+	if req.UnitSystem != nil {
+		profileData["unit_system"] = *req.UnitSystem
+	}
+	if req.TimeZone != nil {
+		if _, err := time.LoadLocation(*req.TimeZone); err != nil || *req.TimeZone == "" || *req.TimeZone == "Local" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "time_zone must be an IANA time zone such as Europe/London"})
+			return
+		}
+		profileData["time_zone"] = *req.TimeZone
+	}
+	if req.CalorieTarget != nil {
+		profileData["calorie_target"] = *req.CalorieTarget
+	}
+	if req.ProteinTargetG != nil {
+		profileData["protein_target_g"] = *req.ProteinTargetG
+	}
+	if req.GoalWeightKg != nil {
+		profileData["goal_weight_kg"] = *req.GoalWeightKg
+	}
+	if req.E1RMFormula != nil {
+		profileData["e1rm_formula"] = *req.E1RMFormula
+	}
+	for _, column := range clearableProfileColumns {
+		if string(sent[column]) == "null" {
+			profileData[column] = nil
+		}
+	}
+
+	profileResp, err := userStore(c, h.DB).Upsert("user_profiles", profileData, "user_id", false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save profile: " + err.Error()})
+		return
+	}
+
+	var profiles []models.Profile
+	if err := json.Unmarshal(profileResp, &profiles); err != nil || len(profiles) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse profile"})
+		return
+	}
+	profile := withProfileDefaults(profiles[0])
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// loadProfile returns the stored profile of userID, or a default profile if
+// the user never saved one
+func loadProfile(db database.Store, userID string) (*models.Profile, error) {
+	profile, err := database.One[models.Profile](database.From(db, "user_profiles").Eq("user_id", userID))
+	if errors.Is(err, database.ErrNotFound) {
+		profile, err = &models.Profile{UserID: userID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	defaulted := withProfileDefaults(*profile)
+	return &defaulted, nil
+}
+
+// profileWeightUnit returns the weight unit of the user's unit system, used
+// for weights entered without a unit
+func profileWeightUnit(db database.Store, userID string) (string, error) {
+	profile, err := loadProfile(db, userID)
+	if err != nil {
+		return "", err
+	}
+	return weightUnitOf(profile), nil
+}
+
+// weightUnitOf returns the weight unit of a profile's unit system
+func weightUnitOf(profile *models.Profile) string {
+	if profile.UnitSystem == models.UnitSystemImperial {
+		return models.WeightUnitLb
+	}
+	return models.WeightUnitKg
+}
+
+// withProfileDefaults fills in the column defaults of user_profiles
+func withProfileDefaults(profile models.Profile) models.Profile {
+	if profile.UnitSystem == "" {
+		profile.UnitSystem = models.UnitSystemMetric
+	}
+	if profile.TimeZone == "" {
+		profile.TimeZone = "UTC"
+	}
+	if profile.E1RMFormula == "" {
+		profile.E1RMFormula = models.FormulaEpley
+	}
+	return profile
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+)
+
+func TestUpdateProfileClearsNullFields(t *testing.T) {
+	s := newTestServer(t)
+	session := s.register("lifter@example.com", "password123")
+
+	update := models.ProfileRequest{HeightCm: ptr(180.0), GoalWeightKg: ptr(75.0), UnitSystem: ptr(models.UnitSystemImperial)}
+	s.expect(s.do(http.MethodPut, "/me/profile", session.Token, update), http.StatusOK, nil)
+
+	var profile models.Profile
+	body := map[string]interface{}{"goal_weight_kg": nil, "unit_system": nil}
+	s.expect(s.do(http.MethodPut, "/me/profile", session.Token, body), http.StatusOK, &profile)
+	if profile.GoalWeightKg != nil {
+		t.Errorf("goal_weight_kg = %v, want it cleared", *profile.GoalWeightKg)
+	}
+	if profile.HeightCm == nil || *profile.HeightCm != 180 {
+		t.Errorf("height_cm = %v, want the omitted field kept at 180", profile.HeightCm)
+	}
+	if profile.UnitSystem != models.UnitSystemImperial {
+		t.Errorf("unit_system = %q, want a required field sent as null kept", profile.UnitSystem)
+	}
+
+	s.expect(s.do(http.MethodGet, "/me/profile", session.Token, nil), http.StatusOK, &profile)
+	if profile.GoalWeightKg != nil {
+		t.Errorf("stored goal_weight_kg = %v, want it cleared", *profile.GoalWeightKg)
+	}
+}
@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+	"github.com/hadiabbas/fittrack-backend/pkg/database"
+)
+
+// routineTreeSelect embeds a routine's exercises
+const routineTreeSelect = "*, exercises:routine_exercises(*)"
+
+type RoutineHandler struct {
+	DB database.Store
+}
+
+func NewRoutineHandler(db database.Store) *RoutineHandler {
+	return &RoutineHandler{DB: db}
+}
+
+// CreateRoutine creates a routine with its exercises
+func (h *RoutineHandler) CreateRoutine(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req models.RoutineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.prepareRoutine(c, &req, nil) {
+		return
+	}
+
+	// Create the routine and its exercises in a single transaction
+	routineID := uuid.New().String()
+	routineData := routinePayload(req)
+	routineData["id"] = routineID
+	routineData["user_id"] = userID
+
+	params := map[string]interface{}{
+		"p_routine": routineData,
+	}
+
+	if _, err := userStore(c, h.DB).RPC("create_routine", params, false); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create routine: " + err.Error()})
+		return
+	}
+
+	h.respondWithRoutine(c, userID, routineID, http.StatusCreated)
+}
+
+// GetRoutines retrieves all of the user's routines with their exercises, by name
+func (h *RoutineHandler) GetRoutines(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	routines, err := database.All[models.Routine](routineTree(userStore(c, h.DB), userID).Order("name", false))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch routines: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, routines)
+}
+
+// GetRoutine retrieves a single routine with its exercises
+func (h *RoutineHandler) GetRoutine(c *gin.Context) {
+	routine, ok := h.findRoutine(c, c.GetString("user_id"), c.Param("id"))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, routine)
+}
+
+// UpdateRoutine replaces a routine and its exercises. Exercises sent with
+// their id are updated in place, so workouts logged from them stay linked;
+// exercises left out are deleted.
+func (h *RoutineHandler) UpdateRoutine(c *gin.Context) {
+	userID := c.GetString("user_id")
+	routineID := c.Param("id")
+
+	var req models.RoutineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	routine, ok := h.findRoutine(c, userID, routineID)
+	if !ok {
+		return
+	}
+	if !h.prepareRoutine(c, &req, routine) {
+		return
+	}
+
+	// Replace the routine and its exercises in a single transaction; the
+	// function only touches the routine if it belongs to this user
+	params := map[string]interface{}{
+		"p_routine_id": routineID,
+		"p_user_id":    userID,
+		"p_routine":    routinePayload(req),
+	}
+
+	result, err := userStore(c, h.DB).RPC("replace_routine", params, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update routine: " + err.Error()})
+		return
+	}
+
+	var replaced bool
+	if err := json.Unmarshal(result, &replaced); err != nil || !replaced {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Routine not found"})
+		return
+	}
+
+	h.respondWithRoutine(c, userID, routineID, http.StatusOK)
+}
+
+// DeleteRoutine deletes a routine and its exercises. Workouts logged from it
+// are kept but no longer linked to it.
+func (h *RoutineHandler) DeleteRoutine(c *gin.Context) {
+	routineID := c.Param("id")
+
+	if _, ok := h.findRoutine(c, c.GetString("user_id"), routineID); !ok {
+		return
+	}
+
+	if err := userStore(c, h.DB).Delete("routines", routineID, false); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete routine"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Routine deleted successfully"})
+}
+
+// StartRoutine returns a workout pre-filled from a routine, ready to be
+// completed and posted to CreateWorkout. Nothing is saved.
+func (h *RoutineHandler) StartRoutine(c *gin.Context) {
+	routine, ok := h.findRoutine(c, c.GetString("user_id"), c.Param("id"))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, startWorkout(*routine, time.Now().UTC()))
+}
+
+// findRoutine returns the routine with its exercises if it exists and
+// belongs to userID, writing the error response and returning false
+// otherwise
+func (h *RoutineHandler) findRoutine(c *gin.Context, userID, routineID string) (*models.Routine, bool) {
+	routine, err := database.One[models.Routine](routineTree(userStore(c, h.DB), userID).Eq("id", routineID))
+	if errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Routine not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch routine"})
+		return nil, false
+	}
+
+	return routine, true
+}
+
+// respondWithRoutine writes the routine with its exercises
+func (h *RoutineHandler) respondWithRoutine(c *gin.Context, userID, routineID string, status int) {
+	routine, err := database.One[models.Routine](routineTree(userStore(c, h.DB), userID).Eq("id", routineID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch routine: " + err.Error()})
+		return
+	}
+
+	c.JSON(status, routine)
+}
+
+// prepareRoutine validates req and fills in its defaults, writing the error
+// response and returning false if it is invalid. Exercise ids must belong to
+// existing, the routine being replaced. Rep ranges default to a single rep
+// target, weights without a unit are in the unit system of the user's
+// profile, and exercises are linked to the catalog like logged ones.
+func (h *RoutineHandler) prepareRoutine(c *gin.Context, req *models.RoutineRequest, existing *models.Routine) bool {
+	db := userStore(c, h.DB)
+	userID := c.GetString("user_id")
+
+	req.Name = strings.Join(strings.Fields(req.Name), " ")
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return false
+	}
+	if req.ActivityType == "" {
+		req.ActivityType = "strength"
+	}
+	if len(req.Exercises) == 0 {
+		return true
+	}
+
+	current := make(map[string]bool)
+	if existing != nil {
+		for _, exercise := range existing.Exercises {
+			current[exercise.ID] = true
+		}
+	}
+
+	exercises, err := loadCatalog(db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exercises"})
+		return false
+	}
+
+	var defaultUnit string
+	seen := make(map[string]bool)
+	for i := range req.Exercises {
+		exercise := &req.Exercises[i]
+		if exercise.ID != "" {
+			if !current[exercise.ID] || seen[exercise.ID] {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("exercise %d: id %s is not an exercise of this routine", i+1, exercise.ID)})
+				return false
+			}
+			seen[exercise.ID] = true
+		}
+
+		exercise.ExerciseID, exercise.Name, err = catalogLink(exercises, i+1, exercise.ExerciseID, exercise.Name)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return false
+		}
+
+		switch {
+		case exercise.TargetRepsMax != nil && exercise.TargetRepsMin == nil:
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("exercise %d: target_reps_max needs target_reps_min", i+1)})
+			return false
+		case exercise.TargetRepsMax != nil && *exercise.TargetRepsMax < *exercise.TargetRepsMin:
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("exercise %d: target_reps_max must be at least target_reps_min", i+1)})
+			return false
+		case exercise.TargetRepsMin != nil && exercise.TargetRepsMax == nil:
+			exercise.TargetRepsMax = exercise.TargetRepsMin
+		}
+
+		if exercise.TargetWeight == nil {
+			exercise.WeightUnit = ""
+			continue
+		}
+		if exercise.WeightUnit == "" {
+			if defaultUnit == "" {
+				if defaultUnit, err = profileWeightUnit(db, userID); err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profile"})
+					return false
+				}
+			}
+			exercise.WeightUnit = defaultUnit
+		}
+	}
+	return true
+}
+
+// routinePayload converts a request into the nested routine document
+// accepted by the create_routine and replace_routine database functions
+func routinePayload(req models.RoutineRequest) map[string]interface{} {
+	exercises := make([]map[string]interface{}, 0, len(req.Exercises))
+	for _, exercise := range req.Exercises {
+		exercises = append(exercises, map[string]interface{}{
+			"id":              nullIfEmpty(exercise.ID),
+			"exercise_id":     exercise.ExerciseID,
+			"name":            exercise.Name,
+			"notes":           exercise.Notes,
+			"target_sets":     exercise.TargetSets,
+			"target_reps_min": exercise.TargetRepsMin,
+			"target_reps_max": exercise.TargetRepsMax,
+			"target_weight":   exercise.TargetWeight,
+			"weight_unit":     nullIfEmpty(exercise.WeightUnit),
+			"target_rpe":      exercise.TargetRPE,
+		})
+	}
+
+	return map[string]interface{}{
+		"name":          req.Name,
+		"notes":         req.Notes,
+		"activity_type": req.ActivityType,
+		"exercises":     exercises,
+	}
+}
+
+// startWorkout pre-fills a workout from a routine on date: every prescribed
+// exercise, linked back to the routine, with its target number of working
+// sets at the bottom of the rep range, the target weight and the target RPE.
+// The duration and overall RPE are left for the user to fill in.
+func startWorkout(routine models.Routine, date time.Time) models.CreateWorkoutRequest {
+	workout := models.CreateWorkoutRequest{
+		WorkoutName:  routine.Name,
+		WorkoutDate:  date,
+		ActivityType: routine.ActivityType,
+		Exercises:    make([]models.WorkoutExercise, 0, len(routine.Exercises)),
+	}
+
+	for _, prescribed := range routine.Exercises {
+		routineExerciseID := prescribed.ID
+		exercise := models.WorkoutExercise{
+			ExerciseID:        prescribed.ExerciseID,
+			RoutineExerciseID: &routineExerciseID,
+			Name:              prescribed.Name,
+			Notes:             prescribed.Notes,
+			Order:             prescribed.Order,
+			Sets:              make([]models.WorkoutSet, 0, prescribed.TargetSets),
+		}
+		for i := 0; i < prescribed.TargetSets; i++ {
+			exercise.Sets = append(exercise.Sets, models.WorkoutSet{
+				Weight:     prescribed.TargetWeight,
+				WeightUnit: prescribed.WeightUnit,
+				Reps:       prescribed.TargetRepsMin,
+				SetType:    models.SetTypeWorking,
+				RPE:        prescribed.TargetRPE,
+				Order:      i,
+			})
+		}
+		workout.Exercises = append(workout.Exercises, exercise)
+	}
+	return workout
+}
+
+// routineTree starts a query for a user's routines with their exercises
+// embedded in order
+func routineTree(db database.Store, userID interface{}) *database.QueryBuilder {
+	return database.From(db, "routines").
+		Select(routineTreeSelect).
+		Eq("user_id", userID).
+		OrderRelation("exercises", "order", false)
+}
+
+// loadRoutineExercises returns the exercises of all of the user's routines
+// by id
+func loadRoutineExercises(db database.Store, userID interface{}) (map[string]models.RoutineExercise, error) {
+	routines, err := database.All[models.Routine](database.From(db, "routines").
+		Select("id, exercises:routine_exercises(*)").
+		Eq("user_id", userID))
+	if err != nil {
+		return nil, err
+	}
+
+	exercises := make(map[string]models.RoutineExercise)
+	for _, routine := range routines {
+		for _, exercise := range routine.Exercises {
+			exercises[exercise.ID] = exercise
+		}
+	}
+	return exercises, nil
+}
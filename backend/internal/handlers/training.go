@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hadiabbas/fittrack-backend/internal/analytics"
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+	"github.com/hadiabbas/fittrack-backend/pkg/database"
+)
+
+// trainingLoadSelect is every column session loads are computed from
+const trainingLoadSelect = "id, workout_date, duration_hours, duration_minutes, overall_rpe"
+
+// defaultSecondaryWeight is the share of a set credited to the secondary
+// muscles of an exercise
+const defaultSecondaryWeight = 0.5
+
+type TrainingHandler struct {
+	DB database.Store
+}
+
+func NewTrainingHandler(db database.Store) *TrainingHandler {
+	return &TrainingHandler{DB: db}
+}
+
+// GetTrainingLoad returns the daily and weekly training load for the from/to
+// range (default: the last 90 days) in the user's time zone, with the
+// acute:chronic workload ratio, monotony and strain
+func (h *TrainingHandler) GetTrainingLoad(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	loc, ok := userTimeZone(c, h.DB)
+	if !ok {
+		return
+	}
+
+	from, to, err := dateRange(c, loc, 90)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Earlier workouts feed the rolling loads and tell whether the history
+	// is long enough for a ratio
+	workouts, err := database.All[models.Workout](database.From(userStore(c, h.DB), "workout_sessions").
+		Select(trainingLoadSelect).
+		Eq("user_id", userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch workouts: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TrainingLoad{
+		From:   from,
+		To:     to,
+		Daily:  analytics.DailyLoad(workouts, loc, from, to),
+		Weekly: analytics.WeeklyLoad(workouts, loc, from, to),
+	})
+}
+
+// GetMuscleVolume returns the hard sets and tonnage per muscle group per
+// week for the from/to range (default: the last 28 days) in the user's time
+// zone. Exercises are mapped to muscles through the exercise catalog, and
+// secondary muscles are credited with secondary_weight (0 to 1) of each set.
+func (h *TrainingHandler) GetMuscleVolume(c *gin.Context) {
+	userID := c.GetString("user_id")
+	db := userStore(c, h.DB)
+
+	loc, ok := userTimeZone(c, h.DB)
+	if !ok {
+		return
+	}
+
+	from, to, err := dateRange(c, loc, 28)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	secondaryWeight, err := floatQuery(c, "secondary_weight", defaultSecondaryWeight, 0, 1)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	exercises, err := loadCatalog(db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exercises: " + err.Error()})
+		return
+	}
+
+	workouts, err := database.All[models.Workout](workoutTree(db, userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch workouts: " + err.Error()})
+		return
+	}
+
+	muscleOf := func(logged models.WorkoutExercise) ([]string, []string, bool) {
+		exercise, ok := exercises.ExerciseOf(logged)
+		if !ok {
+			return nil, nil, false
+		}
+		return exercise.PrimaryMuscles, exercise.SecondaryMuscles, true
+	}
+	weeks, unmapped := analytics.WeeklyMuscleVolume(workouts, loc, from, to, muscleOf, secondaryWeight)
+
+	c.JSON(http.StatusOK, models.MuscleVolumeReport{
+		From:            from,
+		To:              to,
+		SecondaryWeight: secondaryWeight,
+		Weeks:           weeks,
+		Unmapped:        unmapped,
+	})
+}
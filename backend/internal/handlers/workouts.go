@@ -2,20 +2,45 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/hadiabbas/fittrack-backend/internal/models"
+	"github.com/hadiabbas/fittrack-backend/pkg/calories"
 	"github.com/hadiabbas/fittrack-backend/pkg/database"
 )
 
+// workoutTreeSelect embeds a workout's exercises and their sets
+const workoutTreeSelect = "*, exercises:workout_exercises(*, sets:workout_sets(*))"
+
+// Pagination limits for GetWorkouts
+const (
+	defaultWorkoutPageSize = 20
+	maxWorkoutPageSize     = 100
+)
+
+// workoutSortColumns are the columns GetWorkouts can sort by
+var workoutSortColumns = map[string]bool{
+	"workout_date":       true,
+	"workout_name":       true,
+	"overall_rpe":        true,
+	"duration_minutes":   true,
+	"estimated_calories": true,
+	"created_at":         true,
+}
+
 type WorkoutHandler struct {
-	DB *database.SupabaseClient
+	DB database.Store
 }
 
-func NewWorkoutHandler(db *database.SupabaseClient) *WorkoutHandler {
+func NewWorkoutHandler(db database.Store) *WorkoutHandler {
 	return &WorkoutHandler{DB: db}
 }
 
@@ -28,253 +53,564 @@ func (h *WorkoutHandler) CreateWorkout(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-
-	// Generate workout ID
-	workoutID := uuid.New().String()
-
-	// Create workout data for database
-	workoutData := map[string]interface{}{
-		"id":                 workoutID,
-		"user_id":            userID,
-		"workout_name":       req.WorkoutName,
-		"workout_date":       req.WorkoutDate,
-		"duration_hours":     req.DurationHours,
-		"duration_minutes":   req.DurationMinutes,
-		"overall_rpe":        req.OverallRPE,
-		"estimated_calories": req.EstimatedCalories,
-		"activity_type":      req.ActivityType,
-		"created_at":         time.Now(),
-		"updated_at":         time.Now(),
-	}
-
-	// Insert workout
-	_, err := h.DB.Insert("workout_sessions", workoutData, false)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create workout: " + err.Error()})
+	if !h.prepareSets(c, &req) || !h.linkExercises(c, &req) {
 		return
 	}
 
-	// Insert exercises and sets
-	for i, exercise := range req.Exercises {
-		exerciseID := uuid.New().String()
-		exerciseData := map[string]interface{}{
-			"id":         exerciseID,
-			"workout_id": workoutID,
-			"name":       exercise.Name,
-			"notes":      exercise.Notes,
-			"order":      i,
-		}
+	// Generate workout ID
+	workoutID := uuid.New().String()
 
-		_, err := h.DB.Insert("workout_exercises", exerciseData, false)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create exercise: " + err.Error()})
+	// Create workout, exercises and sets in a single transaction
+	workoutData := workoutPayload(req)
+	workoutData["id"] = workoutID
+	workoutData["user_id"] = userID
+	if req.EstimatedCalories == nil {
+		if err := h.estimateCalories(c, userID, requestSession(req), workoutData); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to estimate calories: " + err.Error()})
 			return
 		}
+	}
 
-		// Insert sets for this exercise
-		for _, set := range exercise.Sets {
-			setID := uuid.New().String()
-			setData := map[string]interface{}{
-				"id":          setID,
-				"exercise_id": exerciseID,
-				"weight":      set.Weight,
-				"reps":        set.Reps,
-				"rpe":         set.RPE,
-			}
+	params := map[string]interface{}{
+		"p_workout": workoutData,
+	}
 
-			_, err := h.DB.Insert("workout_sets", setData, false)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create set: " + err.Error()})
-				return
-			}
-		}
+	if _, err := userStore(c, h.DB).RPC("create_workout", params, false); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create workout: " + err.Error()})
+		return
+	}
+
+	// The workout is saved, so failing to compare it with the history only
+	// leaves the records out of the response
+	records, err := h.personalRecords(c, userID, workoutID, req)
+	if err != nil {
+		log.Printf("finding personal records of workout %s: %v", workoutID, err)
+		records = []models.PersonalRecord{}
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"id":      workoutID,
-		"message": "Workout created successfully",
+		"id":                 workoutID,
+		"estimated_calories": workoutData["estimated_calories"],
+		"personal_records":   records,
+		"message":            "Workout created successfully",
 	})
 }
 
-// GetWorkouts retrieves all workouts for a user
+// GetWorkouts retrieves a page of the user's workouts, optionally filtered
+// by date range, activity type and name. The total number of matching
+// workouts is returned in the X-Total-Count header.
 func (h *WorkoutHandler) GetWorkouts(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 
-	// Query workouts
-	query := map[string]interface{}{
-		"user_id": userID,
+	// Fetch workouts with their exercises and sets in a single request
+	loc, ok := userTimeZone(c, h.DB)
+	if !ok {
+		return
+	}
+	query := workoutTree(userStore(c, h.DB), userID)
+	if err := workoutListQuery(c, query, loc); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	workoutsData, err := h.DB.Query("workout_sessions", query, false)
+	workouts, total, err := database.Page[models.Workout](query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch workouts: " + err.Error()})
 		return
 	}
 
-	// Parse workouts
-	var workouts []models.Workout
-	if err := json.Unmarshal(workoutsData, &workouts); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse workouts"})
+	opts := query.Options()
+	c.Header("X-Total-Count", strconv.Itoa(total))
+	c.Header("X-Limit", strconv.Itoa(opts.Limit))
+	c.Header("X-Offset", strconv.Itoa(opts.Offset))
+	c.JSON(http.StatusOK, workouts)
+}
+
+// GetWorkout retrieves a specific workout
+func (h *WorkoutHandler) GetWorkout(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	workoutID := c.Param("id")
+
+	// Fetch the workout with its exercises and sets in a single request
+	workout, err := database.One[models.Workout](workoutTree(userStore(c, h.DB), userID).Eq("id", workoutID))
+	if errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workout not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch workout: " + err.Error()})
 		return
 	}
 
-	// Fetch exercises for each workout
-	for i := range workouts {
-		exerciseQuery := map[string]interface{}{
-			"workout_id": workouts[i].ID,
-		}
+	c.JSON(http.StatusOK, workout)
+}
 
-		exercisesData, err := h.DB.Query("workout_exercises", exerciseQuery, false)
-		if err != nil {
-			continue
-		}
+// UpdateWorkout replaces a workout, including all of its exercises and sets
+func (h *WorkoutHandler) UpdateWorkout(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	workoutID := c.Param("id")
 
-		var exercises []models.WorkoutExercise
-		if err := json.Unmarshal(exercisesData, &exercises); err != nil {
-			continue
+	var req models.CreateWorkoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.prepareSets(c, &req) || !h.linkExercises(c, &req) {
+		return
+	}
+
+	workoutData := workoutPayload(req)
+	if req.EstimatedCalories == nil {
+		if err := h.estimateCalories(c, userID, requestSession(req), workoutData); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to estimate calories: " + err.Error()})
+			return
 		}
+	}
 
-		// Fetch sets for each exercise
-		for j := range exercises {
-			setQuery := map[string]interface{}{
-				"exercise_id": exercises[j].ID,
-			}
+	// Replace session, exercises and sets in a single transaction; the
+	// function only touches the workout if it belongs to this user
+	params := map[string]interface{}{
+		"p_workout_id": workoutID,
+		"p_user_id":    userID,
+		"p_workout":    workoutData,
+	}
 
-			setsData, err := h.DB.Query("workout_sets", setQuery, false)
-			if err != nil {
-				continue
-			}
+	result, err := userStore(c, h.DB).RPC("replace_workout", params, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update workout: " + err.Error()})
+		return
+	}
 
-			var sets []models.WorkoutSet
-			if err := json.Unmarshal(setsData, &sets); err != nil {
-				continue
-			}
+	var replaced bool
+	if err := json.Unmarshal(result, &replaced); err != nil || !replaced {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workout not found"})
+		return
+	}
+
+	h.respondWithWorkout(c, userID, workoutID)
+}
 
-			exercises[j].Sets = sets
+// PatchWorkout updates session fields of a workout, leaving exercises and sets untouched
+func (h *WorkoutHandler) PatchWorkout(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	workoutID := c.Param("id")
+
+	var req models.PatchWorkoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	workoutData := map[string]interface{}{}
+	if req.WorkoutName != nil {
+		workoutData["workout_name"] = *req.WorkoutName
+	}
+	if req.WorkoutDate != nil {
+		workoutData["workout_date"] = *req.WorkoutDate
+	}
+	if req.DurationHours != nil {
+		workoutData["duration_hours"] = *req.DurationHours
+	}
+	if req.DurationMinutes != nil {
+		workoutData["duration_minutes"] = *req.DurationMinutes
+	}
+	if req.OverallRPE != nil {
+		workoutData["overall_rpe"] = *req.OverallRPE
+	}
+	if req.EstimatedCalories != nil {
+		workoutData["estimated_calories"] = *req.EstimatedCalories
+		workoutData["calorie_formula_version"] = nil
+	}
+	if req.ActivityType != nil {
+		workoutData["activity_type"] = *req.ActivityType
+	}
+	if len(workoutData) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No fields to update"})
+		return
+	}
+	workoutData["updated_at"] = time.Now()
+
+	// First verify the workout belongs to this user
+	current, ok := h.findOwnedWorkout(c, userID, workoutID)
+	if !ok {
+		return
+	}
+
+	// Estimated calories follow the session they were estimated from
+	if req.EstimatedCalories == nil && current.CalorieFormulaVersion != nil {
+		if err := h.estimateCalories(c, userID, patchedSession(*current, req), workoutData); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to estimate calories: " + err.Error()})
+			return
 		}
+	}
 
-		workouts[i].Exercises = exercises
+	if _, err := userStore(c, h.DB).Update("workout_sessions", workoutID, workoutData, false); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update workout: " + err.Error()})
+		return
 	}
 
-	c.JSON(http.StatusOK, workouts)
+	h.respondWithWorkout(c, userID, workoutID)
 }
 
-// GetWorkout retrieves a specific workout
-func (h *WorkoutHandler) GetWorkout(c *gin.Context) {
+// DeleteWorkout deletes a workout
+func (h *WorkoutHandler) DeleteWorkout(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	workoutID := c.Param("id")
 
-	// Query specific workout
-	query := map[string]interface{}{
-		"id":      workoutID,
-		"user_id": userID,
+	// Delete the workout if it belongs to this user; exercises and sets are
+	// removed by the cascading foreign keys in the same transaction
+	params := map[string]interface{}{
+		"p_workout_id": workoutID,
+		"p_user_id":    userID,
 	}
 
-	workoutData, err := h.DB.Query("workout_sessions", query, false)
+	result, err := userStore(c, h.DB).RPC("delete_workout", params, false)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch workout: " + err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete workout"})
 		return
 	}
 
-	var workouts []models.Workout
-	if err := json.Unmarshal(workoutData, &workouts); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse workout"})
+	var deleted bool
+	if err := json.Unmarshal(result, &deleted); err != nil || !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workout not found"})
 		return
 	}
 
-	if len(workouts) == 0 {
+	c.JSON(http.StatusOK, gin.H{"message": "Workout deleted successfully"})
+}
+
+// findOwnedWorkout returns the session fields of the workout if it exists
+// and belongs to userID, writing the error response and returning false
+// otherwise
+func (h *WorkoutHandler) findOwnedWorkout(c *gin.Context, userID interface{}, workoutID string) (*models.Workout, bool) {
+	workout, err := database.One[models.Workout](database.From(userStore(c, h.DB), "workout_sessions").
+		Eq("id", workoutID).
+		Eq("user_id", userID))
+	if errors.Is(err, database.ErrNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Workout not found"})
-		return
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify workout"})
+		return nil, false
 	}
 
-	workout := workouts[0]
+	return workout, true
+}
 
-	// Fetch exercises
-	exerciseQuery := map[string]interface{}{
-		"workout_id": workout.ID,
+// personalRecords returns the personal records broken by the workout created
+// from req, using the user's e1RM formula
+func (h *WorkoutHandler) personalRecords(c *gin.Context, userID interface{}, workoutID string, req models.CreateWorkoutRequest) ([]models.PersonalRecord, error) {
+	db := userStore(c, h.DB)
+	profile, err := loadProfile(db, c.GetString("user_id"))
+	if err != nil {
+		return nil, err
 	}
 
-	exercisesData, err := h.DB.Query("workout_exercises", exerciseQuery, false)
-	if err == nil {
-		var exercises []models.WorkoutExercise
-		if err := json.Unmarshal(exercisesData, &exercises); err == nil {
-			// Fetch sets for each exercise
-			for j := range exercises {
-				setQuery := map[string]interface{}{
-					"exercise_id": exercises[j].ID,
-				}
+	workout := requestSession(req)
+	workout.ID = workoutID
+	workout.Exercises = req.Exercises
+	return newPersonalRecords(db, userID, workout, profile.E1RMFormula)
+}
 
-				setsData, err := h.DB.Query("workout_sets", setQuery, false)
-				if err == nil {
-					var sets []models.WorkoutSet
-					if err := json.Unmarshal(setsData, &sets); err == nil {
-						exercises[j].Sets = sets
-					}
-				}
-			}
-			workout.Exercises = exercises
-		}
+// estimateCalories estimates the calories burned in workout from its MET
+// value and the user's body weight, storing them in workoutData together
+// with the formula version
+func (h *WorkoutHandler) estimateCalories(c *gin.Context, userID interface{}, workout models.Workout, workoutData map[string]interface{}) error {
+	weight, err := h.bodyWeightOn(c, userID, models.NewDate(workout.WorkoutDate))
+	if err != nil {
+		return err
 	}
 
-	c.JSON(http.StatusOK, workout)
+	workoutData["estimated_calories"] = calories.Estimate(calories.Workout{
+		ActivityType: workout.ActivityType,
+		Name:         workout.WorkoutName,
+		RPE:          workout.OverallRPE,
+		Duration:     time.Duration(workout.DurationHours*60+workout.DurationMinutes) * time.Minute,
+	}, weight)
+	workoutData["calorie_formula_version"] = calories.FormulaVersion
+	return nil
 }
 
-// DeleteWorkout deletes a workout
-func (h *WorkoutHandler) DeleteWorkout(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	workoutID := c.Param("id")
+// bodyWeightOn returns the user's latest weigh-in on or before date, the
+// first one after it if there is none, or 0 if the user never logged weight
+func (h *WorkoutHandler) bodyWeightOn(c *gin.Context, userID interface{}, date models.Date) (float64, error) {
+	weighIns := func() *database.QueryBuilder {
+		return database.From(userStore(c, h.DB), "body_metrics").
+			Select("body_weight_kg").
+			Eq("user_id", userID).
+			IsNot("body_weight_kg", nil).
+			Limit(1)
+	}
 
-	// First verify the workout belongs to this user
-	query := map[string]interface{}{
-		"id":      workoutID,
-		"user_id": userID,
+	metrics, err := database.All[models.BodyMetric](weighIns().Lte("log_date", date).Order("log_date", true))
+	if err == nil && len(metrics) == 0 {
+		metrics, err = database.All[models.BodyMetric](weighIns().Gt("log_date", date).Order("log_date", false))
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(metrics) == 0 || metrics[0].BodyWeightKg == nil {
+		return 0, nil
+	}
+	return *metrics[0].BodyWeightKg, nil
+}
+
+// requestSession returns the session fields of a create or replace request
+func requestSession(req models.CreateWorkoutRequest) models.Workout {
+	return models.Workout{
+		WorkoutName:     req.WorkoutName,
+		WorkoutDate:     req.WorkoutDate,
+		DurationHours:   req.DurationHours,
+		DurationMinutes: req.DurationMinutes,
+		OverallRPE:      req.OverallRPE,
+		ActivityType:    req.ActivityType,
+	}
+}
+
+// patchedSession applies the session fields of a patch to workout
+func patchedSession(workout models.Workout, req models.PatchWorkoutRequest) models.Workout {
+	if req.WorkoutName != nil {
+		workout.WorkoutName = *req.WorkoutName
+	}
+	if req.WorkoutDate != nil {
+		workout.WorkoutDate = *req.WorkoutDate
+	}
+	if req.DurationHours != nil {
+		workout.DurationHours = *req.DurationHours
+	}
+	if req.DurationMinutes != nil {
+		workout.DurationMinutes = *req.DurationMinutes
 	}
+	if req.OverallRPE != nil {
+		workout.OverallRPE = *req.OverallRPE
+	}
+	if req.ActivityType != nil {
+		workout.ActivityType = *req.ActivityType
+	}
+	return workout
+}
 
-	workoutData, err := h.DB.Query("workout_sessions", query, false)
+// respondWithWorkout writes the workout with its exercises and sets
+func (h *WorkoutHandler) respondWithWorkout(c *gin.Context, userID interface{}, workoutID string) {
+	workout, err := database.One[models.Workout](workoutTree(userStore(c, h.DB), userID).Eq("id", workoutID))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify workout"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch workout: " + err.Error()})
 		return
 	}
 
-	var workouts []models.Workout
-	if err := json.Unmarshal(workoutData, &workouts); err != nil || len(workouts) == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Workout not found"})
-		return
+	c.JSON(http.StatusOK, workout)
+}
+
+// workoutTree starts a query for a user's workouts with their exercises and
+// sets embedded in logged order
+func workoutTree(db database.Store, userID interface{}) *database.QueryBuilder {
+	return database.From(db, "workout_sessions").
+		Select(workoutTreeSelect).
+		Eq("user_id", userID).
+		OrderRelation("exercises", "order", false).
+		OrderRelation("exercises.sets", "order", false)
+}
+
+// prepareSets checks that every set records something and fills in the
+// default set type and weight unit, writing the error response and
+// returning false if a set is invalid. Weights without a unit are in the
+// unit system of the user's profile.
+func (h *WorkoutHandler) prepareSets(c *gin.Context, req *models.CreateWorkoutRequest) bool {
+	var defaultUnit string
+	for i := range req.Exercises {
+		exercise := &req.Exercises[i]
+		for j := range exercise.Sets {
+			set := &exercise.Sets[j]
+			if set.Reps == nil && set.DistanceM == nil && set.DurationSeconds == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("set %d of %s needs reps, distance_m or duration_seconds", j+1, exercise.Name)})
+				return false
+			}
+			if set.SetType == "" {
+				set.SetType = models.SetTypeWorking
+			}
+			if set.Weight == nil {
+				set.WeightUnit = ""
+				continue
+			}
+			if set.WeightUnit == "" {
+				if defaultUnit == "" {
+					var err error
+					if defaultUnit, err = profileWeightUnit(userStore(c, h.DB), c.GetString("user_id")); err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profile"})
+						return false
+					}
+				}
+				set.WeightUnit = defaultUnit
+			}
+		}
 	}
+	return true
+}
 
-	// Delete exercises and sets (cascade will handle this in Supabase with proper FK setup)
-	// For now, manually delete them
-	exerciseQuery := map[string]interface{}{
-		"workout_id": workoutID,
+// linkExercises links every exercise of req to the catalog and checks that
+// routine exercises they refer to belong to the user, writing the error
+// response and returning false if one cannot be linked. Exercises without
+// a name take the one of their routine exercise, then the catalog name.
+func (h *WorkoutHandler) linkExercises(c *gin.Context, req *models.CreateWorkoutRequest) bool {
+	if len(req.Exercises) == 0 {
+		return true
 	}
+	db := userStore(c, h.DB)
 
-	exercisesData, _ := h.DB.Query("workout_exercises", exerciseQuery, false)
-	var exercises []models.WorkoutExercise
-	if json.Unmarshal(exercisesData, &exercises) == nil {
-		for _, exercise := range exercises {
-			// Delete sets
-			setQuery := map[string]interface{}{
-				"exercise_id": exercise.ID,
+	var prescribed map[string]models.RoutineExercise
+	for i := range req.Exercises {
+		exercise := &req.Exercises[i]
+		if exercise.RoutineExerciseID == nil {
+			continue
+		}
+		if prescribed == nil {
+			var err error
+			if prescribed, err = loadRoutineExercises(db, c.GetString("user_id")); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch routines"})
+				return false
 			}
-			setsData, _ := h.DB.Query("workout_sets", setQuery, false)
-			var sets []models.WorkoutSet
-			if json.Unmarshal(setsData, &sets) == nil {
-				for _, set := range sets {
-					h.DB.Delete("workout_sets", set.ID, false)
-				}
+		}
+		routineExercise, ok := prescribed[*exercise.RoutineExerciseID]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("exercise %d: routine_exercise_id %s is not in one of your routines", i+1, *exercise.RoutineExerciseID)})
+			return false
+		}
+		if strings.TrimSpace(exercise.Name) == "" {
+			exercise.Name = routineExercise.Name
+			if exercise.ExerciseID == nil {
+				exercise.ExerciseID = routineExercise.ExerciseID
 			}
-			// Delete exercise
-			h.DB.Delete("workout_exercises", exercise.ID, false)
 		}
 	}
 
-	// Delete workout
-	err = h.DB.Delete("workout_sessions", workoutID, false)
+	exercises, err := loadCatalog(db, c.GetString("user_id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete workout"})
-		return
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exercises"})
+		return false
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Workout deleted successfully"})
+	for i := range req.Exercises {
+		exercise := &req.Exercises[i]
+		exercise.ExerciseID, exercise.Name, err = catalogLink(exercises, i+1, exercise.ExerciseID, exercise.Name)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return false
+		}
+	}
+	return true
+}
+
+// nullIfEmpty maps an empty string to a NULL column value
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// workoutPayload converts a request into the nested workout document
+// accepted by the create_workout and replace_workout database functions
+func workoutPayload(req models.CreateWorkoutRequest) map[string]interface{} {
+	exercises := make([]map[string]interface{}, 0, len(req.Exercises))
+	for _, exercise := range req.Exercises {
+		sets := make([]map[string]interface{}, 0, len(exercise.Sets))
+		for _, set := range exercise.Sets {
+			sets = append(sets, map[string]interface{}{
+				"weight":           set.Weight,
+				"weight_unit":      nullIfEmpty(set.WeightUnit),
+				"reps":             set.Reps,
+				"set_type":         set.SetType,
+				"distance_m":       set.DistanceM,
+				"duration_seconds": set.DurationSeconds,
+				"rpe":              set.RPE,
+			})
+		}
+
+		exercises = append(exercises, map[string]interface{}{
+			"exercise_id":         exercise.ExerciseID,
+			"routine_exercise_id": exercise.RoutineExerciseID,
+			"name":                exercise.Name,
+			"notes":               exercise.Notes,
+			"sets":                sets,
+		})
+	}
+
+	return map[string]interface{}{
+		"workout_name":            req.WorkoutName,
+		"workout_date":            req.WorkoutDate,
+		"duration_hours":          req.DurationHours,
+		"duration_minutes":        req.DurationMinutes,
+		"overall_rpe":             req.OverallRPE,
+		"estimated_calories":      req.EstimatedCalories,
+		"calorie_formula_version": nil,
+		"activity_type":           req.ActivityType,
+		"exercises":               exercises,
+	}
 }
 
+// workoutListQuery applies the limit, offset, from, to, activity_type, q,
+// sort and order query parameters of GetWorkouts to query, reading dates in loc
+func workoutListQuery(c *gin.Context, query *database.QueryBuilder, loc *time.Location) error {
+	limit, offset := defaultWorkoutPageSize, 0
+	if s := c.Query("limit"); s != "" {
+		var err error
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit < 1 || limit > maxWorkoutPageSize {
+			return fmt.Errorf("limit must be between 1 and %d", maxWorkoutPageSize)
+		}
+	}
+	if s := c.Query("offset"); s != "" {
+		var err error
+		offset, err = strconv.Atoi(s)
+		if err != nil || offset < 0 {
+			return fmt.Errorf("offset must be a non-negative integer")
+		}
+	}
+	query.Limit(limit).Offset(offset).Count()
+
+	// Date filters are calendar days in the user's time zone
+	if s := c.Query("from"); s != "" {
+		from, err := models.ParseDate(s)
+		if err != nil {
+			return fmt.Errorf("invalid from date, expected YYYY-MM-DD")
+		}
+		query.Gte("workout_date", time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc))
+	}
+	if s := c.Query("to"); s != "" {
+		to, err := models.ParseDate(s)
+		if err != nil {
+			return fmt.Errorf("invalid to date, expected YYYY-MM-DD")
+		}
+		query.Lt("workout_date", time.Date(to.Year(), to.Month(), to.Day()+1, 0, 0, 0, 0, loc))
+	}
+
+	if activityType := c.Query("activity_type"); activityType != "" {
+		if activityType != "strength" && activityType != "cardio" {
+			return fmt.Errorf("activity_type must be one of strength, cardio")
+		}
+		query.Eq("activity_type", activityType)
+	}
+
+	if search := strings.Trim(strings.NewReplacer("*", "", "%", "").Replace(c.Query("q")), " "); search != "" {
+		query.ILike("workout_name", "*"+search+"*")
+	}
+
+	sortColumn := c.DefaultQuery("sort", "workout_date")
+	if !workoutSortColumns[sortColumn] {
+		return fmt.Errorf("sort must be one of workout_date, workout_name, overall_rpe, duration_minutes, estimated_calories, created_at")
+	}
+	order := c.DefaultQuery("order", "desc")
+	if order != "asc" && order != "desc" {
+		return fmt.Errorf("order must be one of asc, desc")
+	}
+
+	// Ties list the most recently logged first, then fall back to the id so
+	// pages never overlap
+	query.Order(sortColumn, order == "desc")
+	if sortColumn != "created_at" {
+		query.Order("created_at", true)
+	}
+	query.Order("id", false)
+	return nil
+}
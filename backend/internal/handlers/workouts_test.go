@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hadiabbas/fittrack-backend/internal/models"
+	"github.com/hadiabbas/fittrack-backend/pkg/calories"
+)
+
+// benchWorkout is a strength workout with one exercise of two sets
+func benchWorkout(name string, date time.Time) models.CreateWorkoutRequest {
+	return models.CreateWorkoutRequest{
+		WorkoutName:       name,
+		WorkoutDate:       date,
+		DurationMinutes:   45,
+		OverallRPE:        7,
+		EstimatedCalories: ptr(300),
+		ActivityType:      "strength",
+		Exercises: []models.WorkoutExercise{{
+			Name: "Bench Press",
+			Sets: []models.WorkoutSet{
+				{Weight: ptr(60.0), WeightUnit: models.WeightUnitKg, Reps: ptr(8)},
+				{Weight: ptr(62.5), WeightUnit: models.WeightUnitKg, Reps: ptr(6)},
+			},
+		}},
+	}
+}
+
+func TestWorkoutCRUD(t *testing.T) {
+	s := newTestServer(t)
+	session := s.register("lifter@example.com", "password123")
+	date := time.Date(2024, 3, 4, 18, 0, 0, 0, time.UTC)
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	s.expect(s.do(http.MethodPost, "/workouts", session.Token, benchWorkout("Push", date)), http.StatusCreated, &created)
+
+	var workout models.Workout
+	s.expect(s.do(http.MethodGet, "/workouts/"+created.ID, session.Token, nil), http.StatusOK, &workout)
+	if workout.WorkoutName != "Push" || len(workout.Exercises) != 1 || len(workout.Exercises[0].Sets) != 2 {
+		t.Fatalf("workout = %+v, want Push with one exercise of two sets", workout)
+	}
+	if reps := workout.Exercises[0].Sets[1].Reps; reps == nil || *reps != 6 {
+		t.Errorf("second set reps = %v, want 6", reps)
+	}
+
+	// Replacing the workout replaces its exercises and sets
+	update := benchWorkout("Push B", date)
+	update.Exercises[0].Sets = update.Exercises[0].Sets[:1]
+	s.expect(s.do(http.MethodPut, "/workouts/"+created.ID, session.Token, update), http.StatusOK, &workout)
+	if workout.WorkoutName != "Push B" || len(workout.Exercises[0].Sets) != 1 {
+		t.Errorf("updated workout = %+v, want Push B with one set", workout)
+	}
+
+	s.expect(s.do(http.MethodDelete, "/workouts/"+created.ID, session.Token, nil), http.StatusOK, nil)
+	s.expect(s.do(http.MethodGet, "/workouts/"+created.ID, session.Token, nil), http.StatusNotFound, nil)
+}
+
+func TestWorkoutsList(t *testing.T) {
+	s := newTestServer(t)
+	session := s.register("lifter@example.com", "password123")
+
+	start := time.Date(2024, 3, 1, 18, 0, 0, 0, time.UTC)
+	for i, name := range []string{"Push", "Pull", "Legs"} {
+		workout := benchWorkout(name, start.AddDate(0, 0, i))
+		s.expect(s.do(http.MethodPost, "/workouts", session.Token, workout), http.StatusCreated, nil)
+	}
+
+	var workouts []models.Workout
+	w := s.do(http.MethodGet, "/workouts?limit=2", session.Token, nil)
+	s.expect(w, http.StatusOK, &workouts)
+	if got := w.Header().Get("X-Total-Count"); got != "3" {
+		t.Errorf("X-Total-Count = %q, want 3", got)
+	}
+	if len(workouts) != 2 || workouts[0].WorkoutName != "Legs" || workouts[1].WorkoutName != "Pull" {
+		t.Errorf("first page = %+v, want Legs and Pull", workouts)
+	}
+	if len(workouts) > 0 && len(workouts[0].Exercises) != 1 {
+		t.Errorf("listed workout has %d exercises, want 1", len(workouts[0].Exercises))
+	}
+
+	s.expect(s.do(http.MethodGet, "/workouts?sort=created_at&order=asc", session.Token, nil), http.StatusOK, &workouts)
+	if len(workouts) != 3 || workouts[0].WorkoutName != "Push" || workouts[2].WorkoutName != "Legs" {
+		t.Errorf("workouts by created_at = %+v, want Push, Pull, Legs", workouts)
+	}
+
+	s.expect(s.do(http.MethodGet, "/workouts?to=2024-03-01", session.Token, nil), http.StatusOK, &workouts)
+	if len(workouts) != 1 || workouts[0].WorkoutName != "Push" {
+		t.Errorf("workouts to 2024-03-01 = %+v, want Push", workouts)
+	}
+}
+
+func TestWorkoutsAreOwnedByUser(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register("owner@example.com", "password123")
+	other := s.register("other@example.com", "password123")
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	workout := benchWorkout("Push", time.Date(2024, 3, 4, 18, 0, 0, 0, time.UTC))
+	s.expect(s.do(http.MethodPost, "/workouts", owner.Token, workout), http.StatusCreated, &created)
+
+	s.expect(s.do(http.MethodGet, "/workouts/"+created.ID, other.Token, nil), http.StatusNotFound, nil)
+	s.expect(s.do(http.MethodDelete, "/workouts/"+created.ID, other.Token, nil), http.StatusNotFound, nil)
+
+	var workouts []models.Workout
+	s.expect(s.do(http.MethodGet, "/workouts", other.Token, nil), http.StatusOK, &workouts)
+	if len(workouts) != 0 {
+		t.Errorf("other user lists %d workouts, want 0", len(workouts))
+	}
+
+	s.expect(s.do(http.MethodGet, "/workouts/"+created.ID, owner.Token, nil), http.StatusOK, nil)
+	s.expect(s.do(http.MethodGet, "/workouts", "", nil), http.StatusUnauthorized, nil)
+}
+
+func TestWorkoutCaloriesEstimated(t *testing.T) {
+	s := newTestServer(t)
+	session := s.register("lifter@example.com", "password123")
+	date := time.Date(2024, 3, 4, 18, 0, 0, 0, time.UTC)
+	weighIn := map[string]interface{}{"log_date": "2024-03-01", "body_weight_kg": 80}
+	s.expect(s.do(http.MethodPost, "/body-metrics", session.Token, weighIn), http.StatusOK, nil)
+
+	estimate := func(minutes int) int {
+		return calories.Estimate(calories.Workout{
+			ActivityType: calories.ActivityStrength,
+			Name:         "Push",
+			RPE:          7,
+			Duration:     time.Duration(minutes) * time.Minute,
+		}, 80)
+	}
+
+	workout := benchWorkout("Push", date)
+	workout.EstimatedCalories = nil
+	var created struct {
+		ID string `json:"id"`
+	}
+	s.expect(s.do(http.MethodPost, "/workouts", session.Token, workout), http.StatusCreated, &created)
+
+	var stored models.Workout
+	s.expect(s.do(http.MethodGet, "/workouts/"+created.ID, session.Token, nil), http.StatusOK, &stored)
+	if stored.EstimatedCalories != estimate(45) {
+		t.Errorf("estimated_calories = %d, want %d", stored.EstimatedCalories, estimate(45))
+	}
+	if stored.CalorieFormulaVersion == nil || *stored.CalorieFormulaVersion != calories.FormulaVersion {
+		t.Errorf("calorie_formula_version = %v, want %s", stored.CalorieFormulaVersion, calories.FormulaVersion)
+	}
+
+	// Replacing the workout estimates again from its new duration
+	workout.DurationMinutes = 90
+	s.expect(s.do(http.MethodPut, "/workouts/"+created.ID, session.Token, workout), http.StatusOK, &stored)
+	if stored.EstimatedCalories != estimate(90) {
+		t.Errorf("estimated_calories after PUT = %d, want %d", stored.EstimatedCalories, estimate(90))
+	}
+
+	// Calories entered by the user are kept without a formula version
+	workout.EstimatedCalories = ptr(500)
+	var entered models.Workout
+	s.expect(s.do(http.MethodPut, "/workouts/"+created.ID, session.Token, workout), http.StatusOK, &entered)
+	if entered.EstimatedCalories != 500 || entered.CalorieFormulaVersion != nil {
+		t.Errorf("entered calories = %d (formula %v), want 500 without a formula", entered.EstimatedCalories, entered.CalorieFormulaVersion)
+	}
+}
@@ -1,16 +1,20 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
-	"os"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/hadiabbas/fittrack-backend/pkg/database"
+	"github.com/hadiabbas/fittrack-backend/pkg/revocation"
+	"github.com/hadiabbas/fittrack-backend/pkg/utils"
 )
 
-// AuthMiddleware validates JWT tokens
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware validates JWT tokens against the trusted signing keys,
+// rejecting tokens that have been revoked or whose session has ended. The
+// session's Supabase access token is looked up in db for the handlers.
+func AuthMiddleware(keys *utils.KeyManager, revoked revocation.Store, db database.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -30,31 +34,79 @@ func AuthMiddleware() gin.HandlerFunc {
 		tokenString := parts[1]
 
 		// Parse and validate token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Validate signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
-
-		if err != nil || !token.Valid {
+		claims, err := keys.ParseJWT(tokenString)
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
 			return
 		}
 
 		// Extract claims
-		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			c.Set("user_id", claims["user_id"])
-			c.Set("email", claims["email"])
-		} else {
+		jti, _ := claims["jti"].(string)
+		userID, _ := claims["user_id"].(string)
+		sessionID, _ := claims["sid"].(string)
+		issuedAt, err := claims.GetIssuedAt()
+		if jti == "" || userID == "" || sessionID == "" || err != nil || issuedAt == nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
 			c.Abort()
 			return
 		}
 
+		// Reject tokens that were signed out before they expired
+		isRevoked, err := revoked.IsRevoked(jti, userID, issuedAt.Time)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check token revocation"})
+			c.Abort()
+			return
+		}
+		if isRevoked {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+			c.Abort()
+			return
+		}
+
+		supabaseToken, err := sessionAccessToken(db, sessionID, userID)
+		if errors.Is(err, database.ErrNotFound) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has ended"})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load session"})
+			c.Abort()
+			return
+		}
+
+		c.Set("user_id", userID)
+		c.Set("email", claims["email"])
+		c.Set("supabase_token", supabaseToken)
+		c.Set("jti", jti)
+
 		c.Next()
 	}
 }
 
+// storedSession is the Supabase session kept with a refresh token family
+type storedSession struct {
+	SupabaseAccessToken *string `json:"supabase_access_token"`
+}
+
+// sessionAccessToken returns the Supabase access token of the user's refresh
+// token family sessionID. It returns database.ErrNotFound once the family has
+// been logged out, revoked or deleted.
+func sessionAccessToken(db database.Store, sessionID, userID string) (string, error) {
+	query := database.From(db, "refresh_tokens").
+		Select("supabase_access_token").
+		Eq("family_id", sessionID).
+		Eq("user_id", userID).
+		Is("revoked_at", nil).
+		ServiceKey()
+	session, err := database.One[storedSession](query)
+	if err != nil {
+		return "", err
+	}
+	if session.SupabaseAccessToken == nil {
+		return "", database.ErrNotFound
+	}
+	return *session.SupabaseAccessToken, nil
+}
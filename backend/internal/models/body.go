@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// BodyMetric represents a single day's body measurements
+type BodyMetric struct {
+	ID             string    `json:"id"`
+	UserID         string    `json:"user_id"`
+	LogDate        Date      `json:"log_date"`
+	BodyWeightKg   *float64  `json:"body_weight_kg,omitempty"`
+	BodyFatPercent *float64  `json:"body_fat_percent,omitempty"`
+	MuscleMassKg   *float64  `json:"muscle_mass_kg,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// BodyMetricRequest represents the payload to record a day's measurements.
+// Omitted measurements keep their previously recorded value for that day.
+type BodyMetricRequest struct {
+	LogDate        *Date    `json:"log_date,omitempty"` // Defaults to today
+	BodyWeightKg   *float64 `json:"body_weight_kg,omitempty" binding:"omitempty,min=20,max=400"`
+	BodyFatPercent *float64 `json:"body_fat_percent,omitempty" binding:"omitempty,min=1,max=75"`
+	MuscleMassKg   *float64 `json:"muscle_mass_kg,omitempty" binding:"omitempty,min=5,max=200"`
+}
+
+// WeightTrendPoint represents a weigh-in and its rolling average
+type WeightTrendPoint struct {
+	Date         Date    `json:"date"`
+	WeightKg     float64 `json:"weight_kg"`
+	AverageKg    float64 `json:"average_kg"`
+	SamplesCount int     `json:"samples_count"`
+}
@@ -0,0 +1,41 @@
+package models
+
+// CaloriePoint represents one day's calorie intake against the target
+type CaloriePoint struct {
+	Date     Date `json:"date"`
+	Calories int  `json:"calories"`
+	Target   int  `json:"target"`
+	Delta    int  `json:"delta"` // Calories minus target
+	Logged   bool `json:"logged"`
+}
+
+// WeeklyVolumePoint represents the training volume of one ISO week
+type WeeklyVolumePoint struct {
+	WeekStart Date    `json:"week_start"`
+	VolumeKg  float64 `json:"volume_kg"` // Sum of weight × reps
+	Sets      int     `json:"sets"`
+	Sessions  int     `json:"sessions"`
+}
+
+// DashboardRange represents the charted series for one time range
+type DashboardRange struct {
+	Range           string              `json:"range"` // "7d", "30d" or "all"
+	From            Date                `json:"from"`
+	To              Date                `json:"to"`
+	Calories        []CaloriePoint      `json:"calories"`
+	AverageCalories *float64            `json:"average_calories"` // Over days with at least one log
+	Weight          []WeightTrendPoint  `json:"weight"`
+	WeightChangeKg  *float64            `json:"weight_change_kg"` // Change of the smoothed weight
+	WeeklyVolume    []WeeklyVolumePoint `json:"weekly_volume"`
+	WeeklyLoad      []WeeklyLoadPoint   `json:"weekly_load"`
+	SessionCount    int                 `json:"session_count"`
+	AverageRPE      *float64            `json:"average_rpe"`
+}
+
+// Dashboard represents the insights returned by GET /dashboard
+type Dashboard struct {
+	Timezone      string            `json:"timezone"`
+	CalorieTarget int               `json:"calorie_target"`
+	TrainingLoad  TrainingLoadPoint `json:"training_load"` // Today's load and workload ratio
+	Ranges        []DashboardRange  `json:"ranges"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DateLayout is the format used for DATE columns
+const DateLayout = "2006-01-02"
+
+// Date is a calendar date without a time of day, stored in DATE columns
+type Date struct {
+	time.Time
+}
+
+// NewDate truncates t to its calendar date in t's location
+func NewDate(t time.Time) Date {
+	y, m, d := t.Date()
+	return Date{time.Date(y, m, d, 0, 0, 0, 0, time.UTC)}
+}
+
+// ParseDate parses a YYYY-MM-DD string
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(DateLayout, s)
+	if err != nil {
+		return Date{}, err
+	}
+	return Date{t}, nil
+}
+
+// String formats the date as YYYY-MM-DD
+func (d Date) String() string {
+	return d.Format(DateLayout)
+}
+
+// MarshalJSON encodes the date as "YYYY-MM-DD"
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON accepts "YYYY-MM-DD" as well as full RFC 3339 timestamps
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if t, err := time.Parse(DateLayout, s); err == nil {
+		d.Time = t
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return err
+	}
+	*d = NewDate(t)
+	return nil
+}
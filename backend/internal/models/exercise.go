@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+)
+
+// MuscleGroups are the muscle groups an exercise can train
+var MuscleGroups = []string{
+	"chest", "lats", "upper_back", "traps", "lower_back",
+	"front_delts", "side_delts", "rear_delts", "biceps", "triceps", "forearms",
+	"abs", "obliques", "glutes", "quads", "hamstrings", "adductors", "abductors", "calves",
+}
+
+// Equipment an exercise can be done with
+var Equipment = []string{
+	"barbell", "dumbbell", "kettlebell", "machine", "cable", "bodyweight",
+	"band", "ez_bar", "trap_bar", "smith_machine", "cardio_machine", "other",
+}
+
+// MovementPatterns classify exercises by the movement they train
+var MovementPatterns = []string{
+	"horizontal_push", "vertical_push", "horizontal_pull", "vertical_pull",
+	"squat", "hinge", "lunge", "carry", "isolation", "core", "cardio",
+}
+
+// Exercise is an entry of the exercise catalog: either built in, shared by
+// every user, or a custom exercise defined by one user
+type Exercise struct {
+	ID               string     `json:"id"`
+	UserID           *string    `json:"user_id"` // Nil for built-in exercises
+	Name             string     `json:"name"`
+	Aliases          []string   `json:"aliases"`
+	PrimaryMuscles   []string   `json:"primary_muscles"`
+	SecondaryMuscles []string   `json:"secondary_muscles"`
+	Equipment        string     `json:"equipment"`
+	MovementPattern  string     `json:"movement_pattern"`
+	Unilateral       bool       `json:"unilateral"` // Trains one side at a time
+	CreatedAt        *time.Time `json:"created_at,omitempty"`
+	UpdatedAt        *time.Time `json:"updated_at,omitempty"`
+}
+
+// ExerciseMatch is a search result with its relevance from 0 to 1
+type ExerciseMatch struct {
+	Exercise
+	Score float64 `json:"score"`
+}
+
+// ExerciseRequest represents the request to create or update a custom exercise
+type ExerciseRequest struct {
+	Name             string   `json:"name" binding:"required,min=1,max=100"`
+	Aliases          []string `json:"aliases" binding:"max=20,dive,min=1,max=100"`
+	PrimaryMuscles   []string `json:"primary_muscles" binding:"required,min=1"`
+	SecondaryMuscles []string `json:"secondary_muscles"`
+	Equipment        string   `json:"equipment"`
+	MovementPattern  string   `json:"movement_pattern"`
+	Unilateral       bool     `json:"unilateral"`
+}
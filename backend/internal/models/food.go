@@ -8,7 +8,7 @@ import (
 type FoodLog struct {
 	ID              string    `json:"id"`
 	UserID          string    `json:"user_id"`
-	LogDate         time.Time `json:"log_date"`
+	LogDate         Date      `json:"log_date"`
 	MealType        string    `json:"meal_type"` // "breakfast", "lunch", "dinner", "snack"
 	SourceText      string    `json:"source_text"`
 	CaloriesEst     int       `json:"calories_estimated"`
@@ -23,6 +23,7 @@ type FoodLog struct {
 type ParseTextRequest struct {
 	Query    string `json:"query" binding:"required"`
 	MealType string `json:"meal_type" binding:"required,oneof=breakfast lunch dinner snack"`
+	LogDate  *Date  `json:"log_date,omitempty"` // Defaults to today
 }
 
 // ParseImageRequest represents a request to parse food from an image
@@ -40,3 +41,45 @@ type NutritionInfo struct {
 	Confidence float64 `json:"confidence"`
 }
 
+
+// ParsedFoodItem represents a single food recognised in a text query
+type ParsedFoodItem struct {
+	Name       string  `json:"name"`
+	Quantity   float64 `json:"quantity"`
+	Unit       string  `json:"unit"`
+	Grams      float64 `json:"grams"`
+	Calories   int     `json:"calories"`
+	Protein    float64 `json:"protein"`
+	Fat        float64 `json:"fat"`
+	Carbs      float64 `json:"carbs"`
+	Confidence float64 `json:"confidence"`
+}
+
+// ParseTextResponse represents the result of parsing and logging a meal
+type ParseTextResponse struct {
+	FoodLog   FoodLog          `json:"food_log"`
+	Items     []ParsedFoodItem `json:"items"`
+	Nutrition NutritionInfo    `json:"nutrition"`
+	Parser    string           `json:"parser"`
+}
+
+// FoodLogRequest represents the payload to create or replace a food log entry
+type FoodLogRequest struct {
+	LogDate    *Date    `json:"log_date,omitempty"` // Defaults to today
+	MealType   string   `json:"meal_type" binding:"required,oneof=breakfast lunch dinner snack"`
+	SourceText string   `json:"source_text"`
+	Calories   *int     `json:"calories_estimated" binding:"required,min=0"`
+	ProteinG   *float64 `json:"protein_g,omitempty" binding:"omitempty,min=0"`
+	FatG       *float64 `json:"fat_g,omitempty" binding:"omitempty,min=0"`
+	CarbsG     *float64 `json:"carbs_g,omitempty" binding:"omitempty,min=0"`
+}
+
+// DailyNutrition represents the totals of every food log on one calendar day
+type DailyNutrition struct {
+	Date     Date    `json:"date"`
+	Calories int     `json:"calories"`
+	ProteinG float64 `json:"protein_g"`
+	FatG     float64 `json:"fat_g"`
+	CarbsG   float64 `json:"carbs_g"`
+	Entries  int     `json:"entries"`
+}
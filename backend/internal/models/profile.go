@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+)
+
+// Unit systems a user can prefer for displaying weights and distances
+const (
+	UnitSystemMetric   = "metric"
+	UnitSystemImperial = "imperial"
+)
+
+// Profile holds a user's body details, preferences and goals
+type Profile struct {
+	UserID         string     `json:"user_id"`
+	HeightCm       *float64   `json:"height_cm,omitempty"`
+	BirthDate      *Date      `json:"birth_date,omitempty"`
+	Sex            *string    `json:"sex,omitempty"`
+	UnitSystem     string     `json:"unit_system"`
+	TimeZone       string     `json:"time_zone"`
+	CalorieTarget  *int       `json:"calorie_target,omitempty"`
+	ProteinTargetG *int       `json:"protein_target_g,omitempty"`
+	GoalWeightKg   *float64   `json:"goal_weight_kg,omitempty"`
+	E1RMFormula    string     `json:"e1rm_formula"` // "epley" or "brzycki"
+	CreatedAt      *time.Time `json:"created_at,omitempty"`
+	UpdatedAt      *time.Time `json:"updated_at,omitempty"`
+}
+
+// ProfileRequest represents the payload to update a profile. Omitted fields
+// keep their current value, and the optional ones can be cleared with null.
+type ProfileRequest struct {
+	HeightCm       *float64 `json:"height_cm,omitempty" binding:"omitempty,min=50,max=275"`
+	BirthDate      *Date    `json:"birth_date,omitempty"`
+	Sex            *string  `json:"sex,omitempty" binding:"omitempty,oneof=female male other"`
+	UnitSystem     *string  `json:"unit_system,omitempty" binding:"omitempty,oneof=metric imperial"`
+	TimeZone       *string  `json:"time_zone,omitempty"` // IANA name such as Europe/London
+	CalorieTarget  *int     `json:"calorie_target,omitempty" binding:"omitempty,min=500,max=10000"`
+	ProteinTargetG *int     `json:"protein_target_g,omitempty" binding:"omitempty,min=0,max=500"`
+	GoalWeightKg   *float64 `json:"goal_weight_kg,omitempty" binding:"omitempty,min=20,max=400"`
+	E1RMFormula    *string  `json:"e1rm_formula,omitempty" binding:"omitempty,oneof=epley brzycki"`
+}
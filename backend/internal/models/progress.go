@@ -0,0 +1,96 @@
+package models
+
+import (
+	"time"
+)
+
+// Formulas estimating a one-rep max from a set of several reps
+const (
+	FormulaEpley   = "epley"
+	FormulaBrzycki = "brzycki"
+)
+
+// Personal record types
+const (
+	RecordHeaviestWeight = "heaviest_weight"
+	RecordBestE1RM       = "best_e1rm"
+	RecordMostReps       = "most_reps" // At one weight
+	RecordBestVolume     = "best_volume"
+)
+
+// PersonalRecord represents a user's best performance of one type on an exercise
+type PersonalRecord struct {
+	Exercise    string    `json:"exercise"`
+	Type        string    `json:"type"`
+	Value       float64   `json:"value"`               // Kilograms, or reps for most_reps
+	Previous    *float64  `json:"previous,omitempty"`  // The record that was broken
+	WeightKg    *float64  `json:"weight_kg,omitempty"` // Weight of the set that set the record
+	Reps        *int      `json:"reps,omitempty"`      // Reps of the set that set the record
+	WorkoutID   string    `json:"workout_id"`
+	WorkoutDate time.Time `json:"workout_date"`
+}
+
+// ExerciseSession represents the best values of one exercise in one workout
+type ExerciseSession struct {
+	WorkoutID   string    `json:"workout_id"`
+	WorkoutDate time.Time `json:"workout_date"`
+	Sets        int       `json:"sets"` // Excluding warm-up sets
+	TopWeightKg *float64  `json:"top_weight_kg"`
+	BestE1RMKg  *float64  `json:"best_e1rm_kg"`
+	VolumeKg    float64   `json:"volume_kg"`
+	NewRecords  []string  `json:"new_records"` // Types of the records broken in this workout
+}
+
+// ExerciseProgress represents the history and personal records of an exercise
+type ExerciseProgress struct {
+	ExerciseID *string           `json:"exercise_id"` // Nil for names missing from the catalog
+	Exercise   string            `json:"exercise"`
+	Formula    string            `json:"formula"`
+	Sessions   []ExerciseSession `json:"sessions"` // Oldest first
+	Records    []PersonalRecord  `json:"records"`
+}
+
+// Progression schemes suggesting the next target of an exercise
+const (
+	SchemeLinear            = "linear"             // Add weight whenever every set reaches the target reps
+	SchemeDoubleProgression = "double_progression" // Add reps up to the top of a range, then weight
+	SchemeRPE               = "rpe"                // Pick the weight expected at a target RPE
+)
+
+// ProgressionSettings configures how the next target of an exercise is
+// suggested
+type ProgressionSettings struct {
+	Scheme     string   `json:"scheme"`
+	WeightUnit string   `json:"weight_unit"`
+	Increment  float64  `json:"increment"`            // Smallest weight step, in weight_unit
+	RepsMin    int      `json:"reps_min"`             // Target reps; 0 repeats the last top set's reps
+	RepsMax    int      `json:"reps_max"`             // Top of the rep range for double progression
+	TargetRPE  *float64 `json:"target_rpe,omitempty"` // For the rpe scheme
+}
+
+// ProgressionTarget is the work suggested for the next session of an exercise
+type ProgressionTarget struct {
+	Weight     *float64 `json:"weight"` // Nil for exercises logged without weight
+	WeightUnit string   `json:"weight_unit,omitempty"`
+	Reps       int      `json:"reps"`
+	Sets       int      `json:"sets"`
+	RPE        *float64 `json:"rpe,omitempty"`
+	Reason     string   `json:"reason"`
+}
+
+// ExercisePerformance represents the sets of an exercise logged in one workout
+type ExercisePerformance struct {
+	WorkoutID   string       `json:"workout_id"`
+	WorkoutDate time.Time    `json:"workout_date"`
+	Sets        []WorkoutSet `json:"sets"` // Excluding warm-up sets
+}
+
+// ProgressionSuggestion represents the recent performances of an exercise and
+// the target suggested for the next session
+type ProgressionSuggestion struct {
+	ExerciseID   *string               `json:"exercise_id"` // Nil for names missing from the catalog
+	Exercise     string                `json:"exercise"`
+	Settings     ProgressionSettings   `json:"settings"`
+	Performances []ExercisePerformance `json:"performances"` // Newest first
+	Next         *ProgressionTarget    `json:"next"`         // Nil until the exercise is logged with reps
+}
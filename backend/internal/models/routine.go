@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+)
+
+// Routine represents a workout template: an ordered list of exercises with
+// the sets, reps, weight or RPE to aim for
+type Routine struct {
+	ID           string            `json:"id"`
+	UserID       string            `json:"user_id"`
+	Name         string            `json:"name"`
+	Notes        string            `json:"notes"`
+	ActivityType string            `json:"activity_type"` // "strength" or "cardio"
+	Exercises    []RoutineExercise `json:"exercises"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+}
+
+// RoutineExercise represents an exercise prescribed by a routine. Workouts
+// started from the routine link their exercises back to it through
+// routine_exercise_id.
+type RoutineExercise struct {
+	ID            string   `json:"id"` // Keep it when updating a routine so logged workouts stay linked
+	RoutineID     string   `json:"routine_id"`
+	ExerciseID    *string  `json:"exercise_id,omitempty"` // Catalog exercise; resolved from the name when omitted
+	Name          string   `json:"name"`                  // Display name; defaults to the catalog name
+	Notes         string   `json:"notes"`
+	Order         int      `json:"order"`
+	TargetSets    int      `json:"target_sets" binding:"required,min=1,max=50"`
+	TargetRepsMin *int     `json:"target_reps_min,omitempty" binding:"omitempty,min=1,max=1000"`
+	TargetRepsMax *int     `json:"target_reps_max,omitempty" binding:"omitempty,min=1,max=1000"` // Top of a rep range; defaults to target_reps_min
+	TargetWeight  *float64 `json:"target_weight,omitempty" binding:"omitempty,min=0,max=2000"`
+	WeightUnit    string   `json:"weight_unit,omitempty" binding:"omitempty,oneof=kg lb"` // Defaults to the profile's unit system
+	TargetRPE     *float64 `json:"target_rpe,omitempty" binding:"omitempty,min=1,max=10"`
+}
+
+// RoutineRequest represents the request to create or replace a routine
+type RoutineRequest struct {
+	Name         string            `json:"name" binding:"required,min=1,max=100"`
+	Notes        string            `json:"notes" binding:"max=1000"`
+	ActivityType string            `json:"activity_type" binding:"omitempty,oneof=strength cardio"` // Defaults to strength
+	Exercises    []RoutineExercise `json:"exercises" binding:"max=50,dive"`
+}
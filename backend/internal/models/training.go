@@ -0,0 +1,63 @@
+package models
+
+// Zones of the acute:chronic workload ratio
+const (
+	LoadZoneLow      = "low"       // Below 0.8: training less than usual
+	LoadZoneOptimal  = "optimal"   // 0.8 to 1.3
+	LoadZoneHigh     = "high"      // 1.3 to 1.5: ramping up quickly
+	LoadZoneVeryHigh = "very_high" // Above 1.5: ramping up too fast
+)
+
+// TrainingLoadPoint represents the training load of one day and the rolling
+// loads ending on it. Session load is overall RPE × minutes (sRPE).
+type TrainingLoadPoint struct {
+	Date        Date     `json:"date"`
+	Load        float64  `json:"load"` // Sum of the day's session loads
+	Sessions    int      `json:"sessions"`
+	AcuteLoad   float64  `json:"acute_load"`   // Load of the last 7 days
+	ChronicLoad float64  `json:"chronic_load"` // Average weekly load of the last 28 days
+	ACWR        *float64 `json:"acwr"`         // Acute / chronic; nil without 28 days of history
+	Zone        string   `json:"zone,omitempty"`
+}
+
+// WeeklyLoadPoint represents the training load of one ISO week
+type WeeklyLoadPoint struct {
+	WeekStart Date     `json:"week_start"`
+	Load      float64  `json:"load"`
+	Sessions  int      `json:"sessions"`
+	Monotony  *float64 `json:"monotony"` // Mean / standard deviation of the daily loads; nil when they do not vary
+	Strain    *float64 `json:"strain"`   // Load × monotony
+}
+
+// TrainingLoad represents the training load series of a date range
+type TrainingLoad struct {
+	From   Date                `json:"from"`
+	To     Date                `json:"to"`
+	Daily  []TrainingLoadPoint `json:"daily"`
+	Weekly []WeeklyLoadPoint   `json:"weekly"`
+}
+
+// MuscleVolume represents the training volume of one muscle group
+type MuscleVolume struct {
+	Muscle    string  `json:"muscle"`
+	HardSets  float64 `json:"hard_sets"`  // Secondary muscles count a fraction of each set
+	TonnageKg float64 `json:"tonnage_kg"` // Weight × reps, weighted like the sets
+}
+
+// WeeklyMuscleVolume represents the training volume per muscle group of one
+// ISO week
+type WeeklyMuscleVolume struct {
+	WeekStart    Date           `json:"week_start"`
+	Muscles      []MuscleVolume `json:"muscles"`       // Every muscle group, in the order of MuscleGroups
+	UnmappedSets int            `json:"unmapped_sets"` // Hard sets of exercises missing from the catalog
+}
+
+// MuscleVolumeReport represents the weekly volume per muscle group of a date
+// range
+type MuscleVolumeReport struct {
+	From            Date                 `json:"from"`
+	To              Date                 `json:"to"`
+	SecondaryWeight float64              `json:"secondary_weight"`
+	Weeks           []WeeklyMuscleVolume `json:"weeks"`
+	Unmapped        []string             `json:"unmapped"` // Names of the logged exercises missing from the catalog
+}
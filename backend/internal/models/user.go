@@ -24,9 +24,42 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
-// AuthResponse represents the response after successful auth
+// RefreshRequest carries the refresh token for the refresh and logout endpoints
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// ForgotPasswordRequest asks for a password reset code to be emailed
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest sets a new password using an emailed reset code
+type ResetPasswordRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+// VerifyEmailRequest confirms an email address with an emailed code
+type VerifyEmailRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Token string `json:"token" binding:"required"`
+}
+
+// DeleteAccountRequest confirms the deletion of an account with its password
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// AuthResponse represents the response after successful auth. Token is a
+// short-lived access token; RefreshToken is single-use and is replaced by a
+// new one on every refresh. Profile is included when logging in.
 type AuthResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token        string   `json:"token"`
+	ExpiresIn    int      `json:"expires_in"`
+	RefreshToken string   `json:"refresh_token"`
+	User         User     `json:"user"`
+	Profile      *Profile `json:"profile,omitempty"`
 }
 
@@ -6,37 +6,65 @@ import (
 
 // Workout represents a workout session
 type Workout struct {
-	ID                string            `json:"id"`
-	UserID            string            `json:"user_id"`
-	WorkoutName       string            `json:"workout_name"`
-	WorkoutDate       time.Time         `json:"workout_date"`
-	DurationHours     int               `json:"duration_hours"`
-	DurationMinutes   int               `json:"duration_minutes"`
-	OverallRPE        float64           `json:"overall_rpe"`
-	EstimatedCalories int               `json:"estimated_calories"`
-	ActivityType      string            `json:"activity_type"` // "strength" or "cardio"
-	Exercises         []WorkoutExercise `json:"exercises"`
-	CreatedAt         time.Time         `json:"created_at"`
-	UpdatedAt         time.Time         `json:"updated_at"`
+	ID                    string            `json:"id"`
+	UserID                string            `json:"user_id"`
+	WorkoutName           string            `json:"workout_name"`
+	WorkoutDate           time.Time         `json:"workout_date"`
+	DurationHours         int               `json:"duration_hours"`
+	DurationMinutes       int               `json:"duration_minutes"`
+	OverallRPE            float64           `json:"overall_rpe"`
+	EstimatedCalories     int               `json:"estimated_calories"`
+	CalorieFormulaVersion *string           `json:"calorie_formula_version,omitempty"` // Nil when calories were entered by the user
+	ActivityType          string            `json:"activity_type"`                     // "strength" or "cardio"
+	Exercises             []WorkoutExercise `json:"exercises"`
+	CreatedAt             time.Time         `json:"created_at"`
+	UpdatedAt             time.Time         `json:"updated_at"`
 }
 
 // WorkoutExercise represents an exercise within a workout
 type WorkoutExercise struct {
-	ID        string       `json:"id"`
-	WorkoutID string       `json:"workout_id"`
-	Name      string       `json:"name"`
-	Notes     string       `json:"notes"`
-	Order     int          `json:"order"`
-	Sets      []WorkoutSet `json:"sets"`
+	ID                string       `json:"id"`
+	WorkoutID         string       `json:"workout_id"`
+	ExerciseID        *string      `json:"exercise_id,omitempty"`         // Catalog exercise; resolved from the name when omitted
+	RoutineExerciseID *string      `json:"routine_exercise_id,omitempty"` // Routine exercise this one was prescribed by
+	Name              string       `json:"name"`                          // Display name; defaults to the catalog name
+	Notes             string       `json:"notes"`
+	Order             int          `json:"order"`
+	Sets              []WorkoutSet `json:"sets" binding:"dive"`
 }
 
-// WorkoutSet represents a single set of an exercise
+// Weight units of a set
+const (
+	WeightUnitKg = "kg"
+	WeightUnitLb = "lb"
+)
+
+// Set types
+const (
+	SetTypeWarmup  = "warmup"
+	SetTypeWorking = "working"
+	SetTypeDrop    = "drop"
+	SetTypeFailure = "failure"
+)
+
+// WorkoutSet represents a single set of an exercise. Strength sets have
+// reps and usually a weight; cardio sets have a distance and/or a duration.
 type WorkoutSet struct {
-	ID         string   `json:"id"`
-	ExerciseID string   `json:"exercise_id"`
-	Weight     string   `json:"weight"`
-	Reps       string   `json:"reps"`
-	RPE        *float64 `json:"rpe,omitempty"` // Optional RPE for individual sets
+	ID              string   `json:"id"`
+	ExerciseID      string   `json:"exercise_id"`
+	Weight          *float64 `json:"weight,omitempty" binding:"omitempty,min=0,max=2000"`
+	WeightUnit      string   `json:"weight_unit,omitempty" binding:"omitempty,oneof=kg lb"` // Defaults to the profile's unit system
+	Reps            *int     `json:"reps,omitempty" binding:"omitempty,min=0,max=1000"`
+	SetType         string   `json:"set_type" binding:"omitempty,oneof=warmup working drop failure"` // Defaults to working
+	DistanceM       *float64 `json:"distance_m,omitempty" binding:"omitempty,min=0"`
+	DurationSeconds *int     `json:"duration_seconds,omitempty" binding:"omitempty,min=0"`
+	RPE             *float64 `json:"rpe,omitempty" binding:"omitempty,min=1,max=10"` // Optional RPE for individual sets
+	Order           int      `json:"order"`
+	// Sets migrated from free text keep the text when it could not be
+	// parsed, and are flagged for the user to review
+	WeightText  *string `json:"weight_text,omitempty"`
+	RepsText    *string `json:"reps_text,omitempty"`
+	NeedsReview bool    `json:"needs_review,omitempty"`
 }
 
 // CreateWorkoutRequest represents the request to create a workout
@@ -46,8 +74,18 @@ type CreateWorkoutRequest struct {
 	DurationHours     int               `json:"duration_hours"`
 	DurationMinutes   int               `json:"duration_minutes" binding:"required"`
 	OverallRPE        float64           `json:"overall_rpe" binding:"required,min=1,max=10"`
-	EstimatedCalories int               `json:"estimated_calories"`
+	EstimatedCalories *int              `json:"estimated_calories" binding:"omitempty,min=0"` // Estimated from MET and RPE when omitted
 	ActivityType      string            `json:"activity_type" binding:"required,oneof=strength cardio"`
-	Exercises         []WorkoutExercise `json:"exercises"`
+	Exercises         []WorkoutExercise `json:"exercises" binding:"dive"`
 }
 
+// PatchWorkoutRequest represents a partial update of a workout's session fields
+type PatchWorkoutRequest struct {
+	WorkoutName       *string    `json:"workout_name" binding:"omitempty,min=1"`
+	WorkoutDate       *time.Time `json:"workout_date"`
+	DurationHours     *int       `json:"duration_hours" binding:"omitempty,min=0"`
+	DurationMinutes   *int       `json:"duration_minutes" binding:"omitempty,min=0"`
+	OverallRPE        *float64   `json:"overall_rpe" binding:"omitempty,min=1,max=10"`
+	EstimatedCalories *int       `json:"estimated_calories" binding:"omitempty,min=0"`
+	ActivityType      *string    `json:"activity_type" binding:"omitempty,oneof=strength cardio"`
+}
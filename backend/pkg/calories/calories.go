@@ -0,0 +1,128 @@
+// Package calories estimates the energy spent in a workout from its duration,
+// intensity and the user's body weight using MET values
+package calories
+
+import (
+	"math"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// FormulaVersion identifies the MET table and formula used by Estimate. It is
+// stored with every estimate so values can be told apart, and recomputed,
+// when the formula changes.
+const FormulaVersion = "met-rpe-v1"
+
+// DefaultBodyWeightKg is used when the user has not logged their weight
+const DefaultBodyWeightKg = 70.0
+
+// Activity types of a workout
+const (
+	ActivityStrength = "strength"
+	ActivityCardio   = "cardio"
+)
+
+// Workout is the input to Estimate
+type Workout struct {
+	ActivityType string
+	Name         string
+	RPE          float64 // Session RPE, 1-10
+	Duration     time.Duration
+}
+
+// metRange is the MET value of an activity at light and at vigorous effort,
+// taken from the Compendium of Physical Activities
+type metRange struct {
+	Keywords []string // Prefixes of the words in a workout name
+	Light    float64
+	Vigorous float64
+	// Strength lets the activity apply to strength workouts too. Most cardio
+	// keywords also name lifts ("rows", "step-ups"), so they only apply to
+	// cardio workouts.
+	Strength bool
+}
+
+// activityMETs are matched against the workout name in order, so more
+// specific activities come first
+var activityMETs = []metRange{
+	{Keywords: []string{"walk"}, Light: 2.8, Vigorous: 5.0},
+	{Keywords: []string{"hike", "hiking"}, Light: 5.3, Vigorous: 7.8},
+	{Keywords: []string{"jump", "skipping"}, Light: 8.8, Vigorous: 12.3},
+	{Keywords: []string{"run", "jog", "sprint", "treadmill"}, Light: 7.0, Vigorous: 12.5},
+	{Keywords: []string{"cycl", "bike", "biking", "spin"}, Light: 4.0, Vigorous: 10.0},
+	{Keywords: []string{"swim"}, Light: 5.8, Vigorous: 10.0},
+	{Keywords: []string{"row"}, Light: 4.8, Vigorous: 12.0},
+	{Keywords: []string{"elliptical"}, Light: 4.6, Vigorous: 7.5},
+	{Keywords: []string{"stair", "step"}, Light: 4.0, Vigorous: 8.8},
+	{Keywords: []string{"hiit", "circuit", "crossfit", "bootcamp"}, Light: 4.3, Vigorous: 8.0, Strength: true},
+	{Keywords: []string{"yoga", "pilates", "stretch", "mobility"}, Light: 2.3, Vigorous: 4.0, Strength: true},
+	{Keywords: []string{"danc", "zumba"}, Light: 4.5, Vigorous: 7.8},
+}
+
+// Defaults for workouts whose name matches no activity
+var (
+	strengthMETs = metRange{Light: 3.5, Vigorous: 6.0}
+	cardioMETs   = metRange{Light: 4.0, Vigorous: 9.0}
+)
+
+// RPE values treated as light and as vigorous effort; METs are interpolated
+// between them and held constant outside
+const (
+	lightRPE    = 3.0
+	vigorousRPE = 8.0
+)
+
+// MET returns the metabolic equivalent of a workout. The activity is looked
+// up by the words of its name, falling back to its activity type, and the
+// effort is set by the session RPE.
+func MET(activityType, name string, rpe float64) float64 {
+	met := cardioMETs
+	if activityType == ActivityStrength {
+		met = strengthMETs
+	}
+	if m, ok := lookupActivity(activityType, name); ok {
+		met = m
+	}
+
+	effort := (rpe - lightRPE) / (vigorousRPE - lightRPE)
+	effort = math.Max(0, math.Min(1, effort))
+	return met.Light + effort*(met.Vigorous-met.Light)
+}
+
+// Estimate returns the kilocalories spent in w by a person weighing
+// bodyWeightKg, using the ACSM formula kcal/min = MET × 3.5 × kg / 200. A
+// non-positive body weight is replaced by DefaultBodyWeightKg.
+func Estimate(w Workout, bodyWeightKg float64) int {
+	if bodyWeightKg <= 0 {
+		bodyWeightKg = DefaultBodyWeightKg
+	}
+	if w.Duration <= 0 {
+		return 0
+	}
+
+	perMinute := MET(w.ActivityType, w.Name, w.RPE) * 3.5 * bodyWeightKg / 200
+	return int(math.Round(perMinute * w.Duration.Minutes()))
+}
+
+// lookupActivity finds the first activity applying to activityType with a
+// keyword that starts one of the words of name
+func lookupActivity(activityType, name string) (metRange, bool) {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	for _, activity := range activityMETs {
+		if activityType == ActivityStrength && !activity.Strength {
+			continue
+		}
+		for _, keyword := range activity.Keywords {
+			for _, word := range words {
+				if strings.HasPrefix(word, keyword) {
+					return activity, true
+				}
+			}
+		}
+	}
+	return metRange{}, false
+}
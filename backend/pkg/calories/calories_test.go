@@ -0,0 +1,63 @@
+package calories
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMET(t *testing.T) {
+	tests := []struct {
+		name         string
+		activityType string
+		workout      string
+		rpe          float64
+		want         float64
+	}{
+		{"vigorous run", ActivityCardio, "Morning Run", 8, 12.5},
+		{"light run", ActivityCardio, "Run", 3, 7.0},
+		{"halfway between light and vigorous", ActivityCardio, "Run", 5.5, 9.75},
+		{"below light effort", ActivityCardio, "Run", 1, 7.0},
+		{"above vigorous effort", ActivityCardio, "Run", 10, 12.5},
+		{"keyword starts a word", ActivityCardio, "Easy running", 8, 12.5},
+		{"keyword inside a word", ActivityCardio, "Overrun", 8, 9.0},
+		{"earlier activity wins", ActivityCardio, "Walking stairs", 8, 5.0},
+		{"cardio keyword in a cardio workout", ActivityCardio, "Rowing", 8, 12.0},
+		{"cardio keyword in a strength workout", ActivityStrength, "Rows and curls", 8, 6.0},
+		{"strength keyword in a strength workout", ActivityStrength, "HIIT circuit", 8, 8.0},
+		{"strength default", ActivityStrength, "Push day", 3, 3.5},
+		{"cardio default", ActivityCardio, "Something new", 8, 9.0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MET(tt.activityType, tt.workout, tt.rpe); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("MET(%q, %q, %v) = %v, want %v", tt.activityType, tt.workout, tt.rpe, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	run := Workout{ActivityType: ActivityCardio, Name: "Run", RPE: 8, Duration: time.Hour}
+
+	tests := []struct {
+		name   string
+		w      Workout
+		weight float64
+		want   int
+	}{
+		// 12.5 MET × 3.5 × 80 kg / 200 = 17.5 kcal/min
+		{"logged body weight", run, 80, 1050},
+		// 12.5 MET × 3.5 × 70 kg / 200 × 60 min = 918.75
+		{"default body weight", run, 0, 919},
+		{"negative body weight", run, -5, 919},
+		{"no duration", Workout{ActivityType: ActivityCardio, Name: "Run", RPE: 8}, 80, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Estimate(tt.w, tt.weight); got != tt.want {
+				t.Errorf("Estimate = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
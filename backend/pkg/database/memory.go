@@ -0,0 +1,674 @@
+package database
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// memoryRow is a single table row as it would be returned by PostgREST
+type memoryRow map[string]interface{}
+
+// memoryUser is an account registered with the in-memory auth emulation
+type memoryUser struct {
+	ID           string
+	Email        string
+	PasswordHash []byte
+	CreatedAt    time.Time
+	ConfirmedAt  *time.Time
+}
+
+// memorySession is a GoTrue session: its tokens are renewed by refreshing
+// and all stop working when it is signed out
+type memorySession struct {
+	User *memoryUser
+}
+
+// memoryOTP is an outstanding one-time email code
+type memoryOTP struct {
+	Type      string
+	Token     string
+	ExpiresAt time.Time
+}
+
+// memoryOTPLifetime matches GoTrue's default email OTP expiry
+const memoryOTPLifetime = time.Hour
+
+// foreignKey describes a reference between two tables, ON DELETE CASCADE
+// unless SetNull is set
+type foreignKey struct {
+	Table    string
+	Column   string
+	RefTable string
+	SetNull  bool // ON DELETE SET NULL
+}
+
+// authUsersTable is the GoTrue users table, which the memory store keeps in
+// its users map rather than as rows
+const authUsersTable = "auth.users"
+
+// memoryForeignKeys mirrors the references in database/schema.sql
+var memoryForeignKeys = []foreignKey{
+	{Table: "workout_sessions", Column: "user_id", RefTable: authUsersTable},
+	{Table: "workout_exercises", Column: "workout_id", RefTable: "workout_sessions"},
+	{Table: "workout_sets", Column: "exercise_id", RefTable: "workout_exercises"},
+	{Table: "food_logs", Column: "user_id", RefTable: authUsersTable},
+	{Table: "body_metrics", Column: "user_id", RefTable: authUsersTable},
+	{Table: "user_profiles", Column: "user_id", RefTable: authUsersTable},
+	{Table: "exercises", Column: "user_id", RefTable: authUsersTable},
+	{Table: "workout_exercises", Column: "exercise_id", RefTable: "exercises", SetNull: true},
+	{Table: "routines", Column: "user_id", RefTable: authUsersTable},
+	{Table: "routine_exercises", Column: "routine_id", RefTable: "routines"},
+	{Table: "routine_exercises", Column: "exercise_id", RefTable: "exercises", SetNull: true},
+	{Table: "workout_exercises", Column: "routine_exercise_id", RefTable: "routine_exercises", SetNull: true},
+	{Table: "refresh_tokens", Column: "user_id", RefTable: authUsersTable},
+	{Table: "revoked_tokens", Column: "user_id", RefTable: authUsersTable},
+}
+
+// MemoryStore is a Store that keeps every table in process memory. It is meant
+// for local development and handler tests; nothing survives a restart.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	tables map[string][]memoryRow
+	users  map[string]*memoryUser // keyed by lower-cased email
+	// refreshTokens maps each unused GoTrue refresh token to its session
+	refreshTokens map[string]*memorySession
+	// accessTokens maps each issued GoTrue access token to its session
+	accessTokens map[string]*memorySession
+	// otps holds the last one-time code generated per lower-cased email
+	otps map[string]memoryOTP
+	now  func() time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		tables:        make(map[string][]memoryRow),
+		users:         make(map[string]*memoryUser),
+		refreshTokens: make(map[string]*memorySession),
+		accessTokens:  make(map[string]*memorySession),
+		otps:          make(map[string]memoryOTP),
+		now:           time.Now,
+	}
+}
+
+// Query returns the rows matching opts shaped by the select clause, sorted
+// and paginated, embedding related rows like PostgREST
+func (s *MemoryStore) Query(table string, opts QueryOptions, useServiceKey bool) ([]byte, int, error) {
+	items, err := parseSelect(opts.Select)
+	if err != nil {
+		return nil, 0, err
+	}
+	filters, err := normalizeFilters(opts.Filters)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matches := make([]memoryRow, 0)
+	for _, row := range s.tables[table] {
+		if rowMatchesFilters(row, filters) {
+			matches = append(matches, row)
+		}
+	}
+	sortRows(matches, opts.Order, "")
+
+	total := -1
+	if opts.Count {
+		total = len(matches)
+	}
+	matches = paginate(matches, opts.Offset, opts.Limit)
+
+	projected := make([]memoryRow, 0, len(matches))
+	for _, row := range matches {
+		p, err := s.project(table, row, items, opts.Order, "")
+		if err != nil {
+			return nil, 0, err
+		}
+		projected = append(projected, p)
+	}
+
+	if opts.Single {
+		switch len(projected) {
+		case 0:
+			return nil, total, ErrNotFound
+		case 1:
+			body, err := json.Marshal(projected[0])
+			return body, total, err
+		default:
+			return nil, total, ErrMultipleRows
+		}
+	}
+
+	body, err := json.Marshal(projected)
+	return body, total, err
+}
+
+// Insert inserts one row (or a slice of rows) and returns the stored rows
+func (s *MemoryStore) Insert(table string, data interface{}, useServiceKey bool) ([]byte, error) {
+	rows, err := toRows(data)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now().UTC().Format(time.RFC3339Nano)
+	for _, row := range rows {
+		if id, ok := row["id"].(string); !ok || id == "" {
+			row["id"] = uuid.New().String()
+		}
+		if s.findRow(table, row["id"].(string)) >= 0 {
+			return nil, fmt.Errorf("memory store: duplicate key value for %s.id", table)
+		}
+		if _, ok := row["created_at"]; !ok {
+			row["created_at"] = now
+		}
+	}
+	s.tables[table] = append(s.tables[table], rows...)
+
+	return json.Marshal(rows)
+}
+
+// Update patches the row with the given id and returns the stored rows
+func (s *MemoryStore) Update(table string, id string, data interface{}, useServiceKey bool) ([]byte, error) {
+	patches, err := toRows(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(patches) != 1 {
+		return nil, fmt.Errorf("memory store: update expects a single object")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updated := make([]memoryRow, 0, 1)
+	if i := s.findRow(table, id); i >= 0 {
+		row := s.tables[table][i]
+		for k, v := range patches[0] {
+			if k == "id" {
+				continue
+			}
+			row[k] = v
+		}
+		if _, ok := row["updated_at"]; ok {
+			row["updated_at"] = s.now().UTC().Format(time.RFC3339Nano)
+		}
+		updated = append(updated, row)
+	}
+
+	return json.Marshal(updated)
+}
+
+// Upsert inserts rows, merging any that match an existing row on every
+// onConflict column
+func (s *MemoryStore) Upsert(table string, data interface{}, onConflict string, useServiceKey bool) ([]byte, error) {
+	rows, err := toRows(data)
+	if err != nil {
+		return nil, err
+	}
+	columns := strings.Split(onConflict, ",")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now().UTC().Format(time.RFC3339Nano)
+	stored := make([]memoryRow, 0, len(rows))
+	for _, row := range rows {
+		conflict := make(map[string]interface{}, len(columns))
+		for _, column := range columns {
+			conflict[strings.TrimSpace(column)] = row[strings.TrimSpace(column)]
+		}
+
+		var existing memoryRow
+		for _, candidate := range s.tables[table] {
+			if rowMatches(candidate, conflict) {
+				existing = candidate
+				break
+			}
+		}
+
+		if existing != nil {
+			for k, v := range row {
+				if k == "id" {
+					continue
+				}
+				existing[k] = v
+			}
+			if _, ok := existing["updated_at"]; ok {
+				existing["updated_at"] = now
+			}
+			stored = append(stored, existing)
+			continue
+		}
+
+		if id, ok := row["id"].(string); !ok || id == "" {
+			row["id"] = uuid.New().String()
+		}
+		if _, ok := row["created_at"]; !ok {
+			row["created_at"] = now
+		}
+		s.tables[table] = append(s.tables[table], row)
+		stored = append(stored, row)
+	}
+
+	return json.Marshal(stored)
+}
+
+// Delete removes the row with the given id, cascading to referencing rows
+func (s *MemoryStore) Delete(table string, id string, useServiceKey bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleteCascade(table, id)
+	return nil
+}
+
+// RPC runs one of the memoryFunctions under the store lock, so it is applied
+// atomically like the Postgres function it emulates
+func (s *MemoryStore) RPC(function string, params interface{}, useServiceKey bool) ([]byte, error) {
+	fn, ok := memoryFunctions[function]
+	if !ok {
+		return nil, fmt.Errorf("memory store: function %s does not exist", function)
+	}
+
+	args, err := toRows(params)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != 1 {
+		return nil, fmt.Errorf("memory store: rpc expects a single object of named params")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := fn(s, args[0])
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(result)
+}
+
+// WithAccessToken returns the store itself: the memory backend has no row
+// level security, so ownership is only enforced by the handlers' filters
+func (s *MemoryStore) WithAccessToken(accessToken string) Store {
+	return s
+}
+
+// AuthSignUp creates a new, already confirmed, user account
+func (s *MemoryStore) AuthSignUp(email, password string) ([]byte, error) {
+	key := strings.ToLower(email)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.users[key]; exists {
+		return nil, fmt.Errorf("signup error: User already registered")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now().UTC()
+	user := &memoryUser{
+		ID:           uuid.New().String(),
+		Email:        email,
+		PasswordHash: hash,
+		CreatedAt:    now,
+		ConfirmedAt:  &now,
+	}
+	s.users[key] = user
+
+	return s.session(&memorySession{User: user})
+}
+
+// AuthSignIn signs a user in with email and password
+func (s *MemoryStore) AuthSignIn(email, password string) ([]byte, error) {
+	// Copy what is checked while holding the lock, since resets and updates
+	// change it, but compare the slow bcrypt hash without blocking others
+	s.mu.RLock()
+	user, ok := s.users[strings.ToLower(email)]
+	var passwordHash []byte
+	var confirmed bool
+	if ok {
+		passwordHash, confirmed = user.PasswordHash, user.ConfirmedAt != nil
+	}
+	s.mu.RUnlock()
+
+	if !ok || bcrypt.CompareHashAndPassword(passwordHash, []byte(password)) != nil {
+		return nil, fmt.Errorf("signin error: Invalid login credentials")
+	}
+	if !confirmed {
+		return nil, fmt.Errorf("signin error: Email not confirmed")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.session(&memorySession{User: user})
+}
+
+// AuthRefresh exchanges a refresh token for a new session. Like GoTrue, each
+// refresh token can only be used once.
+func (s *MemoryStore) AuthRefresh(refreshToken string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.refreshTokens[refreshToken]
+	if !ok {
+		return nil, fmt.Errorf("refresh error: Invalid Refresh Token")
+	}
+	delete(s.refreshTokens, refreshToken)
+
+	return s.session(session)
+}
+
+// AuthGenerateLink creates a six-digit email code. A signup code creates the
+// user unconfirmed, or resets the password of a user who never confirmed.
+func (s *MemoryStore) AuthGenerateLink(linkType, email, password string) ([]byte, error) {
+	key := strings.ToLower(email)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, exists := s.users[key]
+	switch linkType {
+	case LinkTypeSignup:
+		if exists && user.ConfirmedAt != nil {
+			return nil, fmt.Errorf("generate link error: A user with this email address has already been registered")
+		}
+		if password == "" {
+			return nil, fmt.Errorf("generate link error: Password is required for signup links")
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			user = &memoryUser{ID: uuid.New().String(), Email: email, CreatedAt: s.now().UTC()}
+			s.users[key] = user
+		}
+		user.PasswordHash = hash
+	case LinkTypeRecovery:
+		if !exists {
+			return nil, fmt.Errorf("generate link error: User with this email not found")
+		}
+	default:
+		return nil, fmt.Errorf("generate link error: Invalid link type %q", linkType)
+	}
+
+	token, err := randomDigits(6)
+	if err != nil {
+		return nil, err
+	}
+	s.otps[key] = memoryOTP{Type: linkType, Token: token, ExpiresAt: s.now().Add(memoryOTPLifetime)}
+
+	return json.Marshal(map[string]interface{}{
+		"id":                user.ID,
+		"email":             user.Email,
+		"created_at":        user.CreatedAt,
+		"email_otp":         token,
+		"verification_type": linkType,
+	})
+}
+
+// AuthVerify consumes an email code, confirms the user and starts a session
+func (s *MemoryStore) AuthVerify(linkType, email, token string) ([]byte, error) {
+	key := strings.ToLower(email)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	otp, ok := s.otps[key]
+	user := s.users[key]
+	if !ok || user == nil || otp.Type != linkType || otp.Token != token || s.now().After(otp.ExpiresAt) {
+		return nil, fmt.Errorf("verify error: Token has expired or is invalid")
+	}
+	delete(s.otps, key)
+
+	if user.ConfirmedAt == nil {
+		now := s.now().UTC()
+		user.ConfirmedAt = &now
+	}
+	return s.session(&memorySession{User: user})
+}
+
+// AuthUpdateUser changes the password of the user owning accessToken
+func (s *MemoryStore) AuthUpdateUser(accessToken string, attributes map[string]interface{}) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.accessTokens[accessToken]
+	if !ok {
+		return nil, fmt.Errorf("update user error: invalid JWT")
+	}
+	user := session.User
+
+	if password, ok := attributes["password"].(string); ok {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		user.PasswordHash = hash
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"id":         user.ID,
+		"email":      user.Email,
+		"created_at": user.CreatedAt,
+	})
+}
+
+// AuthDeleteUser deletes a user, its sessions and every row referencing it
+func (s *MemoryStore) AuthDeleteUser(userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var user *memoryUser
+	for key, u := range s.users {
+		if u.ID == userID {
+			user = u
+			delete(s.users, key)
+			delete(s.otps, key)
+		}
+	}
+	if user == nil {
+		return fmt.Errorf("delete user error: User not found")
+	}
+
+	s.endSessions(func(session *memorySession) bool { return session.User == user })
+
+	s.deleteReferences(authUsersTable, userID)
+	return nil
+}
+
+// AuthSignOut ends the session of accessToken, or with SignOutGlobal every
+// session of its user
+func (s *MemoryStore) AuthSignOut(accessToken, scope string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.accessTokens[accessToken]
+	if !ok {
+		return fmt.Errorf("sign out error: invalid JWT")
+	}
+
+	switch scope {
+	case SignOutLocal:
+		s.endSessions(func(session *memorySession) bool { return session == current })
+	case SignOutGlobal:
+		s.endSessions(func(session *memorySession) bool { return session.User == current.User })
+	default:
+		return fmt.Errorf("sign out error: invalid scope %q", scope)
+	}
+	return nil
+}
+
+// endSessions invalidates the tokens of every session matching ended. The
+// caller must hold the write lock.
+func (s *MemoryStore) endSessions(ended func(*memorySession) bool) {
+	for token, session := range s.refreshTokens {
+		if ended(session) {
+			delete(s.refreshTokens, token)
+		}
+	}
+	for token, session := range s.accessTokens {
+		if ended(session) {
+			delete(s.accessTokens, token)
+		}
+	}
+}
+
+// session issues new tokens for session and builds a GoTrue-shaped session
+// response. The caller must hold the write lock.
+func (s *MemoryStore) session(session *memorySession) ([]byte, error) {
+	accessToken, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	s.refreshTokens[refreshToken] = session
+	s.accessTokens[accessToken] = session
+
+	return json.Marshal(map[string]interface{}{
+		"access_token":  accessToken,
+		"token_type":    "bearer",
+		"expires_in":    3600,
+		"refresh_token": refreshToken,
+		"user": map[string]interface{}{
+			"id":         session.User.ID,
+			"email":      session.User.Email,
+			"created_at": session.User.CreatedAt,
+		},
+	})
+}
+
+// findRow returns the index of the row with the given id, or -1
+func (s *MemoryStore) findRow(table, id string) int {
+	for i, row := range s.tables[table] {
+		if row["id"] == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// deleteCascade removes a row and every row that references it
+func (s *MemoryStore) deleteCascade(table, id string) {
+	i := s.findRow(table, id)
+	if i < 0 {
+		return
+	}
+	rows := s.tables[table]
+	s.tables[table] = append(rows[:i:i], rows[i+1:]...)
+
+	s.deleteReferences(table, id)
+}
+
+// deleteReferences deletes the rows referencing the row id of table, and
+// everything referencing those in turn, or clears their reference if the
+// foreign key is ON DELETE SET NULL
+func (s *MemoryStore) deleteReferences(table, id string) {
+	for _, fk := range memoryForeignKeys {
+		if fk.RefTable != table {
+			continue
+		}
+		if fk.SetNull {
+			for _, row := range s.tables[fk.Table] {
+				if row[fk.Column] == id {
+					row[fk.Column] = nil
+				}
+			}
+			continue
+		}
+		kept := s.tables[fk.Table][:0]
+		var children []string
+		for _, row := range s.tables[fk.Table] {
+			if row[fk.Column] != id {
+				kept = append(kept, row)
+				continue
+			}
+			if childID, ok := row["id"].(string); ok {
+				children = append(children, childID)
+			}
+		}
+		s.tables[fk.Table] = kept
+		for _, childID := range children {
+			s.deleteReferences(fk.Table, childID)
+		}
+	}
+}
+
+// rowMatches reports whether row satisfies every equality filter
+func rowMatches(row memoryRow, query map[string]interface{}) bool {
+	for k, v := range query {
+		value, ok := row[k]
+		if !ok || value == nil || fmt.Sprint(value) != fmt.Sprint(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// toRows converts a struct, map or slice into rows by round-tripping through
+// JSON, so stored values look exactly like PostgREST responses
+func toRows(data interface{}) ([]memoryRow, error) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed := strings.TrimSpace(string(jsonData))
+	if strings.HasPrefix(trimmed, "[") {
+		var rows []memoryRow
+		if err := json.Unmarshal(jsonData, &rows); err != nil {
+			return nil, err
+		}
+		return rows, nil
+	}
+
+	var row memoryRow
+	if err := json.Unmarshal(jsonData, &row); err != nil {
+		return nil, fmt.Errorf("memory store: expected an object or array of objects")
+	}
+	return []memoryRow{row}, nil
+}
+
+// randomToken returns a random opaque token
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// randomDigits returns n uniformly random decimal digits
+func randomDigits(n int) (string, error) {
+	digits := make([]byte, n)
+	for i := range digits {
+		d, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		digits[i] = byte('0' + d.Int64())
+	}
+	return string(digits), nil
+}
@@ -0,0 +1,519 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// memoryFunction emulates a Postgres function. It is called with the store
+// lock held and must validate everything before mutating any table.
+type memoryFunction func(s *MemoryStore, args memoryRow) (interface{}, error)
+
+// memoryFunctions mirrors the functions defined in database/schema.sql
+var memoryFunctions = map[string]memoryFunction{
+	"create_workout":  memoryCreateWorkout,
+	"replace_workout": memoryReplaceWorkout,
+	"delete_workout":  memoryDeleteWorkout,
+
+	"create_routine":  memoryCreateRoutine,
+	"replace_routine": memoryReplaceRoutine,
+
+	"rotate_refresh_token": memoryRotateRefreshToken,
+	"revoke_refresh_token": memoryRevokeRefreshToken,
+
+	"revoke_user_refresh_tokens": memoryRevokeUserRefreshTokens,
+	"revoke_user_tokens":         memoryRevokeUserTokens,
+	"is_token_revoked":           memoryIsTokenRevoked,
+}
+
+// memoryCreateWorkout inserts a session with its exercises and sets and
+// returns the new workout id
+func memoryCreateWorkout(s *MemoryStore, args memoryRow) (interface{}, error) {
+	workout, ok := args["p_workout"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("memory store: create_workout requires p_workout")
+	}
+	if _, ok := workout["user_id"].(string); !ok {
+		return nil, fmt.Errorf("memory store: null value in column \"user_id\"")
+	}
+
+	session, children, err := workoutRows(workout)
+	if err != nil {
+		return nil, err
+	}
+	if id, ok := session["id"].(string); !ok || id == "" {
+		session["id"] = uuid.New().String()
+	}
+	if s.findRow("workout_sessions", session["id"].(string)) >= 0 {
+		return nil, fmt.Errorf("memory store: duplicate key value for workout_sessions.id")
+	}
+
+	now := s.now().UTC().Format(time.RFC3339Nano)
+	session["created_at"] = now
+	session["updated_at"] = now
+	s.tables["workout_sessions"] = append(s.tables["workout_sessions"], session)
+	s.insertExercisesLocked(session["id"].(string), children)
+
+	return session["id"], nil
+}
+
+// memoryReplaceWorkout overwrites a user's workout including its exercises
+// and sets, returning false when the workout does not exist
+func memoryReplaceWorkout(s *MemoryStore, args memoryRow) (interface{}, error) {
+	workoutID, _ := args["p_workout_id"].(string)
+	userID, _ := args["p_user_id"].(string)
+	workout, ok := args["p_workout"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("memory store: replace_workout requires p_workout")
+	}
+
+	i := s.findRow("workout_sessions", workoutID)
+	if i < 0 || s.tables["workout_sessions"][i]["user_id"] != userID {
+		return false, nil
+	}
+
+	session, children, err := workoutRows(workout)
+	if err != nil {
+		return nil, err
+	}
+
+	row := s.tables["workout_sessions"][i]
+	for k, v := range session {
+		if k == "id" || k == "user_id" || k == "created_at" {
+			continue
+		}
+		row[k] = v
+	}
+	row["updated_at"] = s.now().UTC().Format(time.RFC3339Nano)
+
+	var existing []string
+	for _, exercise := range s.tables["workout_exercises"] {
+		if exercise["workout_id"] == workoutID {
+			existing = append(existing, exercise["id"].(string))
+		}
+	}
+	for _, id := range existing {
+		s.deleteCascade("workout_exercises", id)
+	}
+	s.insertExercisesLocked(workoutID, children)
+
+	return true, nil
+}
+
+// memoryDeleteWorkout deletes a user's workout, returning false when it does
+// not exist
+func memoryDeleteWorkout(s *MemoryStore, args memoryRow) (interface{}, error) {
+	workoutID, _ := args["p_workout_id"].(string)
+	userID, _ := args["p_user_id"].(string)
+
+	i := s.findRow("workout_sessions", workoutID)
+	if i < 0 || s.tables["workout_sessions"][i]["user_id"] != userID {
+		return false, nil
+	}
+
+	s.deleteCascade("workout_sessions", workoutID)
+	return true, nil
+}
+
+// memoryCreateRoutine inserts a routine with its exercises and returns the
+// new routine id
+func memoryCreateRoutine(s *MemoryStore, args memoryRow) (interface{}, error) {
+	routine, ok := args["p_routine"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("memory store: create_routine requires p_routine")
+	}
+	if _, ok := routine["user_id"].(string); !ok {
+		return nil, fmt.Errorf("memory store: null value in column \"user_id\"")
+	}
+
+	row, exercises, err := routineRows(routine)
+	if err != nil {
+		return nil, err
+	}
+	if id, ok := row["id"].(string); !ok || id == "" {
+		row["id"] = uuid.New().String()
+	}
+	if s.findRow("routines", row["id"].(string)) >= 0 {
+		return nil, fmt.Errorf("memory store: duplicate key value for routines.id")
+	}
+	routineID := row["id"].(string)
+	if err := s.checkRoutineExercisesLocked(routineID, exercises); err != nil {
+		return nil, err
+	}
+
+	now := s.now().UTC().Format(time.RFC3339Nano)
+	row["created_at"] = now
+	row["updated_at"] = now
+	s.tables["routines"] = append(s.tables["routines"], row)
+	s.saveRoutineExercisesLocked(routineID, exercises)
+
+	return routineID, nil
+}
+
+// memoryReplaceRoutine overwrites a user's routine and its exercises,
+// returning false when the routine does not exist
+func memoryReplaceRoutine(s *MemoryStore, args memoryRow) (interface{}, error) {
+	routineID, _ := args["p_routine_id"].(string)
+	userID, _ := args["p_user_id"].(string)
+	routine, ok := args["p_routine"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("memory store: replace_routine requires p_routine")
+	}
+
+	i := s.findRow("routines", routineID)
+	if i < 0 || s.tables["routines"][i]["user_id"] != userID {
+		return false, nil
+	}
+
+	fields, exercises, err := routineRows(routine)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkRoutineExercisesLocked(routineID, exercises); err != nil {
+		return nil, err
+	}
+
+	row := s.tables["routines"][i]
+	for k, v := range fields {
+		if k == "id" || k == "user_id" || k == "created_at" {
+			continue
+		}
+		row[k] = v
+	}
+	row["updated_at"] = s.now().UTC().Format(time.RFC3339Nano)
+	s.saveRoutineExercisesLocked(routineID, exercises)
+
+	return true, nil
+}
+
+// memoryRotateRefreshToken replaces a refresh token with a new one in the
+// same family, revoking the whole family when a rotated token is reused
+func memoryRotateRefreshToken(s *MemoryStore, args memoryRow) (interface{}, error) {
+	tokenHash, _ := args["p_token_hash"].(string)
+	newTokenHash, _ := args["p_new_token_hash"].(string)
+	expiresAt, _ := args["p_expires_at"].(string)
+	if newTokenHash == "" || expiresAt == "" {
+		return nil, fmt.Errorf("memory store: rotate_refresh_token requires p_new_token_hash and p_expires_at")
+	}
+
+	var token memoryRow
+	for _, row := range s.tables["refresh_tokens"] {
+		if row["token_hash"] == tokenHash {
+			token = row
+			break
+		}
+	}
+	if token == nil {
+		return map[string]interface{}{"status": "invalid"}, nil
+	}
+
+	now := s.now().UTC()
+	if token["revoked_at"] != nil {
+		if token["replaced_by"] == nil {
+			return map[string]interface{}{"status": "revoked"}, nil
+		}
+		for _, row := range s.tables["refresh_tokens"] {
+			if row["family_id"] == token["family_id"] && row["revoked_at"] == nil {
+				row["revoked_at"] = now.Format(time.RFC3339Nano)
+				row["supabase_refresh_token"] = nil
+				row["supabase_access_token"] = nil
+			}
+		}
+		return map[string]interface{}{"status": "reused", "user_id": token["user_id"], "family_id": token["family_id"]}, nil
+	}
+
+	if expires, err := time.Parse(time.RFC3339Nano, fmt.Sprint(token["expires_at"])); err != nil || !expires.After(now) {
+		return map[string]interface{}{"status": "expired"}, nil
+	}
+
+	replacement := memoryRow{
+		"id":                     uuid.New().String(),
+		"user_id":                token["user_id"],
+		"family_id":              token["family_id"],
+		"token_hash":             newTokenHash,
+		"supabase_refresh_token": token["supabase_refresh_token"],
+		"supabase_access_token":  token["supabase_access_token"],
+		"expires_at":             expiresAt,
+		"revoked_at":             nil,
+		"replaced_by":            nil,
+		"created_at":             now.Format(time.RFC3339Nano),
+	}
+	s.tables["refresh_tokens"] = append(s.tables["refresh_tokens"], replacement)
+
+	supabaseRefreshToken := token["supabase_refresh_token"]
+	token["revoked_at"] = now.Format(time.RFC3339Nano)
+	token["replaced_by"] = replacement["id"]
+	token["supabase_refresh_token"] = nil
+	token["supabase_access_token"] = nil
+
+	return map[string]interface{}{
+		"status":                 "rotated",
+		"id":                     replacement["id"],
+		"user_id":                token["user_id"],
+		"family_id":              token["family_id"],
+		"supabase_refresh_token": supabaseRefreshToken,
+	}, nil
+}
+
+// memoryRevokeRefreshToken revokes a single refresh token, returning false if
+// it was unknown or already revoked
+func memoryRevokeRefreshToken(s *MemoryStore, args memoryRow) (interface{}, error) {
+	tokenHash, _ := args["p_token_hash"].(string)
+	for _, row := range s.tables["refresh_tokens"] {
+		if row["token_hash"] == tokenHash && row["revoked_at"] == nil {
+			row["revoked_at"] = s.now().UTC().Format(time.RFC3339Nano)
+			row["supabase_refresh_token"] = nil
+			row["supabase_access_token"] = nil
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// memoryRevokeUserRefreshTokens revokes every refresh token of a user and
+// returns how many were revoked
+func memoryRevokeUserRefreshTokens(s *MemoryStore, args memoryRow) (interface{}, error) {
+	userID, _ := args["p_user_id"].(string)
+	count := 0
+	for _, row := range s.tables["refresh_tokens"] {
+		if row["user_id"] == userID && row["revoked_at"] == nil {
+			row["revoked_at"] = s.now().UTC().Format(time.RFC3339Nano)
+			row["supabase_refresh_token"] = nil
+			row["supabase_access_token"] = nil
+			count++
+		}
+	}
+	return count, nil
+}
+
+// memoryRevokeUserTokens sets a user's token cutoff, keeping the later of the
+// stored and the new revoked_before and expires_at
+func memoryRevokeUserTokens(s *MemoryStore, args memoryRow) (interface{}, error) {
+	userID, _ := args["p_user_id"].(string)
+	revokedBefore, err := time.Parse(time.RFC3339Nano, fmt.Sprint(args["p_revoked_before"]))
+	if err != nil {
+		return nil, fmt.Errorf("memory store: invalid p_revoked_before")
+	}
+	expiresAt, err := time.Parse(time.RFC3339Nano, fmt.Sprint(args["p_expires_at"]))
+	if err != nil {
+		return nil, fmt.Errorf("memory store: invalid p_expires_at")
+	}
+
+	for _, row := range s.tables["token_cutoffs"] {
+		if row["user_id"] != userID {
+			continue
+		}
+		row["revoked_before"] = latestTime(row["revoked_before"], revokedBefore)
+		row["expires_at"] = latestTime(row["expires_at"], expiresAt)
+		return nil, nil
+	}
+	s.tables["token_cutoffs"] = append(s.tables["token_cutoffs"], memoryRow{
+		"user_id":        userID,
+		"revoked_before": revokedBefore.UTC().Format(time.RFC3339Nano),
+		"expires_at":     expiresAt.UTC().Format(time.RFC3339Nano),
+		"created_at":     s.now().UTC().Format(time.RFC3339Nano),
+	})
+	return nil, nil
+}
+
+// latestTime is GREATEST(stored, t) for a stored timestamp column
+func latestTime(stored interface{}, t time.Time) string {
+	if current, err := time.Parse(time.RFC3339Nano, fmt.Sprint(stored)); err == nil && current.After(t) {
+		return current.UTC().Format(time.RFC3339Nano)
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// memoryIsTokenRevoked reports whether an access token was revoked by jti or
+// by a user-wide cutoff
+func memoryIsTokenRevoked(s *MemoryStore, args memoryRow) (interface{}, error) {
+	jti, _ := args["p_jti"].(string)
+	userID, _ := args["p_user_id"].(string)
+	issuedAt, err := time.Parse(time.RFC3339Nano, fmt.Sprint(args["p_issued_at"]))
+	if err != nil {
+		return nil, fmt.Errorf("memory store: invalid p_issued_at")
+	}
+
+	for _, row := range s.tables["revoked_tokens"] {
+		if row["jti"] == jti {
+			return true, nil
+		}
+	}
+	for _, row := range s.tables["token_cutoffs"] {
+		if row["user_id"] != userID {
+			continue
+		}
+		before, err := time.Parse(time.RFC3339Nano, fmt.Sprint(row["revoked_before"]))
+		if err == nil && issuedAt.Before(before) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// workoutExerciseRows is an exercise row together with its set rows
+type workoutExerciseRows struct {
+	Exercise memoryRow
+	Sets     []memoryRow
+}
+
+// workoutRows splits a nested workout payload into the session row and its
+// exercise and set rows, checking the NOT NULL columns up front
+func workoutRows(workout map[string]interface{}) (memoryRow, []workoutExerciseRows, error) {
+	session := memoryRow{}
+	for k, v := range workout {
+		if k != "exercises" {
+			session[k] = v
+		}
+	}
+	if _, ok := session["workout_name"].(string); !ok {
+		return nil, nil, fmt.Errorf("memory store: null value in column \"workout_name\"")
+	}
+
+	exercises, _ := workout["exercises"].([]interface{})
+	children := make([]workoutExerciseRows, 0, len(exercises))
+	for _, e := range exercises {
+		exercise, ok := e.(map[string]interface{})
+		if !ok {
+			return nil, nil, fmt.Errorf("memory store: exercises must be objects")
+		}
+		if _, ok := exercise["name"].(string); !ok {
+			return nil, nil, fmt.Errorf("memory store: null value in column \"name\"")
+		}
+
+		child := workoutExerciseRows{Exercise: memoryRow{}}
+		for k, v := range exercise {
+			if k != "sets" {
+				child.Exercise[k] = v
+			}
+		}
+
+		sets, _ := exercise["sets"].([]interface{})
+		for _, st := range sets {
+			set, ok := st.(map[string]interface{})
+			if !ok {
+				return nil, nil, fmt.Errorf("memory store: sets must be objects")
+			}
+			child.Sets = append(child.Sets, memoryRow(set))
+		}
+		children = append(children, child)
+	}
+
+	return session, children, nil
+}
+
+// routineRows splits a nested routine payload into the routine row and its
+// exercise rows, filling in the column defaults and checking the NOT NULL
+// columns up front
+func routineRows(routine map[string]interface{}) (memoryRow, []memoryRow, error) {
+	row := memoryRow{"notes": "", "activity_type": "strength"}
+	for k, v := range routine {
+		if k != "exercises" && v != nil {
+			row[k] = v
+		}
+	}
+	if _, ok := row["name"].(string); !ok {
+		return nil, nil, fmt.Errorf("memory store: null value in column \"name\"")
+	}
+
+	items, _ := routine["exercises"].([]interface{})
+	exercises := make([]memoryRow, 0, len(items))
+	for _, item := range items {
+		exercise, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, nil, fmt.Errorf("memory store: exercises must be objects")
+		}
+		if _, ok := exercise["name"].(string); !ok {
+			return nil, nil, fmt.Errorf("memory store: null value in column \"name\"")
+		}
+		if _, ok := exercise["target_sets"].(float64); !ok {
+			return nil, nil, fmt.Errorf("memory store: null value in column \"target_sets\"")
+		}
+		exercises = append(exercises, memoryRow(exercise))
+	}
+
+	return row, exercises, nil
+}
+
+// checkRoutineExercisesLocked checks that every exercise with an id already
+// belongs to the routine
+func (s *MemoryStore) checkRoutineExercisesLocked(routineID string, exercises []memoryRow) error {
+	for _, exercise := range exercises {
+		id, ok := exercise["id"].(string)
+		if !ok {
+			continue
+		}
+		i := s.findRow("routine_exercises", id)
+		if i < 0 || s.tables["routine_exercises"][i]["routine_id"] != routineID {
+			return fmt.Errorf("memory store: routine exercise %s does not belong to routine %s", id, routineID)
+		}
+	}
+	return nil
+}
+
+// saveRoutineExercisesLocked updates the routine exercises with an id in
+// place, inserts the others and deletes those missing from exercises,
+// numbering them in payload order
+func (s *MemoryStore) saveRoutineExercisesLocked(routineID string, exercises []memoryRow) {
+	kept := make(map[string]bool)
+	for _, exercise := range exercises {
+		if id, ok := exercise["id"].(string); ok {
+			kept[id] = true
+		}
+	}
+	var removed []string
+	for _, row := range s.tables["routine_exercises"] {
+		if row["routine_id"] == routineID && !kept[row["id"].(string)] {
+			removed = append(removed, row["id"].(string))
+		}
+	}
+	for _, id := range removed {
+		s.deleteCascade("routine_exercises", id)
+	}
+
+	now := s.now().UTC().Format(time.RFC3339Nano)
+	for i, exercise := range exercises {
+		exercise["routine_id"] = routineID
+		exercise["order"] = float64(i)
+		if notes, ok := exercise["notes"].(string); !ok || notes == "" {
+			exercise["notes"] = ""
+		}
+
+		if id, ok := exercise["id"].(string); ok {
+			row := s.tables["routine_exercises"][s.findRow("routine_exercises", id)]
+			for k, v := range exercise {
+				if k != "created_at" {
+					row[k] = v
+				}
+			}
+			continue
+		}
+		exercise["id"] = uuid.New().String()
+		exercise["created_at"] = now
+		s.tables["routine_exercises"] = append(s.tables["routine_exercises"], exercise)
+	}
+}
+
+// insertExercisesLocked inserts exercise and set rows for a workout, numbering
+// exercises and sets in payload order
+func (s *MemoryStore) insertExercisesLocked(workoutID string, children []workoutExerciseRows) {
+	now := s.now().UTC().Format(time.RFC3339Nano)
+	for i, child := range children {
+		exerciseID := uuid.New().String()
+		child.Exercise["id"] = exerciseID
+		child.Exercise["workout_id"] = workoutID
+		child.Exercise["order"] = float64(i)
+		child.Exercise["created_at"] = now
+		s.tables["workout_exercises"] = append(s.tables["workout_exercises"], child.Exercise)
+
+		for j, set := range child.Sets {
+			set["id"] = uuid.New().String()
+			set["order"] = float64(j)
+			set["exercise_id"] = exerciseID
+			set["created_at"] = now
+			s.tables["workout_sets"] = append(s.tables["workout_sets"], set)
+		}
+	}
+}
@@ -0,0 +1,358 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// selectItem is one entry of a parsed PostgREST select clause
+type selectItem struct {
+	Alias  string // key in the output row
+	Name   string // column name, or table name for embedded relations
+	Star   bool
+	Embed  bool
+	Nested []selectItem
+}
+
+// parseSelect parses clauses such as "*, exercises:workout_exercises(*, workout_sets(id, reps))"
+func parseSelect(clause string) ([]selectItem, error) {
+	var items []selectItem
+	for _, part := range splitTopLevel(clause) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if part == "*" {
+			items = append(items, selectItem{Star: true})
+			continue
+		}
+
+		item := selectItem{}
+		name := part
+		if open := strings.Index(part, "("); open >= 0 {
+			if !strings.HasSuffix(part, ")") {
+				return nil, fmt.Errorf("memory store: unbalanced parentheses in select %q", clause)
+			}
+			nested, err := parseSelect(part[open+1 : len(part)-1])
+			if err != nil {
+				return nil, err
+			}
+			item.Embed, item.Nested, name = true, nested, part[:open]
+		}
+
+		if alias, column, ok := strings.Cut(name, ":"); ok {
+			item.Alias, name = strings.TrimSpace(alias), column
+		}
+		// Drop join hints such as "!inner"
+		name, _, _ = strings.Cut(strings.TrimSpace(name), "!")
+		item.Name = name
+		if item.Alias == "" {
+			item.Alias = name
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// splitTopLevel splits on commas that are not inside parentheses
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// project shapes a row according to the select items, embedding related rows
+// through memoryForeignKeys. path is the dotted alias path of the row's table.
+func (s *MemoryStore) project(table string, row memoryRow, items []selectItem, orders []OrderBy, path string) (memoryRow, error) {
+	out := memoryRow{}
+	if len(items) == 0 {
+		items = []selectItem{{Star: true}}
+	}
+
+	for _, item := range items {
+		switch {
+		case item.Star:
+			for k, v := range row {
+				out[k] = v
+			}
+		case !item.Embed:
+			out[item.Alias] = row[item.Name]
+		default:
+			embedded, err := s.embed(table, row, item, orders, joinPath(path, item.Alias))
+			if err != nil {
+				return nil, err
+			}
+			out[item.Alias] = embedded
+		}
+	}
+	return out, nil
+}
+
+// embed resolves an embedded relation: referencing rows become an array and a
+// referenced row becomes a single object
+func (s *MemoryStore) embed(table string, row memoryRow, item selectItem, orders []OrderBy, path string) (interface{}, error) {
+	for _, fk := range memoryForeignKeys {
+		if fk.Table == item.Name && fk.RefTable == table {
+			children := make([]memoryRow, 0)
+			for _, child := range s.tables[fk.Table] {
+				if child[fk.Column] == row["id"] {
+					children = append(children, child)
+				}
+			}
+			sortRows(children, orders, path)
+
+			projected := make([]memoryRow, 0, len(children))
+			for _, child := range children {
+				p, err := s.project(fk.Table, child, item.Nested, orders, path)
+				if err != nil {
+					return nil, err
+				}
+				projected = append(projected, p)
+			}
+			return projected, nil
+		}
+
+		if fk.Table == table && fk.RefTable == item.Name {
+			id, _ := row[fk.Column].(string)
+			if i := s.findRow(fk.RefTable, id); i >= 0 {
+				return s.project(fk.RefTable, s.tables[fk.RefTable][i], item.Nested, orders, path)
+			}
+			return nil, nil
+		}
+	}
+
+	return nil, fmt.Errorf("memory store: could not find a relationship between %s and %s", table, item.Name)
+}
+
+// sortRows orders rows by the terms that apply to relation, keeping the
+// relative order of equal rows. Nulls sort last ascending and first descending.
+func sortRows(rows []memoryRow, orders []OrderBy, relation string) {
+	var terms []OrderBy
+	for _, order := range orders {
+		if order.Relation == relation {
+			terms = append(terms, order)
+		}
+	}
+	if len(terms) == 0 {
+		return
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, term := range terms {
+			cmp := compareValues(rows[i][term.Column], rows[j][term.Column])
+			if term.Descending {
+				cmp = -cmp
+			}
+			if cmp != 0 {
+				return cmp < 0
+			}
+		}
+		return false
+	})
+}
+
+// compareValues compares two JSON values, treating RFC 3339 strings as times
+// and nil as greater than any value
+func compareValues(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+
+	switch av := a.(type) {
+	case float64:
+		if bv, ok := b.(float64); ok {
+			return compareOrdered(av, bv)
+		}
+	case int:
+		if bv, ok := b.(int); ok {
+			return compareOrdered(av, bv)
+		}
+	case bool:
+		if bv, ok := b.(bool); ok {
+			return compareOrdered(boolRank(av), boolRank(bv))
+		}
+	case string:
+		if bv, ok := b.(string); ok {
+			at, aerr := time.Parse(time.RFC3339Nano, av)
+			bt, berr := time.Parse(time.RFC3339Nano, bv)
+			if aerr == nil && berr == nil {
+				return at.Compare(bt)
+			}
+			return strings.Compare(av, bv)
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func compareOrdered[T int | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func joinPath(path, alias string) string {
+	if path == "" {
+		return alias
+	}
+	return path + "." + alias
+}
+
+// normalizeFilters converts filter values to their JSON form so they compare
+// like the stored rows
+func normalizeFilters(filters []Filter) ([]Filter, error) {
+	normalized := make([]Filter, 0, len(filters))
+	for _, filter := range filters {
+		data, err := json.Marshal(filter.Value)
+		if err != nil {
+			return nil, err
+		}
+		var value interface{}
+		if err := json.Unmarshal(data, &value); err != nil {
+			return nil, err
+		}
+		filter.Value = value
+		normalized = append(normalized, filter)
+	}
+	return normalized, nil
+}
+
+// rowMatchesFilters reports whether row satisfies every filter
+func rowMatchesFilters(row memoryRow, filters []Filter) bool {
+	for _, filter := range filters {
+		value := row[filter.Column]
+		switch filter.Operator {
+		case OpEq, OpNeq, OpGt, OpGte, OpLt, OpLte:
+			if value == nil || filter.Value == nil {
+				return false
+			}
+			cmp := compareValues(value, filter.Value)
+			// Numeric columns filtered with a string, as they would be in a URL
+			if fv, ok := filter.Value.(string); ok {
+				var f float64
+				if nv, isNumber := value.(float64); isNumber {
+					if _, err := fmt.Sscan(fv, &f); err == nil {
+						cmp = compareOrdered(nv, f)
+					}
+				}
+			}
+			if !operatorHolds(filter.Operator, cmp) {
+				return false
+			}
+		case OpIn:
+			values := listValues(filter.Value)
+			found := false
+			for _, v := range values {
+				if value != nil && v != nil && compareValues(value, v) == 0 {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		case OpIs:
+			if value != filter.Value {
+				return false
+			}
+		case OpNotIs:
+			if value == filter.Value {
+				return false
+			}
+		case OpLike, OpILike:
+			text, ok := value.(string)
+			if !ok || !likeMatches(text, fmt.Sprint(filter.Value), filter.Operator == OpILike) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func operatorHolds(operator string, cmp int) bool {
+	switch operator {
+	case OpEq:
+		return cmp == 0
+	case OpNeq:
+		return cmp != 0
+	case OpGt:
+		return cmp > 0
+	case OpGte:
+		return cmp >= 0
+	case OpLt:
+		return cmp < 0
+	case OpLte:
+		return cmp <= 0
+	}
+	return false
+}
+
+// likeMatches evaluates a LIKE pattern where both * and % match any run of
+// characters and _ matches a single character
+func likeMatches(text, pattern string, caseInsensitive bool) bool {
+	var b strings.Builder
+	b.WriteString("^")
+	if caseInsensitive {
+		b.WriteString("(?i)")
+	}
+	for _, r := range pattern {
+		switch r {
+		case '*', '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	return err == nil && re.MatchString(text)
+}
+
+// paginate returns rows[offset : offset+limit], with limit 0 meaning no limit
+func paginate(rows []memoryRow, offset, limit int) []memoryRow {
+	if offset >= len(rows) {
+		return rows[:0]
+	}
+	rows = rows[offset:]
+	if limit > 0 && limit < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows
+}
@@ -0,0 +1,202 @@
+package database
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testRow struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Calories *float64 `json:"calories"`
+	LogDate  string   `json:"log_date"`
+}
+
+func ptr[T any](v T) *T { return &v }
+
+// seedFoodLogs stores rows in a fresh memory store
+func seedFoodLogs(t *testing.T) *MemoryStore {
+	t.Helper()
+	s := NewMemoryStore()
+	rows := []testRow{
+		{ID: "1", Name: "Oatmeal", Calories: ptr(300.0), LogDate: "2026-03-01"},
+		{ID: "2", Name: "Banana", Calories: ptr(105.0), LogDate: "2026-03-02"},
+		{ID: "3", Name: "Black coffee", LogDate: "2026-03-02"},
+		{ID: "4", Name: "Oat milk latte", Calories: ptr(190.0), LogDate: "2026-03-03"},
+	}
+	if _, err := s.Insert("food_logs", rows, true); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+	return s
+}
+
+func ids(rows []testRow) []string {
+	out := make([]string, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, row.ID)
+	}
+	return out
+}
+
+func TestMemoryQueryFilters(t *testing.T) {
+	s := seedFoodLogs(t)
+
+	tests := []struct {
+		name  string
+		query func(*QueryBuilder) *QueryBuilder
+		want  []string
+	}{
+		{"eq", func(q *QueryBuilder) *QueryBuilder { return q.Eq("name", "Banana") }, []string{"2"}},
+		{"neq", func(q *QueryBuilder) *QueryBuilder { return q.Neq("name", "Banana") }, []string{"1", "3", "4"}},
+		{"date range", func(q *QueryBuilder) *QueryBuilder {
+			return q.Gte("log_date", "2026-03-02").Lt("log_date", "2026-03-03")
+		}, []string{"2", "3"}},
+		{"numbers compare as numbers", func(q *QueryBuilder) *QueryBuilder { return q.Gt("calories", 190) }, []string{"1"}},
+		{"numbers given as strings", func(q *QueryBuilder) *QueryBuilder { return q.Lte("calories", "190") }, []string{"2", "4"}},
+		{"comparisons skip nulls", func(q *QueryBuilder) *QueryBuilder { return q.Neq("calories", 300) }, []string{"2", "4"}},
+		{"in", func(q *QueryBuilder) *QueryBuilder { return q.In("id", "1", "4", "9") }, []string{"1", "4"}},
+		{"in with a typed slice", func(q *QueryBuilder) *QueryBuilder {
+			return q.Where(Filter{Column: "id", Operator: OpIn, Value: []string{"1", "4"}})
+		}, []string{"1", "4"}},
+		{"is null", func(q *QueryBuilder) *QueryBuilder { return q.Is("calories", nil) }, []string{"3"}},
+		{"is not null", func(q *QueryBuilder) *QueryBuilder { return q.IsNot("calories", nil) }, []string{"1", "2", "4"}},
+		{"like is case-sensitive", func(q *QueryBuilder) *QueryBuilder { return q.Like("name", "oat*") }, []string{}},
+		{"ilike", func(q *QueryBuilder) *QueryBuilder { return q.ILike("name", "oat*") }, []string{"1", "4"}},
+		{"like escapes regexp syntax", func(q *QueryBuilder) *QueryBuilder { return q.Like("name", "Oat.*") }, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := All[testRow](tt.query(From(s, "food_logs").Order("id", false)))
+			if err != nil {
+				t.Fatalf("query: %v", err)
+			}
+			if got := ids(rows); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryQueryOrderAndPage(t *testing.T) {
+	s := seedFoodLogs(t)
+
+	// Nulls sort last ascending and first descending, like Postgres
+	rows, _ := All[testRow](From(s, "food_logs").Order("calories", false))
+	if got := ids(rows); !reflect.DeepEqual(got, []string{"2", "4", "1", "3"}) {
+		t.Errorf("ascending = %v", got)
+	}
+	rows, _ = All[testRow](From(s, "food_logs").Order("calories", true))
+	if got := ids(rows); !reflect.DeepEqual(got, []string{"3", "1", "4", "2"}) {
+		t.Errorf("descending = %v", got)
+	}
+
+	// Ties keep the next order term
+	rows, _ = All[testRow](From(s, "food_logs").Order("log_date", true).Order("name", false))
+	if got := ids(rows); !reflect.DeepEqual(got, []string{"4", "2", "3", "1"}) {
+		t.Errorf("log_date desc, name = %v", got)
+	}
+
+	rows, total, err := Page[testRow](From(s, "food_logs").Order("id", false).Limit(2).Offset(1).Count())
+	if err != nil {
+		t.Fatalf("Page: %v", err)
+	}
+	if got := ids(rows); total != 4 || !reflect.DeepEqual(got, []string{"2", "3"}) {
+		t.Errorf("page = %v of %d, want [2 3] of 4", got, total)
+	}
+	if _, total, _ := Page[testRow](From(s, "food_logs")); total != -1 {
+		t.Errorf("total without Count = %d, want -1", total)
+	}
+}
+
+func TestMemoryQuerySingle(t *testing.T) {
+	s := seedFoodLogs(t)
+
+	row, err := One[testRow](From(s, "food_logs").Eq("id", "2"))
+	if err != nil || row.Name != "Banana" {
+		t.Errorf("One = %+v, %v", row, err)
+	}
+	if _, err := One[testRow](From(s, "food_logs").Eq("id", "9")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("no match: err = %v, want ErrNotFound", err)
+	}
+	if _, err := One[testRow](From(s, "food_logs").Eq("log_date", "2026-03-02")); !errors.Is(err, ErrMultipleRows) {
+		t.Errorf("two matches: err = %v, want ErrMultipleRows", err)
+	}
+}
+
+func TestMemoryEmbedAndCascade(t *testing.T) {
+	s := NewMemoryStore()
+	insert := func(table string, row map[string]interface{}) {
+		t.Helper()
+		if _, err := s.Insert(table, row, true); err != nil {
+			t.Fatalf("Insert %s: %v", table, err)
+		}
+	}
+	insert("exercises", map[string]interface{}{"id": "bench", "name": "Bench Press"})
+	insert("workout_sessions", map[string]interface{}{"id": "w1", "workout_name": "Push", "workout_date": time.Date(2026, 3, 2, 18, 0, 0, 0, time.UTC)})
+	insert("workout_exercises", map[string]interface{}{"id": "e2", "workout_id": "w1", "exercise_id": "bench", "order": 1})
+	insert("workout_exercises", map[string]interface{}{"id": "e1", "workout_id": "w1", "exercise_id": nil, "order": 0})
+	insert("workout_sets", map[string]interface{}{"id": "s2", "exercise_id": "e2", "order": 1})
+	insert("workout_sets", map[string]interface{}{"id": "s1", "exercise_id": "e2", "order": 0})
+
+	type set struct {
+		ID string `json:"id"`
+	}
+	type exercise struct {
+		ID      string `json:"id"`
+		Catalog *struct {
+			Name string `json:"name"`
+		} `json:"catalog"`
+		Sets []set `json:"sets"`
+	}
+	type workout struct {
+		ID        string     `json:"id"`
+		Exercises []exercise `json:"exercises"`
+	}
+
+	query := From(s, "workout_sessions").
+		Select("id, exercises:workout_exercises(id, catalog:exercises(name), sets:workout_sets(id))").
+		OrderRelation("exercises", "order", false).
+		OrderRelation("exercises.sets", "order", false)
+	got, err := One[workout](query)
+	if err != nil {
+		t.Fatalf("One: %v", err)
+	}
+	if len(got.Exercises) != 2 || got.Exercises[0].ID != "e1" || got.Exercises[1].ID != "e2" {
+		t.Fatalf("exercises = %+v, want e1 then e2", got.Exercises)
+	}
+	if got.Exercises[0].Catalog != nil || got.Exercises[1].Catalog == nil || got.Exercises[1].Catalog.Name != "Bench Press" {
+		t.Errorf("to-one embeds = %+v, %+v", got.Exercises[0].Catalog, got.Exercises[1].Catalog)
+	}
+	if sets := got.Exercises[1].Sets; len(sets) != 2 || sets[0].ID != "s1" || sets[1].ID != "s2" {
+		t.Errorf("sets = %+v, want s1 then s2", sets)
+	}
+
+	// Deleting the catalog exercise clears the reference (ON DELETE SET NULL)
+	s.Delete("exercises", "bench", true)
+	row, _ := One[map[string]interface{}](From(s, "workout_exercises").Eq("id", "e2"))
+	if (*row)["exercise_id"] != nil {
+		t.Errorf("exercise_id = %v after deleting the catalog exercise, want null", (*row)["exercise_id"])
+	}
+
+	// Deleting the workout removes its exercises and their sets
+	s.Delete("workout_sessions", "w1", true)
+	for _, table := range []string{"workout_exercises", "workout_sets"} {
+		if rows, _ := All[map[string]interface{}](From(s, table)); len(rows) != 0 {
+			t.Errorf("%s has %d rows after deleting the workout", table, len(rows))
+		}
+	}
+}
+
+func TestMemoryUpsert(t *testing.T) {
+	s := NewMemoryStore()
+	s.Upsert("body_metrics", map[string]interface{}{"user_id": "u1", "log_date": "2026-03-01", "body_weight_kg": 80}, "user_id,log_date", true)
+	s.Upsert("body_metrics", map[string]interface{}{"user_id": "u1", "log_date": "2026-03-01", "body_weight_kg": 79.5}, "user_id,log_date", true)
+	s.Upsert("body_metrics", map[string]interface{}{"user_id": "u1", "log_date": "2026-03-02", "body_weight_kg": 79}, "user_id,log_date", true)
+
+	rows, _ := All[map[string]interface{}](From(s, "body_metrics").Order("log_date", false))
+	if len(rows) != 2 || rows[0]["body_weight_kg"] != 79.5 {
+		t.Errorf("rows = %v, want the first day merged to 79.5 and a second day", rows)
+	}
+}
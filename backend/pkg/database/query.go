@@ -0,0 +1,337 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+var (
+	// ErrNotFound is returned by single-row queries that match no rows
+	ErrNotFound = errors.New("database: no rows found")
+	// ErrMultipleRows is returned by single-row queries that match several rows
+	ErrMultipleRows = errors.New("database: more than one row found")
+)
+
+// Filter operators supported by QueryOptions.Filters
+const (
+	OpEq    = "eq"
+	OpNeq   = "neq"
+	OpGt    = "gt"
+	OpGte   = "gte"
+	OpLt    = "lt"
+	OpLte   = "lte"
+	OpIn    = "in" // Value is a slice of any element type
+	OpIs    = "is" // Value is nil, true or false
+	OpNotIs = "not.is"
+	OpLike  = "like"
+	OpILike = "ilike" // Case-insensitive; use * as the wildcard
+)
+
+// QueryOptions describe a read of a table
+type QueryOptions struct {
+	// Select is a PostgREST select clause. Related tables are embedded with
+	// "alias:table(columns)", e.g. "*, sets:workout_sets(*)".
+	Select string
+	// Filters must all hold for a row to be returned
+	Filters []Filter
+	// Order sorts the top-level rows and the rows of embedded relations
+	Order []OrderBy
+	// Limit caps the number of rows returned, starting at Offset (0 = no limit)
+	Limit  int
+	Offset int
+	// Count requests the total number of matching rows, ignoring Limit
+	Count bool
+	// Single returns exactly one row as an object instead of an array
+	Single bool
+}
+
+// Filter compares a column against a value
+type Filter struct {
+	Column   string
+	Operator string
+	Value    interface{}
+}
+
+// OrderBy sorts rows by a column
+type OrderBy struct {
+	// Relation is the dotted path of an embedded relation (by alias), or
+	// empty for the top-level table
+	Relation   string
+	Column     string
+	Descending bool
+}
+
+// QueryBuilder builds a typed, properly encoded read against a Store:
+//
+//	workouts, err := database.All[models.Workout](
+//		database.From(db, "workout_sessions").Eq("user_id", userID).Order("workout_date", true))
+type QueryBuilder struct {
+	store         Store
+	table         string
+	opts          QueryOptions
+	useServiceKey bool
+}
+
+// From starts a query on table
+func From(store Store, table string) *QueryBuilder {
+	return &QueryBuilder{store: store, table: table}
+}
+
+// Select sets the columns and embedded relations to return
+func (q *QueryBuilder) Select(columns string) *QueryBuilder {
+	q.opts.Select = columns
+	return q
+}
+
+// Eq keeps rows where column equals value
+func (q *QueryBuilder) Eq(column string, value interface{}) *QueryBuilder {
+	return q.filter(column, OpEq, value)
+}
+
+// Neq keeps rows where column does not equal value
+func (q *QueryBuilder) Neq(column string, value interface{}) *QueryBuilder {
+	return q.filter(column, OpNeq, value)
+}
+
+// Gt keeps rows where column is greater than value
+func (q *QueryBuilder) Gt(column string, value interface{}) *QueryBuilder {
+	return q.filter(column, OpGt, value)
+}
+
+// Gte keeps rows where column is greater than or equal to value
+func (q *QueryBuilder) Gte(column string, value interface{}) *QueryBuilder {
+	return q.filter(column, OpGte, value)
+}
+
+// Lt keeps rows where column is less than value
+func (q *QueryBuilder) Lt(column string, value interface{}) *QueryBuilder {
+	return q.filter(column, OpLt, value)
+}
+
+// Lte keeps rows where column is less than or equal to value
+func (q *QueryBuilder) Lte(column string, value interface{}) *QueryBuilder {
+	return q.filter(column, OpLte, value)
+}
+
+// In keeps rows where column equals one of values
+func (q *QueryBuilder) In(column string, values ...interface{}) *QueryBuilder {
+	return q.filter(column, OpIn, values)
+}
+
+// Is keeps rows where column IS value, which must be nil, true or false
+func (q *QueryBuilder) Is(column string, value interface{}) *QueryBuilder {
+	return q.filter(column, OpIs, value)
+}
+
+// IsNot keeps rows where column IS NOT value, e.g. IsNot(column, nil) for
+// columns that are set
+func (q *QueryBuilder) IsNot(column string, value interface{}) *QueryBuilder {
+	return q.filter(column, OpNotIs, value)
+}
+
+// Like keeps rows where column matches pattern; * and % match any characters
+func (q *QueryBuilder) Like(column, pattern string) *QueryBuilder {
+	return q.filter(column, OpLike, pattern)
+}
+
+// ILike is the case-insensitive Like
+func (q *QueryBuilder) ILike(column, pattern string) *QueryBuilder {
+	return q.filter(column, OpILike, pattern)
+}
+
+// Where appends a prebuilt filter
+func (q *QueryBuilder) Where(filters ...Filter) *QueryBuilder {
+	q.opts.Filters = append(q.opts.Filters, filters...)
+	return q
+}
+
+// Order sorts the top-level rows by column; repeated calls add tie-breakers
+func (q *QueryBuilder) Order(column string, descending bool) *QueryBuilder {
+	q.opts.Order = append(q.opts.Order, OrderBy{Column: column, Descending: descending})
+	return q
+}
+
+// OrderRelation sorts the rows of an embedded relation, addressed by its
+// dotted alias path such as "exercises.sets"
+func (q *QueryBuilder) OrderRelation(relation, column string, descending bool) *QueryBuilder {
+	q.opts.Order = append(q.opts.Order, OrderBy{Relation: relation, Column: column, Descending: descending})
+	return q
+}
+
+// Limit caps the number of rows returned
+func (q *QueryBuilder) Limit(n int) *QueryBuilder {
+	q.opts.Limit = n
+	return q
+}
+
+// Offset skips the first n rows
+func (q *QueryBuilder) Offset(n int) *QueryBuilder {
+	q.opts.Offset = n
+	return q
+}
+
+// Count requests the total number of matching rows
+func (q *QueryBuilder) Count() *QueryBuilder {
+	q.opts.Count = true
+	return q
+}
+
+// ServiceKey runs the query with the service role, bypassing row level security
+func (q *QueryBuilder) ServiceKey() *QueryBuilder {
+	q.useServiceKey = true
+	return q
+}
+
+// Options returns the options built so far
+func (q *QueryBuilder) Options() QueryOptions {
+	return q.opts
+}
+
+// Execute runs the query and returns the raw JSON rows and the total count
+func (q *QueryBuilder) Execute() ([]byte, int, error) {
+	return q.store.Query(q.table, q.opts, q.useServiceKey)
+}
+
+func (q *QueryBuilder) filter(column, operator string, value interface{}) *QueryBuilder {
+	q.opts.Filters = append(q.opts.Filters, Filter{Column: column, Operator: operator, Value: value})
+	return q
+}
+
+// All runs the query and decodes every row into T
+func All[T any](q *QueryBuilder) ([]T, error) {
+	rows, _, err := Page[T](q)
+	return rows, err
+}
+
+// Page runs the query and decodes the rows into T, also returning the total
+// number of matching rows when Count was requested (-1 otherwise)
+func Page[T any](q *QueryBuilder) ([]T, int, error) {
+	q.opts.Single = false
+	data, total, err := q.Execute()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows := make([]T, 0)
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, 0, fmt.Errorf("database: decoding %s rows: %w", q.table, err)
+	}
+	return rows, total, nil
+}
+
+// One runs the query in single-row mode and decodes the row into T. It
+// returns ErrNotFound when no row matches and ErrMultipleRows when several do.
+func One[T any](q *QueryBuilder) (*T, error) {
+	q.opts.Single = true
+	data, _, err := q.Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	var row T
+	if err := json.Unmarshal(data, &row); err != nil {
+		return nil, fmt.Errorf("database: decoding %s row: %w", q.table, err)
+	}
+	return &row, nil
+}
+
+// encodeQuery renders opts as URL-encoded PostgREST query parameters
+func encodeQuery(opts QueryOptions) string {
+	params := url.Values{}
+	for _, filter := range opts.Filters {
+		params.Add(filter.Column, filter.Operator+"."+filterValue(filter))
+	}
+	if opts.Select != "" {
+		params.Set("select", opts.Select)
+	}
+	for relation, order := range orderParams(opts.Order) {
+		params.Set(relation, order)
+	}
+	return params.Encode()
+}
+
+// idQuery renders the URL-encoded PostgREST filter selecting the row with
+// the given id, so an id taken from a request cannot add filters of its own
+func idQuery(id string) string {
+	return encodeQuery(QueryOptions{Filters: []Filter{{Column: "id", Operator: OpEq, Value: id}}})
+}
+
+// filterValue renders the right-hand side of a PostgREST filter
+func filterValue(filter Filter) string {
+	switch filter.Operator {
+	case OpIn:
+		values := listValues(filter.Value)
+		quoted := make([]string, 0, len(values))
+		for _, v := range values {
+			quoted = append(quoted, quoteListValue(formatValue(v)))
+		}
+		return "(" + strings.Join(quoted, ",") + ")"
+	case OpIs, OpNotIs:
+		if filter.Value == nil {
+			return "null"
+		}
+		return formatValue(filter.Value)
+	}
+	return formatValue(filter.Value)
+}
+
+// listValues returns the elements of an OpIn value, which can be a slice or
+// array of any type. Any other value is a list of that single value.
+func listValues(value interface{}) []interface{} {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return []interface{}{value}
+	}
+	values := make([]interface{}, v.Len())
+	for i := range values {
+		values[i] = v.Index(i).Interface()
+	}
+	return values
+}
+
+// quoteListValue double-quotes values that contain PostgREST reserved characters
+func quoteListValue(s string) string {
+	if !strings.ContainsAny(s, ",().:\\\" ") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// formatValue renders a filter value the way it appears in JSON, so times
+// use RFC 3339 and dates use YYYY-MM-DD
+func formatValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		return s
+	}
+	return string(data)
+}
+
+// orderParams groups the order terms into PostgREST query parameters,
+// e.g. "order" and "exercises.order"
+func orderParams(orders []OrderBy) map[string]string {
+	params := make(map[string]string)
+	for _, order := range orders {
+		key := "order"
+		if order.Relation != "" {
+			key = order.Relation + ".order"
+		}
+		term := order.Column + ".asc"
+		if order.Descending {
+			term = order.Column + ".desc"
+		}
+		if params[key] != "" {
+			term = params[key] + "," + term
+		}
+		params[key] = term
+	}
+	return params
+}
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"fmt"
+	"os"
+)
+
+// Store is the persistence surface used by the HTTP handlers. It mirrors the
+// PostgREST and GoTrue calls made against Supabase so that handlers can run
+// against either a live project or the in-memory implementation.
+type Store interface {
+	// Query reads the rows of table described by opts. It returns a JSON
+	// array (or a single object when opts.Single is set) and, when opts.Count
+	// is set, the total number of matching rows (-1 otherwise). Prefer the
+	// typed QueryBuilder returned by From over calling Query directly.
+	Query(table string, opts QueryOptions, useServiceKey bool) ([]byte, int, error)
+	// Insert inserts one row (or a slice of rows) and returns the stored rows
+	Insert(table string, data interface{}, useServiceKey bool) ([]byte, error)
+	// Update patches the row with the given id and returns the stored rows
+	Update(table string, id string, data interface{}, useServiceKey bool) ([]byte, error)
+	// Upsert inserts a row or, when it collides with an existing row on the
+	// comma-separated onConflict columns, merges it into that row
+	Upsert(table string, data interface{}, onConflict string, useServiceKey bool) ([]byte, error)
+	// Delete removes the row with the given id
+	Delete(table string, id string, useServiceKey bool) error
+	// RPC calls a database function with named params and returns its result
+	RPC(function string, params interface{}, useServiceKey bool) ([]byte, error)
+	// WithAccessToken returns a Store whose calls without the service key are
+	// made as the user owning accessToken, so row level security applies
+	WithAccessToken(accessToken string) Store
+	// AuthSignUp creates a new user account
+	AuthSignUp(email, password string) ([]byte, error)
+	// AuthSignIn signs a user in with email and password
+	AuthSignIn(email, password string) ([]byte, error)
+	// AuthRefresh exchanges a refresh token for a new session
+	AuthRefresh(refreshToken string) ([]byte, error)
+	// AuthGenerateLink creates a one-time email code of the given LinkType
+	// without sending it, so the caller can deliver it with its own mailer.
+	// Signup links also create the (unconfirmed) user and need a password.
+	AuthGenerateLink(linkType, email, password string) ([]byte, error)
+	// AuthVerify exchanges a one-time email code for a session. Verifying a
+	// code also confirms the user's email address.
+	AuthVerify(linkType, email, token string) ([]byte, error)
+	// AuthUpdateUser changes the attributes (such as the password) of the
+	// user owning accessToken
+	AuthUpdateUser(accessToken string, attributes map[string]interface{}) ([]byte, error)
+	// AuthSignOut ends the GoTrue session accessToken belongs to, or with
+	// SignOutGlobal every session of its user, so their refresh tokens stop
+	// working
+	AuthSignOut(accessToken, scope string) error
+	// AuthDeleteUser deletes a user account together with its GoTrue
+	// sessions. Every row referencing the user is removed by the ON DELETE
+	// CASCADE foreign keys.
+	AuthDeleteUser(userID string) error
+}
+
+// Sign-out scopes accepted by AuthSignOut
+const (
+	SignOutLocal  = "local"
+	SignOutGlobal = "global"
+)
+
+// One-time email code types accepted by AuthGenerateLink and AuthVerify
+const (
+	LinkTypeSignup   = "signup"
+	LinkTypeRecovery = "recovery"
+)
+
+// Backend names accepted by NewStore
+const (
+	BackendSupabase = "supabase"
+	BackendMemory   = "memory"
+)
+
+// Compile-time checks that both backends satisfy Store
+var (
+	_ Store = (*SupabaseClient)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
+
+// BackendFromEnv returns the storage backend selected by DATABASE_BACKEND,
+// defaulting to Supabase
+func BackendFromEnv() string {
+	backend := os.Getenv("DATABASE_BACKEND")
+	if backend == "" {
+		return BackendSupabase
+	}
+	return backend
+}
+
+// NewStore creates the Store for the named backend
+func NewStore(backend string) (Store, error) {
+	switch backend {
+	case BackendSupabase:
+		return NewSupabaseClient(), nil
+	case BackendMemory:
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown database backend %q", backend)
+	}
+}
@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	neturl "net/url"
 	"os"
+	"strconv"
+	"strings"
 )
 
 // SupabaseClient handles interactions with Supabase
@@ -14,7 +17,10 @@ type SupabaseClient struct {
 	URL        string
 	AnonKey    string
 	ServiceKey string
-	HTTPClient *http.Client
+	// AccessToken is the signed-in user's Supabase JWT. When set it replaces
+	// the anon key as the bearer, so PostgREST evaluates RLS as that user.
+	AccessToken string
+	HTTPClient  *http.Client
 }
 
 // NewSupabaseClient creates a new Supabase client
@@ -27,41 +33,93 @@ func NewSupabaseClient() *SupabaseClient {
 	}
 }
 
-// Query executes a query on a Supabase table
-func (c *SupabaseClient) Query(table string, query map[string]interface{}, useServiceKey bool) ([]byte, error) {
+// Query executes a query on a Supabase table, embedding related tables,
+// filtering, ordering and paginating rows as described by opts
+func (c *SupabaseClient) Query(table string, opts QueryOptions, useServiceKey bool) ([]byte, int, error) {
 	url := fmt.Sprintf("%s/rest/v1/%s", c.URL, table)
-	
-	// Build query parameters
-	if len(query) > 0 {
-		url += "?"
-		for k, v := range query {
-			url += fmt.Sprintf("%s=eq.%v&", k, v)
-		}
+	if params := encodeQuery(opts); params != "" {
+		url += "?" + params
 	}
-	
+
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	
+
 	c.setHeaders(req, useServiceKey)
-	
+	if opts.Limit > 0 {
+		req.Header.Set("Range-Unit", "items")
+		req.Header.Set("Range", fmt.Sprintf("%d-%d", opts.Offset, opts.Offset+opts.Limit-1))
+	} else if opts.Offset > 0 {
+		req.Header.Set("Range-Unit", "items")
+		req.Header.Set("Range", fmt.Sprintf("%d-", opts.Offset))
+	}
+	if opts.Count {
+		req.Header.Set("Prefer", "count=exact")
+	}
+	if opts.Single {
+		req.Header.Set("Accept", "application/vnd.pgrst.object+json")
+	}
+
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer resp.Body.Close()
-	
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	
+
+	// 416 means the offset is past the last row; PostgREST still reports the total
+	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		return []byte("[]"), contentRangeTotal(resp.Header.Get("Content-Range")), nil
+	}
+
+	// 406 in single-object mode means zero or several rows matched
+	if opts.Single && resp.StatusCode == http.StatusNotAcceptable {
+		return nil, 0, singleRowError(body)
+	}
+
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("supabase error: %s", string(body))
+		return nil, 0, fmt.Errorf("supabase error: %s", string(body))
 	}
-	
-	return body, nil
+
+	total := -1
+	if opts.Count {
+		total = contentRangeTotal(resp.Header.Get("Content-Range"))
+	}
+
+	return body, total, nil
+}
+
+// singleRowError maps a PostgREST PGRST116 error to ErrNotFound or ErrMultipleRows
+func singleRowError(body []byte) error {
+	var pgErr struct {
+		Code    string `json:"code"`
+		Details string `json:"details"`
+	}
+	if err := json.Unmarshal(body, &pgErr); err != nil || pgErr.Code != "PGRST116" {
+		return fmt.Errorf("supabase error: %s", string(body))
+	}
+	if strings.Contains(pgErr.Details, " 0 rows") {
+		return ErrNotFound
+	}
+	return ErrMultipleRows
+}
+
+// contentRangeTotal reads the total from a header such as "0-24/312" or "*/0"
+func contentRangeTotal(header string) int {
+	_, total, ok := strings.Cut(header, "/")
+	if !ok {
+		return -1
+	}
+	n, err := strconv.Atoi(total)
+	if err != nil {
+		return -1
+	}
+	return n
 }
 
 // Insert inserts data into a Supabase table
@@ -101,7 +159,7 @@ func (c *SupabaseClient) Insert(table string, data interface{}, useServiceKey bo
 
 // Update updates data in a Supabase table
 func (c *SupabaseClient) Update(table string, id string, data interface{}, useServiceKey bool) ([]byte, error) {
-	url := fmt.Sprintf("%s/rest/v1/%s?id=eq.%s", c.URL, table, id)
+	url := fmt.Sprintf("%s/rest/v1/%s?%s", c.URL, table, idQuery(id))
 	
 	jsonData, err := json.Marshal(data)
 	if err != nil {
@@ -134,9 +192,45 @@ func (c *SupabaseClient) Update(table string, id string, data interface{}, useSe
 	return body, nil
 }
 
+// Upsert inserts data into a Supabase table, merging rows that conflict on
+// the onConflict columns
+func (c *SupabaseClient) Upsert(table string, data interface{}, onConflict string, useServiceKey bool) ([]byte, error) {
+	endpoint := fmt.Sprintf("%s/rest/v1/%s?on_conflict=%s", c.URL, table, neturl.QueryEscape(onConflict))
+
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+
+	c.setHeaders(req, useServiceKey)
+	req.Header.Set("Prefer", "resolution=merge-duplicates,return=representation")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("supabase error: %s", string(body))
+	}
+
+	return body, nil
+}
+
 // Delete deletes data from a Supabase table
 func (c *SupabaseClient) Delete(table string, id string, useServiceKey bool) error {
-	url := fmt.Sprintf("%s/rest/v1/%s?id=eq.%s", c.URL, table, id)
+	url := fmt.Sprintf("%s/rest/v1/%s?%s", c.URL, table, idQuery(id))
 	
 	req, err := http.NewRequest("DELETE", url, nil)
 	if err != nil {
@@ -159,6 +253,49 @@ func (c *SupabaseClient) Delete(table string, id string, useServiceKey bool) err
 	return nil
 }
 
+// RPC calls a Postgres function through PostgREST. The function runs in a
+// single transaction, so multi-table writes either fully apply or not at all.
+func (c *SupabaseClient) RPC(function string, params interface{}, useServiceKey bool) ([]byte, error) {
+	url := fmt.Sprintf("%s/rest/v1/rpc/%s", c.URL, function)
+
+	jsonData, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+
+	c.setHeaders(req, useServiceKey)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("supabase error: %s", string(body))
+	}
+
+	return body, nil
+}
+
+// WithAccessToken returns a copy of the client that acts as the user owning
+// accessToken
+func (c *SupabaseClient) WithAccessToken(accessToken string) Store {
+	scoped := *c
+	scoped.AccessToken = accessToken
+	return &scoped
+}
+
 // AuthSignUp creates a new user with Supabase Auth
 func (c *SupabaseClient) AuthSignUp(email, password string) ([]byte, error) {
 	url := fmt.Sprintf("%s/auth/v1/signup", c.URL)
@@ -239,11 +376,143 @@ func (c *SupabaseClient) AuthSignIn(email, password string) ([]byte, error) {
 	return body, nil
 }
 
+// AuthRefresh exchanges a Supabase refresh token for a new session
+func (c *SupabaseClient) AuthRefresh(refreshToken string) ([]byte, error) {
+	url := fmt.Sprintf("%s/auth/v1/token?grant_type=refresh_token", c.URL)
+	
+	data := map[string]string{
+		"refresh_token": refreshToken,
+	}
+	
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	
+	req.Header.Set("apikey", c.AnonKey)
+	req.Header.Set("Content-Type", "application/json")
+	
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("refresh error: %s", string(body))
+	}
+	
+	return body, nil
+}
+
+// AuthGenerateLink creates a signup or recovery code through GoTrue's admin
+// generate_link endpoint, which unlike /signup and /recover does not email it.
+// The response is the user with email_otp and hashed_token fields added.
+func (c *SupabaseClient) AuthGenerateLink(linkType, email, password string) ([]byte, error) {
+	data := map[string]string{
+		"type":  linkType,
+		"email": email,
+	}
+	if password != "" {
+		data["password"] = password
+	}
+
+	return c.authRequest("POST", "/auth/v1/admin/generate_link", true, "", data, "generate link error")
+}
+
+// AuthVerify exchanges an email code for a session with GoTrue's verify endpoint
+func (c *SupabaseClient) AuthVerify(linkType, email, token string) ([]byte, error) {
+	data := map[string]string{
+		"type":  linkType,
+		"email": email,
+		"token": token,
+	}
+
+	return c.authRequest("POST", "/auth/v1/verify", false, "", data, "verify error")
+}
+
+// AuthUpdateUser updates the user owning accessToken
+func (c *SupabaseClient) AuthUpdateUser(accessToken string, attributes map[string]interface{}) ([]byte, error) {
+	return c.authRequest("PUT", "/auth/v1/user", false, accessToken, attributes, "update user error")
+}
+
+// AuthSignOut ends the user's sessions with GoTrue's logout endpoint
+func (c *SupabaseClient) AuthSignOut(accessToken, scope string) error {
+	_, err := c.authRequest("POST", "/auth/v1/logout?scope="+neturl.QueryEscape(scope), false, accessToken, map[string]interface{}{}, "sign out error")
+	return err
+}
+
+// AuthDeleteUser deletes a user with GoTrue's admin endpoint
+func (c *SupabaseClient) AuthDeleteUser(userID string) error {
+	data := map[string]interface{}{
+		"should_soft_delete": false,
+	}
+
+	_, err := c.authRequest("DELETE", "/auth/v1/admin/users/"+neturl.PathEscape(userID), true, "", data, "delete user error")
+	return err
+}
+
+// authRequest sends a JSON request to a GoTrue endpoint, authorized with the
+// service key for admin endpoints or with a user's access token when set
+func (c *SupabaseClient) authRequest(method, path string, useServiceKey bool, accessToken string, data interface{}, errPrefix string) ([]byte, error) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, c.URL+path, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case useServiceKey:
+		req.Header.Set("apikey", c.ServiceKey)
+		req.Header.Set("Authorization", "Bearer "+c.ServiceKey)
+	case accessToken != "":
+		req.Header.Set("apikey", c.AnonKey)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	default:
+		req.Header.Set("apikey", c.AnonKey)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("%s: %s", errPrefix, string(body))
+	}
+
+	return body, nil
+}
+
 // setHeaders sets common headers for Supabase requests
 func (c *SupabaseClient) setHeaders(req *http.Request, useServiceKey bool) {
 	if useServiceKey {
 		req.Header.Set("apikey", c.ServiceKey)
 		req.Header.Set("Authorization", "Bearer "+c.ServiceKey)
+	} else if c.AccessToken != "" {
+		req.Header.Set("apikey", c.AnonKey)
+		req.Header.Set("Authorization", "Bearer "+c.AccessToken)
 	} else {
 		req.Header.Set("apikey", c.AnonKey)
 		req.Header.Set("Authorization", "Bearer "+c.AnonKey)
@@ -0,0 +1,94 @@
+package database
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+// recordQueries starts a fake PostgREST server and returns a client for it
+// together with the query of every request it received
+func recordQueries(t *testing.T) (*SupabaseClient, *[]url.Values) {
+	t.Helper()
+	var queries []url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query())
+		w.Write([]byte("[]"))
+	}))
+	t.Cleanup(server.Close)
+	return &SupabaseClient{URL: server.URL, HTTPClient: server.Client()}, &queries
+}
+
+func TestSupabaseIDIsEscaped(t *testing.T) {
+	client, queries := recordQueries(t)
+	id := "x&user_id=neq.0"
+
+	if _, err := client.Update("food_logs", id, map[string]interface{}{"calories_estimated": 0}, false); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := client.Delete("food_logs", id, false); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	want := url.Values{"id": {"eq." + id}}
+	for i, query := range *queries {
+		if !reflect.DeepEqual(query, want) {
+			t.Errorf("request %d query = %v, want %v", i, query, want)
+		}
+	}
+}
+
+func TestEncodeQuery(t *testing.T) {
+	opts := QueryOptions{
+		Select: "*, sets:workout_sets(*)",
+		Filters: []Filter{
+			{Column: "user_id", Operator: OpEq, Value: "u1&id=neq.0"},
+			{Column: "name", Operator: OpIn, Value: []interface{}{`Bench, "close"`, "Squat"}},
+			{Column: "deleted_at", Operator: OpIs, Value: nil},
+			{Column: "id", Operator: OpIn, Value: []string{"1", "2"}},
+		},
+		Order: []OrderBy{
+			{Column: "workout_date", Descending: true},
+			{Column: "id"},
+			{Relation: "sets", Column: "order"},
+		},
+	}
+
+	got, err := url.ParseQuery(encodeQuery(opts))
+	if err != nil {
+		t.Fatalf("encodeQuery produced an invalid query: %v", err)
+	}
+	want := url.Values{
+		"select":     {"*, sets:workout_sets(*)"},
+		"user_id":    {"eq.u1&id=neq.0"},
+		"name":       {`in.("Bench, \"close\"",Squat)`},
+		"deleted_at": {"is.null"},
+		"id":         {"in.(1,2)"},
+		"order":      {"workout_date.desc,id.asc"},
+		"sets.order": {"order.asc"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("encodeQuery = %v, want %v", got, want)
+	}
+}
+
+func TestSupabaseQueryRange(t *testing.T) {
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+	client := &SupabaseClient{URL: server.URL, HTTPClient: server.Client()}
+
+	for _, opts := range []QueryOptions{{}, {Limit: 10, Offset: 20}, {Offset: 20}} {
+		if _, _, err := client.Query("food_logs", opts, false); err != nil {
+			t.Fatalf("Query: %v", err)
+		}
+	}
+	if want := []string{"", "20-29", "20-"}; !reflect.DeepEqual(ranges, want) {
+		t.Errorf("Range headers = %q, want %q", ranges, want)
+	}
+}
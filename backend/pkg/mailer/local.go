@@ -0,0 +1,56 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// ConsoleMailer prints messages instead of sending them, for development
+type ConsoleMailer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewConsoleMailer creates a mailer that writes messages to w
+func NewConsoleMailer(w io.Writer) *ConsoleMailer {
+	return &ConsoleMailer{w: w}
+}
+
+// Send writes msg to the console
+func (m *ConsoleMailer) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := fmt.Fprintf(m.w, "📧 To: %s\nSubject: %s\n\n%s\n", msg.To, msg.Subject, msg.Body)
+	return err
+}
+
+// FileMailer writes each message to an .eml file, for development and for
+// inspecting emails in end-to-end tests
+type FileMailer struct {
+	Dir string
+	now func() time.Time
+}
+
+// NewFileMailer creates a mailer that writes messages into dir
+func NewFileMailer(dir string) *FileMailer {
+	return &FileMailer{Dir: dir, now: time.Now}
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9@._-]+`)
+
+// Send writes msg to <dir>/<timestamp>-<recipient>.eml
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	if err := os.MkdirAll(m.Dir, 0700); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", m.now().UTC().Format("20060102T150405.000000000Z"), unsafeFileChars.ReplaceAllString(msg.To, "_"))
+	return os.WriteFile(filepath.Join(m.Dir, name), formatMessage("", msg), 0600)
+}
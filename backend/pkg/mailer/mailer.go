@@ -0,0 +1,109 @@
+// Package mailer sends the transactional emails of the auth flows
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Mailer names accepted by NewMailer
+const (
+	MailerConsole = "console"
+	MailerFile    = "file"
+	MailerSMTP    = "smtp"
+)
+
+// NewMailerFromEnv picks a mailer from MAILER, defaulting to console. The
+// SMTP mailer reads SMTP_HOST, SMTP_PORT (587), SMTP_USERNAME, SMTP_PASSWORD
+// and MAIL_FROM; the file mailer writes to MAIL_DIR (./mail).
+func NewMailerFromEnv() (Mailer, error) {
+	name := os.Getenv("MAILER")
+	if name == "" {
+		name = MailerConsole
+	}
+	return NewMailer(name)
+}
+
+// NewMailer creates the named mailer, configured from the environment
+func NewMailer(name string) (Mailer, error) {
+	switch name {
+	case MailerConsole:
+		return NewConsoleMailer(os.Stdout), nil
+	case MailerFile:
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "mail"
+		}
+		return NewFileMailer(dir), nil
+	case MailerSMTP:
+		port := 587
+		if s := os.Getenv("SMTP_PORT"); s != "" {
+			p, err := strconv.Atoi(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid SMTP_PORT %q", s)
+			}
+			port = p
+		}
+		m := &SMTPMailer{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		}
+		if m.Host == "" || m.From == "" {
+			return nil, fmt.Errorf("the smtp mailer requires SMTP_HOST and MAIL_FROM")
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("unknown mailer %q", name)
+	}
+}
+
+// PasswordReset builds the email carrying a password recovery code. When
+// appURL is set the message also links to the app's reset page.
+func PasswordReset(to, code, appURL string) Message {
+	var b strings.Builder
+	b.WriteString("Someone asked to reset the password of your FitTrack account.\n\n")
+	fmt.Fprintf(&b, "Your reset code is: %s\n\n", code)
+	if appURL != "" {
+		fmt.Fprintf(&b, "Or open this link to choose a new password:\n%s\n\n", appLink(appURL, "reset-password", to, code))
+	}
+	b.WriteString("The code expires in one hour. If you did not ask for this, you can ignore this email.\n")
+
+	return Message{To: to, Subject: "Reset your FitTrack password", Body: b.String()}
+}
+
+// EmailConfirmation builds the email carrying a signup confirmation code
+func EmailConfirmation(to, code, appURL string) Message {
+	var b strings.Builder
+	b.WriteString("Welcome to FitTrack!\n\n")
+	fmt.Fprintf(&b, "Your confirmation code is: %s\n\n", code)
+	if appURL != "" {
+		fmt.Fprintf(&b, "Or open this link to confirm your email address:\n%s\n\n", appLink(appURL, "verify-email", to, code))
+	}
+	b.WriteString("If you did not create an account, you can ignore this email.\n")
+
+	return Message{To: to, Subject: "Confirm your FitTrack account", Body: b.String()}
+}
+
+func appLink(appURL, page, email, code string) string {
+	params := url.Values{"email": {email}, "token": {code}}
+	return strings.TrimRight(appURL, "/") + "/" + page + "?" + params.Encode()
+}
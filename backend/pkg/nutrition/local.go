@@ -0,0 +1,294 @@
+package nutrition
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// food is a dictionary entry with nutrients per 100 g
+type food struct {
+	Name    string
+	Aliases []string
+	Kcal    float64
+	Protein float64
+	Fat     float64
+	Carbs   float64
+	// Serving is the weight in grams of one "piece" when no unit is given
+	Serving float64
+	// Units overrides the default gram weight of household units
+	Units map[string]float64
+}
+
+// foods is the built-in dictionary used by LocalParser
+var foods = []food{
+	{Name: "egg", Aliases: []string{"eggs", "boiled egg", "fried egg", "scrambled egg", "scrambled eggs"}, Kcal: 143, Protein: 12.6, Fat: 9.5, Carbs: 0.7, Serving: 50},
+	{Name: "egg white", Aliases: []string{"egg whites"}, Kcal: 52, Protein: 10.9, Fat: 0.2, Carbs: 0.7, Serving: 33},
+	{Name: "chicken breast", Aliases: []string{"chicken", "grilled chicken"}, Kcal: 165, Protein: 31, Fat: 3.6, Carbs: 0, Serving: 150},
+	{Name: "beef", Aliases: []string{"steak", "ground beef", "minced beef"}, Kcal: 250, Protein: 26, Fat: 17, Carbs: 0, Serving: 150},
+	{Name: "salmon", Aliases: []string{"salmon fillet"}, Kcal: 208, Protein: 20, Fat: 13, Carbs: 0, Serving: 150},
+	{Name: "tuna", Aliases: []string{"canned tuna", "tuna can"}, Kcal: 116, Protein: 26, Fat: 1, Carbs: 0, Serving: 120, Units: map[string]float64{"can": 120}},
+	{Name: "white rice", Aliases: []string{"rice", "cooked rice"}, Kcal: 130, Protein: 2.7, Fat: 0.3, Carbs: 28, Serving: 158, Units: map[string]float64{"cup": 158}},
+	{Name: "brown rice", Kcal: 123, Protein: 2.7, Fat: 1, Carbs: 25.6, Serving: 195, Units: map[string]float64{"cup": 195}},
+	{Name: "pasta", Aliases: []string{"spaghetti", "penne", "noodles"}, Kcal: 158, Protein: 5.8, Fat: 0.9, Carbs: 31, Serving: 140, Units: map[string]float64{"cup": 140}},
+	{Name: "oats", Aliases: []string{"oatmeal", "porridge", "rolled oats"}, Kcal: 389, Protein: 16.9, Fat: 6.9, Carbs: 66, Serving: 40, Units: map[string]float64{"cup": 80}},
+	{Name: "bread", Aliases: []string{"toast", "white bread", "whole wheat bread"}, Kcal: 265, Protein: 9, Fat: 3.2, Carbs: 49, Serving: 30, Units: map[string]float64{"slice": 30}},
+	{Name: "bagel", Kcal: 257, Protein: 10, Fat: 1.6, Carbs: 50, Serving: 105},
+	{Name: "potato", Aliases: []string{"potatoes", "baked potato"}, Kcal: 93, Protein: 2.5, Fat: 0.1, Carbs: 21, Serving: 173},
+	{Name: "sweet potato", Aliases: []string{"sweet potatoes"}, Kcal: 90, Protein: 2, Fat: 0.2, Carbs: 21, Serving: 130},
+	{Name: "banana", Aliases: []string{"bananas"}, Kcal: 89, Protein: 1.1, Fat: 0.3, Carbs: 23, Serving: 118},
+	{Name: "apple", Aliases: []string{"apples"}, Kcal: 52, Protein: 0.3, Fat: 0.2, Carbs: 14, Serving: 182},
+	{Name: "orange", Aliases: []string{"oranges"}, Kcal: 47, Protein: 0.9, Fat: 0.1, Carbs: 12, Serving: 131},
+	{Name: "blueberries", Aliases: []string{"berries"}, Kcal: 57, Protein: 0.7, Fat: 0.3, Carbs: 14, Serving: 148, Units: map[string]float64{"cup": 148}},
+	{Name: "avocado", Aliases: []string{"avocados"}, Kcal: 160, Protein: 2, Fat: 15, Carbs: 9, Serving: 150},
+	{Name: "broccoli", Kcal: 34, Protein: 2.8, Fat: 0.4, Carbs: 7, Serving: 90, Units: map[string]float64{"cup": 90}},
+	{Name: "salad", Aliases: []string{"green salad", "lettuce"}, Kcal: 15, Protein: 1.4, Fat: 0.2, Carbs: 2.9, Serving: 100},
+	{Name: "milk", Aliases: []string{"whole milk"}, Kcal: 61, Protein: 3.2, Fat: 3.3, Carbs: 4.8, Serving: 244, Units: map[string]float64{"cup": 244, "glass": 250}},
+	{Name: "greek yogurt", Aliases: []string{"yogurt", "yoghurt", "greek yoghurt"}, Kcal: 97, Protein: 9, Fat: 5, Carbs: 3.9, Serving: 170, Units: map[string]float64{"cup": 245}},
+	{Name: "cheese", Aliases: []string{"cheddar"}, Kcal: 403, Protein: 25, Fat: 33, Carbs: 1.3, Serving: 28, Units: map[string]float64{"slice": 21}},
+	{Name: "butter", Kcal: 717, Protein: 0.9, Fat: 81, Carbs: 0.1, Serving: 14},
+	{Name: "olive oil", Aliases: []string{"oil"}, Kcal: 884, Protein: 0, Fat: 100, Carbs: 0, Serving: 14},
+	{Name: "peanut butter", Kcal: 588, Protein: 25, Fat: 50, Carbs: 20, Serving: 32},
+	{Name: "almonds", Aliases: []string{"nuts"}, Kcal: 579, Protein: 21, Fat: 50, Carbs: 22, Serving: 28, Units: map[string]float64{"handful": 28}},
+	{Name: "whey protein", Aliases: []string{"protein shake", "protein powder", "whey"}, Kcal: 400, Protein: 80, Fat: 6, Carbs: 8, Serving: 30, Units: map[string]float64{"scoop": 30}},
+	{Name: "pizza", Aliases: []string{"pizza slice"}, Kcal: 266, Protein: 11, Fat: 10, Carbs: 33, Serving: 107, Units: map[string]float64{"slice": 107}},
+	{Name: "burger", Aliases: []string{"hamburger", "cheeseburger"}, Kcal: 295, Protein: 17, Fat: 14, Carbs: 24, Serving: 226},
+	{Name: "french fries", Aliases: []string{"fries", "chips"}, Kcal: 312, Protein: 3.4, Fat: 15, Carbs: 41, Serving: 117},
+	{Name: "coffee", Aliases: []string{"black coffee", "espresso"}, Kcal: 2, Protein: 0.3, Fat: 0, Carbs: 0, Serving: 240, Units: map[string]float64{"cup": 240, "mug": 300}},
+	{Name: "orange juice", Aliases: []string{"juice"}, Kcal: 45, Protein: 0.7, Fat: 0.2, Carbs: 10, Serving: 248, Units: map[string]float64{"cup": 248, "glass": 250}},
+	{Name: "sugar", Kcal: 387, Protein: 0, Fat: 0, Carbs: 100, Serving: 4},
+	{Name: "honey", Kcal: 304, Protein: 0.3, Fat: 0, Carbs: 82, Serving: 21},
+	{Name: "tofu", Kcal: 76, Protein: 8, Fat: 4.8, Carbs: 1.9, Serving: 126},
+	{Name: "lentils", Aliases: []string{"dal", "daal"}, Kcal: 116, Protein: 9, Fat: 0.4, Carbs: 20, Serving: 198, Units: map[string]float64{"cup": 198}},
+	{Name: "hummus", Kcal: 166, Protein: 7.9, Fat: 9.6, Carbs: 14, Serving: 30},
+}
+
+// unitGrams are the default gram weights of household and metric units
+var unitGrams = map[string]float64{
+	"g":       1,
+	"kg":      1000,
+	"oz":      28.35,
+	"lb":      453.6,
+	"ml":      1,
+	"l":       1000,
+	"cup":     240,
+	"tbsp":    15,
+	"tsp":     5,
+	"slice":   30,
+	"piece":   0, // uses the food's serving weight
+	"serving": 0,
+	"bowl":    300,
+	"glass":   250,
+	"mug":     300,
+	"can":     330,
+	"scoop":   30,
+	"handful": 30,
+}
+
+// unitAliases maps spellings to the canonical unit names in unitGrams
+var unitAliases = map[string]string{
+	"gram": "g", "grams": "g", "gr": "g",
+	"kilogram": "kg", "kilograms": "kg", "kgs": "kg",
+	"ounce": "oz", "ounces": "oz",
+	"pound": "lb", "pounds": "lb", "lbs": "lb",
+	"milliliter": "ml", "milliliters": "ml", "millilitre": "ml", "millilitres": "ml",
+	"liter": "l", "liters": "l", "litre": "l", "litres": "l",
+	"cups":       "cup",
+	"tablespoon": "tbsp", "tablespoons": "tbsp", "tbsps": "tbsp",
+	"teaspoon": "tsp", "teaspoons": "tsp", "tsps": "tsp",
+	"slices": "slice",
+	"pieces": "piece", "pcs": "piece", "pc": "piece",
+	"servings": "serving", "portion": "serving", "portions": "serving",
+	"bowls": "bowl", "glasses": "glass", "mugs": "mug", "cans": "can",
+	"scoops": "scoop", "handfuls": "handful",
+}
+
+// wordNumbers are quantity words understood in place of digits
+var wordNumbers = map[string]float64{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5,
+	"six": 6, "seven": 7, "eight": 8, "nine": 9, "ten": 10, "half": 0.5,
+	"couple": 2, "few": 3,
+}
+
+var (
+	segmentSplitter = regexp.MustCompile(`(?i)\s*(?:,|;|\+|\n|\band\b|\bwith\b|\bplus\b)\s*`)
+	quantityPattern = regexp.MustCompile(`^(\d+(?:\.\d+)?(?:/\d+)?)\s*([a-z]+)?\b`)
+	fillerWords     = regexp.MustCompile(`\b(of|some|the|had|ate|i|for|my)\b`)
+)
+
+// LocalParser is a deterministic dictionary-based parser that works offline
+type LocalParser struct {
+	foods []food
+}
+
+// NewLocalParser creates a parser backed by the built-in food dictionary
+func NewLocalParser() *LocalParser {
+	return &LocalParser{foods: foods}
+}
+
+// Parse splits the query into segments and matches each against the dictionary
+func (p *LocalParser) Parse(ctx context.Context, query string) (*Result, error) {
+	result := &Result{Items: []Item{}, Parser: ParserLocal}
+	segments := segmentSplitter.Split(strings.ToLower(query), -1)
+
+	recognised := 0
+	total := 0
+	for _, segment := range segments {
+		segment = strings.TrimSpace(segment)
+		if segment == "" {
+			continue
+		}
+		total++
+		if item, ok := p.parseSegment(segment); ok {
+			result.Items = append(result.Items, item)
+			recognised++
+		}
+	}
+
+	if recognised == 0 {
+		return nil, ErrNothingRecognised
+	}
+
+	summarize(result)
+	// Penalise the overall confidence for parts of the query we skipped
+	result.Confidence = round2(result.Confidence * float64(recognised) / float64(total))
+	return result, nil
+}
+
+// parseSegment reads "<quantity> <unit> <food>" from a single segment
+func (p *LocalParser) parseSegment(segment string) (Item, bool) {
+	quantity, unit, rest, explicitQuantity := splitQuantity(segment)
+
+	entry, ok := p.lookup(rest)
+	if !ok {
+		return Item{}, false
+	}
+
+	confidence := 0.9
+	if !explicitQuantity {
+		confidence = 0.7
+	}
+
+	grams := entry.Serving * quantity
+	switch {
+	case unit == "" || unit == "piece" || unit == "serving":
+		if explicitQuantity {
+			confidence -= 0.1
+		}
+		if unit == "" {
+			unit = "serving"
+			if explicitQuantity {
+				unit = "piece"
+			}
+		}
+	case entry.Units[unit] > 0:
+		grams = entry.Units[unit] * quantity
+	default:
+		grams = unitGrams[unit] * quantity
+		if unit != "g" && unit != "kg" && unit != "oz" && unit != "lb" && unit != "ml" && unit != "l" {
+			confidence -= 0.15
+		}
+	}
+
+	factor := grams / 100
+	return Item{
+		Name:       entry.Name,
+		Quantity:   quantity,
+		Unit:       unit,
+		Grams:      round1(grams),
+		Calories:   int(entry.Kcal*factor + 0.5),
+		Protein:    round1(entry.Protein * factor),
+		Fat:        round1(entry.Fat * factor),
+		Carbs:      round1(entry.Carbs * factor),
+		Confidence: round2(confidence),
+	}, true
+}
+
+// lookup finds the dictionary entry whose longest name or alias appears in text
+func (p *LocalParser) lookup(text string) (food, bool) {
+	text = " " + strings.Join(strings.Fields(fillerWords.ReplaceAllString(text, " ")), " ") + " "
+
+	type candidate struct {
+		food food
+		name string
+	}
+	var candidates []candidate
+	for _, f := range p.foods {
+		for _, name := range append([]string{f.Name}, f.Aliases...) {
+			if strings.Contains(text, " "+name+" ") {
+				candidates = append(candidates, candidate{food: f, name: name})
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return food{}, false
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return len(candidates[i].name) > len(candidates[j].name)
+	})
+	return candidates[0].food, true
+}
+
+// splitQuantity extracts a leading quantity and unit from a segment
+func splitQuantity(segment string) (quantity float64, unit, rest string, explicit bool) {
+	quantity, rest = 1, segment
+
+	fields := strings.Fields(segment)
+	if len(fields) > 0 {
+		if n, ok := wordNumbers[fields[0]]; ok {
+			quantity, explicit = n, true
+			rest = strings.Join(fields[1:], " ")
+			if len(fields) > 1 && fields[0] == "half" && (fields[1] == "a" || fields[1] == "an") {
+				rest = strings.Join(fields[2:], " ")
+			}
+		}
+	}
+
+	if m := quantityPattern.FindStringSubmatch(rest); m != nil {
+		quantity, explicit = parseNumber(m[1]), true
+		rest = strings.TrimSpace(rest[len(m[0]):])
+		if m[2] != "" {
+			if u := canonicalUnit(m[2]); u != "" {
+				unit = u
+			} else {
+				// Not a unit, it is the start of the food name
+				rest = strings.TrimSpace(m[2] + " " + rest)
+			}
+		}
+	} else if fields := strings.Fields(rest); len(fields) > 0 {
+		if u := canonicalUnit(fields[0]); u != "" {
+			unit = u
+			rest = strings.Join(fields[1:], " ")
+		}
+	}
+
+	return quantity, unit, rest, explicit
+}
+
+// canonicalUnit normalises a unit spelling, returning "" if it is not a unit
+func canonicalUnit(word string) string {
+	if alias, ok := unitAliases[word]; ok {
+		return alias
+	}
+	if _, ok := unitGrams[word]; ok {
+		return word
+	}
+	return ""
+}
+
+// parseNumber parses decimals and simple fractions such as "1/2"
+func parseNumber(s string) float64 {
+	if num, den, ok := strings.Cut(s, "/"); ok {
+		n, _ := strconv.ParseFloat(num, 64)
+		d, _ := strconv.ParseFloat(den, 64)
+		if d == 0 {
+			return 1
+		}
+		return n / d
+	}
+	n, _ := strconv.ParseFloat(s, 64)
+	return n
+}
@@ -0,0 +1,88 @@
+package nutrition
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestLocalParserItems(t *testing.T) {
+	tests := []struct {
+		query      string
+		name       string
+		quantity   float64
+		unit       string
+		grams      float64
+		calories   int
+		confidence float64
+	}{
+		{"2 eggs", "egg", 2, "piece", 100, 143, 0.8},
+		{"200g chicken breast", "chicken breast", 200, "g", 200, 330, 0.9},
+		{"1.5 lbs beef", "beef", 1.5, "lb", 680.4, 1701, 0.9},
+		{"1/2 cup oats", "oats", 0.5, "cup", 40, 156, 0.9},
+		{"two slices of bread", "bread", 2, "slice", 60, 159, 0.9},
+		{"half an avocado", "avocado", 0.5, "piece", 75, 120, 0.8},
+		{"3 tablespoons peanut butter", "peanut butter", 3, "tbsp", 45, 265, 0.75},
+		{"a bowl of porridge", "oats", 1, "bowl", 300, 1167, 0.75},
+		{"banana", "banana", 1, "serving", 118, 105, 0.7},
+	}
+	p := NewLocalParser()
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			result, err := p.Parse(context.Background(), tt.query)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if len(result.Items) != 1 {
+				t.Fatalf("items = %+v, want one", result.Items)
+			}
+			item := result.Items[0]
+			if item.Name != tt.name || item.Quantity != tt.quantity || item.Unit != tt.unit {
+				t.Errorf("item = %v %s of %s, want %v %s of %s", item.Quantity, item.Unit, item.Name, tt.quantity, tt.unit, tt.name)
+			}
+			if item.Grams != tt.grams || item.Calories != tt.calories {
+				t.Errorf("item = %v g and %d kcal, want %v g and %d kcal", item.Grams, item.Calories, tt.grams, tt.calories)
+			}
+			if item.Confidence != tt.confidence {
+				t.Errorf("confidence = %v, want %v", item.Confidence, tt.confidence)
+			}
+		})
+	}
+}
+
+func TestLocalParserResult(t *testing.T) {
+	tests := []struct {
+		query      string
+		items      int
+		calories   int
+		confidence float64
+	}{
+		// Weighted by calories: (0.8 × 143 + 0.9 × 330) / 473
+		{"2 eggs and 200g chicken breast", 2, 473, 0.87},
+		// Half of the segments were not recognised
+		{"2 eggs, a unicorn", 1, 143, 0.4},
+	}
+	p := NewLocalParser()
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			result, err := p.Parse(context.Background(), tt.query)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if len(result.Items) != tt.items || result.Calories != tt.calories || result.Confidence != tt.confidence {
+				t.Errorf("result = %d items, %d kcal, confidence %v; want %d items, %d kcal, confidence %v",
+					len(result.Items), result.Calories, result.Confidence, tt.items, tt.calories, tt.confidence)
+			}
+			if result.Parser != ParserLocal {
+				t.Errorf("parser = %q, want %q", result.Parser, ParserLocal)
+			}
+		})
+	}
+}
+
+func TestLocalParserNothingRecognised(t *testing.T) {
+	_, err := NewLocalParser().Parse(context.Background(), "a unicorn")
+	if !errors.Is(err, ErrNothingRecognised) {
+		t.Errorf("error = %v, want ErrNothingRecognised", err)
+	}
+}
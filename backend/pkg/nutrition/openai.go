@@ -0,0 +1,130 @@
+package nutrition
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultOpenAIModel = "gpt-4o-mini"
+	openAIEndpoint     = "https://api.openai.com/v1/chat/completions"
+)
+
+// openAIPrompt instructs the model to answer with a fixed JSON shape
+const openAIPrompt = `You are a nutrition assistant. Break the user's meal description into individual foods.
+Respond with JSON only, in the form:
+{"items":[{"name":string,"quantity":number,"unit":string,"grams":number,"calories":integer,"protein":number,"fat":number,"carbs":number,"confidence":number}]}
+Protein, fat and carbs are grams. Confidence is between 0 and 1 and reflects how sure you are about portion size and identification.
+Use typical portion sizes when no amount is given and lower the confidence accordingly.`
+
+// OpenAIParser asks an OpenAI chat model to itemize a meal description
+type OpenAIParser struct {
+	APIKey     string
+	Model      string
+	Endpoint   string
+	HTTPClient *http.Client
+}
+
+// NewOpenAIParser creates an LLM-backed parser
+func NewOpenAIParser(apiKey, model string) (*OpenAIParser, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("OPENAI_API_KEY is required for the openai nutrition parser")
+	}
+	if model == "" {
+		model = defaultOpenAIModel
+	}
+	return &OpenAIParser{
+		APIKey:     apiKey,
+		Model:      model,
+		Endpoint:   openAIEndpoint,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Parse sends the query to the chat completions API and decodes the items
+func (p *OpenAIParser) Parse(ctx context.Context, query string) (*Result, error) {
+	payload := map[string]interface{}{
+		"model":           p.Model,
+		"temperature":     0,
+		"response_format": map[string]string{"type": "json_object"},
+		"messages": []map[string]string{
+			{"role": "system", "content": openAIPrompt},
+			{"role": "user", "content": query},
+		},
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.Endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("openai error: %s", string(body))
+	}
+
+	var completion struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &completion); err != nil {
+		return nil, fmt.Errorf("openai error: unexpected response: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return nil, fmt.Errorf("openai error: empty response")
+	}
+
+	result := &Result{Parser: ParserOpenAI}
+	if err := json.Unmarshal([]byte(completion.Choices[0].Message.Content), result); err != nil {
+		return nil, fmt.Errorf("openai error: invalid JSON from model: %w", err)
+	}
+	if len(result.Items) == 0 {
+		return nil, ErrNothingRecognised
+	}
+
+	for i := range result.Items {
+		item := &result.Items[i]
+		item.Confidence = clamp(item.Confidence, 0, 1)
+		if item.Calories < 0 {
+			item.Calories = 0
+		}
+	}
+	summarize(result)
+
+	return result, nil
+}
+
+func clamp(v, lo, hi float64) float64 {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,96 @@
+package nutrition
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+)
+
+// Item is a single food recognised in a free-text description
+type Item struct {
+	Name       string  `json:"name"`
+	Quantity   float64 `json:"quantity"`
+	Unit       string  `json:"unit"`
+	Grams      float64 `json:"grams"`
+	Calories   int     `json:"calories"`
+	Protein    float64 `json:"protein"`
+	Fat        float64 `json:"fat"`
+	Carbs      float64 `json:"carbs"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Result is the outcome of parsing a food description
+type Result struct {
+	Items      []Item  `json:"items"`
+	Calories   int     `json:"calories"`
+	Protein    float64 `json:"protein"`
+	Fat        float64 `json:"fat"`
+	Carbs      float64 `json:"carbs"`
+	Confidence float64 `json:"confidence"`
+	Parser     string  `json:"parser"`
+}
+
+// Parser turns a free-text meal description into itemized nutrition values
+type Parser interface {
+	Parse(ctx context.Context, query string) (*Result, error)
+}
+
+// Parser names accepted by NewParser
+const (
+	ParserLocal  = "local"
+	ParserOpenAI = "openai"
+)
+
+// ErrNothingRecognised is returned when no food could be identified
+var ErrNothingRecognised = fmt.Errorf("no foods recognised in query")
+
+// NewParserFromEnv picks a parser from NUTRITION_PARSER, falling back to the
+// OpenAI adapter when OPENAI_API_KEY is set and the local parser otherwise
+func NewParserFromEnv() (Parser, error) {
+	name := os.Getenv("NUTRITION_PARSER")
+	if name == "" {
+		name = ParserLocal
+		if os.Getenv("OPENAI_API_KEY") != "" {
+			name = ParserOpenAI
+		}
+	}
+	return NewParser(name)
+}
+
+// NewParser creates the parser with the given name
+func NewParser(name string) (Parser, error) {
+	switch name {
+	case ParserLocal:
+		return NewLocalParser(), nil
+	case ParserOpenAI:
+		return NewOpenAIParser(os.Getenv("OPENAI_API_KEY"), os.Getenv("OPENAI_MODEL"))
+	default:
+		return nil, fmt.Errorf("unknown nutrition parser %q", name)
+	}
+}
+
+// summarize fills the totals and the calorie-weighted confidence of a result
+func summarize(result *Result) {
+	var weighted, weight float64
+	result.Calories, result.Protein, result.Fat, result.Carbs = 0, 0, 0, 0
+	for _, item := range result.Items {
+		result.Calories += item.Calories
+		result.Protein += item.Protein
+		result.Fat += item.Fat
+		result.Carbs += item.Carbs
+
+		w := math.Max(float64(item.Calories), 1)
+		weighted += item.Confidence * w
+		weight += w
+	}
+	result.Protein = round1(result.Protein)
+	result.Fat = round1(result.Fat)
+	result.Carbs = round1(result.Carbs)
+	if weight > 0 {
+		result.Confidence = round2(weighted / weight)
+	}
+}
+
+func round1(v float64) float64 { return math.Round(v*10) / 10 }
+func round2(v float64) float64 { return math.Round(v*100) / 100 }
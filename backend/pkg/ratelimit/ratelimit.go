@@ -0,0 +1,71 @@
+// Package ratelimit limits how often a key, such as an IP or email address,
+// may do something
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter allows each key at most limit events per window. A key's window
+// starts with its first event and its count resets when the window ends.
+// Counts are kept in process memory and are not shared between instances.
+type Limiter struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	windows   map[string]keyWindow
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type keyWindow struct {
+	Start time.Time
+	Count int
+}
+
+// New creates a limiter allowing limit events per key within window
+func New(limit int, window time.Duration) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		window:  window,
+		windows: make(map[string]keyWindow),
+		now:     time.Now,
+	}
+}
+
+// Allow records an event for key and reports whether it is within the limit.
+// Events over the limit are not counted.
+func (l *Limiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweepLocked(now)
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.Start) >= l.window {
+		l.windows[key] = keyWindow{Start: now, Count: 1}
+		return true
+	}
+	if w.Count >= l.limit {
+		return false
+	}
+	w.Count++
+	l.windows[key] = w
+	return true
+}
+
+// sweepLocked forgets the windows that have ended, at most once per window,
+// so only keys seen in the last two windows are kept
+func (l *Limiter) sweepLocked(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	for key, w := range l.windows {
+		if now.Sub(w.Start) >= l.window {
+			delete(l.windows, key)
+		}
+	}
+	l.lastSweep = now
+}
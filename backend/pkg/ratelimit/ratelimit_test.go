@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	l := New(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		key     string
+		want    bool
+	}{
+		{"first event", 0, "a", true},
+		{"second event", 10 * time.Second, "a", true},
+		{"over the limit", 10 * time.Second, "a", false},
+		{"other key", 0, "b", true},
+		{"still in the window", 39 * time.Second, "a", false},
+		{"next window", time.Second, "a", true},
+	}
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		if got := l.Allow(step.key); got != step.want {
+			t.Errorf("%s: Allow(%q) = %v, want %v", step.name, step.key, got, step.want)
+		}
+	}
+}
+
+func TestLimiterForgetsEndedWindows(t *testing.T) {
+	now := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	l := New(1, time.Minute)
+	l.now = func() time.Time { return now }
+
+	l.Allow("a")
+	l.Allow("b")
+	now = now.Add(2 * time.Minute)
+	l.Allow("c")
+
+	if len(l.windows) != 1 {
+		t.Errorf("limiter keeps %d windows, want 1", len(l.windows))
+	}
+}
@@ -0,0 +1,61 @@
+package revocation
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/hadiabbas/fittrack-backend/pkg/database"
+)
+
+// DatabaseStore persists revocations in the revoked_tokens and token_cutoffs
+// tables, so they survive restarts and apply to every server instance
+type DatabaseStore struct {
+	DB database.Store
+}
+
+// NewDatabaseStore creates a revocation store backed by db
+func NewDatabaseStore(db database.Store) *DatabaseStore {
+	return &DatabaseStore{DB: db}
+}
+
+// Revoke rejects the token with the given jti until it expires
+func (s *DatabaseStore) Revoke(jti, userID string, expiresAt time.Time) error {
+	data := map[string]interface{}{
+		"jti":        jti,
+		"user_id":    userID,
+		"expires_at": expiresAt.UTC(),
+	}
+	_, err := s.DB.Upsert("revoked_tokens", data, "jti", true)
+	return err
+}
+
+// RevokeUser rejects every token of userID issued before cutoff. A cutoff
+// earlier than the stored one leaves it unchanged.
+func (s *DatabaseStore) RevokeUser(userID string, cutoff, expiresAt time.Time) error {
+	params := map[string]interface{}{
+		"p_user_id":        userID,
+		"p_revoked_before": cutoff.UTC(),
+		"p_expires_at":     expiresAt.UTC(),
+	}
+	_, err := s.DB.RPC("revoke_user_tokens", params, true)
+	return err
+}
+
+// IsRevoked reports whether a token must be rejected
+func (s *DatabaseStore) IsRevoked(jti, userID string, issuedAt time.Time) (bool, error) {
+	params := map[string]interface{}{
+		"p_jti":       jti,
+		"p_user_id":   userID,
+		"p_issued_at": issuedAt.UTC(),
+	}
+	result, err := s.DB.RPC("is_token_revoked", params, true)
+	if err != nil {
+		return false, err
+	}
+
+	var revoked bool
+	if err := json.Unmarshal(result, &revoked); err != nil {
+		return false, err
+	}
+	return revoked, nil
+}
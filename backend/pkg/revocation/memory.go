@@ -0,0 +1,82 @@
+package revocation
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps revocations in process memory. Revocations are lost on
+// restart and are not shared between instances; use DatabaseStore when
+// running more than one server.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	tokens  map[string]time.Time // jti -> token expiry
+	cutoffs map[string]cutoff    // user id -> cutoff
+	now     func() time.Time
+}
+
+type cutoff struct {
+	Before    time.Time
+	ExpiresAt time.Time
+}
+
+// NewMemoryStore creates an empty in-memory revocation store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		tokens:  make(map[string]time.Time),
+		cutoffs: make(map[string]cutoff),
+		now:     time.Now,
+	}
+}
+
+// Revoke rejects the token with the given jti until it expires
+func (s *MemoryStore) Revoke(jti, userID string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneLocked()
+	s.tokens[jti] = expiresAt
+	return nil
+}
+
+// RevokeUser rejects every token of userID issued before cutoff
+func (s *MemoryStore) RevokeUser(userID string, before, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneLocked()
+	if existing, ok := s.cutoffs[userID]; ok && existing.Before.After(before) {
+		return nil
+	}
+	s.cutoffs[userID] = cutoff{Before: before, ExpiresAt: expiresAt}
+	return nil
+}
+
+// IsRevoked reports whether a token must be rejected
+func (s *MemoryStore) IsRevoked(jti, userID string, issuedAt time.Time) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.tokens[jti]; ok {
+		return true, nil
+	}
+	if c, ok := s.cutoffs[userID]; ok && issuedAt.Before(c.Before) {
+		return true, nil
+	}
+	return false, nil
+}
+
+// pruneLocked drops entries for tokens that have expired anyway
+func (s *MemoryStore) pruneLocked() {
+	now := s.now()
+	for jti, expiresAt := range s.tokens {
+		if expiresAt.Before(now) {
+			delete(s.tokens, jti)
+		}
+	}
+	for userID, c := range s.cutoffs {
+		if c.ExpiresAt.Before(now) {
+			delete(s.cutoffs, userID)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// Package revocation keeps track of access tokens that must be rejected
+// before they expire, either individually by their jti claim or all tokens
+// of a user issued before a cutoff ("sign out all devices").
+package revocation
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hadiabbas/fittrack-backend/pkg/database"
+)
+
+// Store records revoked access tokens
+type Store interface {
+	// Revoke rejects the token with the given jti until it expires
+	Revoke(jti, userID string, expiresAt time.Time) error
+	// RevokeUser rejects every token of userID issued before cutoff. The iat
+	// claim has whole-second precision, so pass a cutoff truncated to the
+	// second or tokens issued later in the same second are rejected too.
+	// Tokens issued before the cutoff expire by expiresAt.
+	RevokeUser(userID string, cutoff, expiresAt time.Time) error
+	// IsRevoked reports whether a token must be rejected
+	IsRevoked(jti, userID string, issuedAt time.Time) (bool, error)
+}
+
+// Backend names accepted by NewStore
+const (
+	BackendMemory   = "memory"
+	BackendDatabase = "database"
+)
+
+// NewStoreFromEnv picks the backend from TOKEN_REVOCATION_BACKEND, defaulting
+// to memory
+func NewStoreFromEnv(db database.Store) (Store, error) {
+	name := os.Getenv("TOKEN_REVOCATION_BACKEND")
+	if name == "" {
+		name = BackendMemory
+	}
+	return NewStore(name, db)
+}
+
+// NewStore creates the named revocation backend
+func NewStore(name string, db database.Store) (Store, error) {
+	switch name {
+	case BackendMemory:
+		return NewMemoryStore(), nil
+	case BackendDatabase:
+		return NewDatabaseStore(db), nil
+	default:
+		return nil, fmt.Errorf("unknown token revocation backend %q", name)
+	}
+}
+
+// Compile-time checks that both backends satisfy Store
+var (
+	_ Store = (*MemoryStore)(nil)
+	_ Store = (*DatabaseStore)(nil)
+)
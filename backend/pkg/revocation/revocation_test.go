@@ -0,0 +1,119 @@
+package revocation
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hadiabbas/fittrack-backend/pkg/database"
+)
+
+func TestMemoryStoreCutoff(t *testing.T) {
+	cutoff := time.Now().Truncate(time.Second)
+
+	tests := []struct {
+		name     string
+		userID   string
+		issuedAt time.Time
+		want     bool
+	}{
+		{"issued a second earlier", "user-1", cutoff.Add(-time.Second), true},
+		{"issued in the cutoff second", "user-1", cutoff, false},
+		{"issued after the cutoff", "user-1", cutoff.Add(time.Minute), false},
+		{"other user", "user-2", cutoff.Add(-time.Second), false},
+	}
+
+	s := NewMemoryStore()
+	if err := s.RevokeUser("user-1", cutoff, cutoff.Add(15*time.Minute)); err != nil {
+		t.Fatalf("RevokeUser: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.IsRevoked("jti", tt.userID, tt.issuedAt)
+			if err != nil {
+				t.Fatalf("IsRevoked: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsRevoked = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreKeepsLatestCutoff(t *testing.T) {
+	later := time.Now().Truncate(time.Second)
+	earlier := later.Add(-time.Minute)
+
+	s := NewMemoryStore()
+	s.RevokeUser("user-1", later, later.Add(15*time.Minute))
+	s.RevokeUser("user-1", earlier, earlier.Add(15*time.Minute))
+
+	if revoked, _ := s.IsRevoked("jti", "user-1", later.Add(-time.Second)); !revoked {
+		t.Error("an earlier cutoff replaced a later one")
+	}
+}
+
+func TestMemoryStoreRevokeByJTI(t *testing.T) {
+	now := time.Now()
+	s := NewMemoryStore()
+	s.Revoke("revoked", "user-1", now.Add(time.Minute))
+
+	if revoked, _ := s.IsRevoked("revoked", "user-1", now); !revoked {
+		t.Error("revoked jti was accepted")
+	}
+	if revoked, _ := s.IsRevoked("other", "user-1", now); revoked {
+		t.Error("unrelated jti was rejected")
+	}
+}
+
+func TestDatabaseStoreCutoff(t *testing.T) {
+	db := database.NewMemoryStore()
+	signup, err := db.AuthSignUp("lifter@example.com", "password123")
+	if err != nil {
+		t.Fatalf("AuthSignUp: %v", err)
+	}
+	var session struct {
+		User struct {
+			ID string `json:"id"`
+		} `json:"user"`
+	}
+	if err := json.Unmarshal(signup, &session); err != nil {
+		t.Fatalf("decoding session: %v", err)
+	}
+	userID := session.User.ID
+
+	cutoff := time.Now().Truncate(time.Second)
+	s := NewDatabaseStore(db)
+	if err := s.RevokeUser(userID, cutoff, cutoff.Add(15*time.Minute)); err != nil {
+		t.Fatalf("RevokeUser: %v", err)
+	}
+	for issuedAt, want := range map[time.Time]bool{
+		cutoff.Add(-time.Second): true,
+		cutoff:                   false,
+	} {
+		got, err := s.IsRevoked("jti", userID, issuedAt)
+		if err != nil {
+			t.Fatalf("IsRevoked: %v", err)
+		}
+		if got != want {
+			t.Errorf("IsRevoked(iat %s) = %v, want %v", issuedAt.Format(time.RFC3339), got, want)
+		}
+	}
+}
+
+func TestDatabaseStoreKeepsLatestCutoff(t *testing.T) {
+	later := time.Now().Truncate(time.Second)
+	earlier := later.Add(-time.Minute)
+
+	s := NewDatabaseStore(database.NewMemoryStore())
+	if err := s.RevokeUser("user-1", later, later.Add(15*time.Minute)); err != nil {
+		t.Fatalf("RevokeUser: %v", err)
+	}
+	if err := s.RevokeUser("user-1", earlier, earlier.Add(15*time.Minute)); err != nil {
+		t.Fatalf("RevokeUser: %v", err)
+	}
+
+	if revoked, _ := s.IsRevoked("jti", "user-1", later.Add(-time.Second)); !revoked {
+		t.Error("an earlier cutoff replaced a later one")
+	}
+}
@@ -1,23 +1,37 @@
 package utils
 
 import (
-	"os"
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-// GenerateJWT creates a new JWT token for a user
-func GenerateJWT(userID, email string) (string, error) {
+// GenerateJWT creates a new JWT token for a user, signed with the current
+// signing key and naming it in the kid header. The sid claim names the
+// session (refresh token family) whose Supabase credentials the server looks
+// up for the user's database calls, and the random jti claim identifies the
+// token for revocation. Tokens are readable by anyone holding them, so no
+// credentials are put in the claims.
+func (m *KeyManager) GenerateJWT(userID, email, sessionID string, expiresIn time.Duration) (string, error) {
+	key := m.currentSigner()
+	if key == nil {
+		return "", errors.New("no JWT signing key available")
+	}
+
 	claims := jwt.MapClaims{
 		"user_id": userID,
 		"email":   email,
-		"exp":     time.Now().Add(time.Hour * 24 * 30).Unix(), // 30 days
+		"sid":     sessionID,
+		"jti":     uuid.New().String(),
+		"exp":     time.Now().Add(expiresIn).Unix(),
 		"iat":     time.Now().Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+	tokenString, err := token.SignedString(key.Private)
 	if err != nil {
 		return "", err
 	}
@@ -25,3 +39,29 @@ func GenerateJWT(userID, email string) (string, error) {
 	return tokenString, nil
 }
 
+// ParseJWT validates a token issued by GenerateJWT and returns its claims.
+// The token must name a trusted key in its kid header and be signed with
+// that key's algorithm.
+func (m *KeyManager) ParseJWT(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := m.verificationKey(kid)
+		if !ok {
+			return nil, jwt.ErrTokenUnverifiable
+		}
+		// Validate signing method
+		if token.Method.Alg() != key.Algorithm {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return key.Public, nil
+	}, jwt.WithValidMethods([]string{AlgorithmEdDSA, AlgorithmRS256}))
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	return claims, nil
+}
@@ -0,0 +1,458 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Signing algorithms accepted by JWT_KEY_ALGORITHM
+const (
+	AlgorithmEdDSA = "EdDSA"
+	AlgorithmRS256 = "RS256"
+)
+
+// DefaultKeyRotation is how often a new signing key is generated unless
+// JWT_KEY_ROTATION says otherwise
+const DefaultKeyRotation = 30 * 24 * time.Hour
+
+// keyIDLayout names generated keys after their creation time, followed by a
+// random suffix so instances sharing a directory never pick the same name.
+// Key files named this way keep their age even if copying them resets their
+// modification time.
+const keyIDLayout = "20060102T150405Z"
+
+// keyCheckInterval is how often the key directory is re-read, so keys added
+// by other instances or by hand are picked up
+const keyCheckInterval = time.Minute
+
+// JWKSMaxAge is how long clients may cache the published key set
+const JWKSMaxAge = 5 * time.Minute
+
+// KeyActivationDelay is how long a new key is published before it signs
+// tokens: long enough for every instance to re-read the key directory and for
+// every cached copy of the key set to expire, so verifiers know the key by
+// the time they see a token signed with it
+const KeyActivationDelay = JWKSMaxAge + keyCheckInterval
+
+// signingKey is one key of the key set. Verification-only keys have no
+// private half.
+type signingKey struct {
+	ID        string
+	Algorithm string
+	Private   crypto.Signer
+	Public    crypto.PublicKey
+	CreatedAt time.Time
+}
+
+// KeyManager signs and verifies access tokens with asymmetric keys. Keys are
+// PEM files in a directory, named <kid>.pem: the newest private key signs new
+// tokens once it is KeyActivationDelay old, while older keys keep verifying
+// the tokens they signed until those have expired, so rotating keys never
+// logs anyone out. Generated keys are then deleted. PUBLIC KEY files are
+// trusted for verification for as long as they exist.
+type KeyManager struct {
+	mu        sync.RWMutex
+	dir       string // empty keeps generated keys in memory only
+	algorithm string
+	rotation  time.Duration
+	generated []*signingKey // keys generated without a directory
+	keys      map[string]*signingKey
+	signer    *signingKey
+	newest    *signingKey // newest private key, which may not sign yet
+	now       func() time.Time
+}
+
+// NewKeyManagerFromEnv loads keys from JWT_KEYS_DIR, generating keys of type
+// JWT_KEY_ALGORITHM (EdDSA by default) every JWT_KEY_ROTATION (30 days by
+// default, 0 disables rotation). Without JWT_KEYS_DIR keys only live in
+// memory and tokens do not survive a restart.
+func NewKeyManagerFromEnv() (*KeyManager, error) {
+	algorithm := os.Getenv("JWT_KEY_ALGORITHM")
+	if algorithm == "" {
+		algorithm = AlgorithmEdDSA
+	}
+
+	rotation := DefaultKeyRotation
+	if s := os.Getenv("JWT_KEY_ROTATION"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid JWT_KEY_ROTATION %q", s)
+		}
+		rotation = d
+	}
+
+	return NewKeyManager(os.Getenv("JWT_KEYS_DIR"), algorithm, rotation)
+}
+
+// NewKeyManager loads the keys in dir, generating a signing key if there is none
+func NewKeyManager(dir, algorithm string, rotation time.Duration) (*KeyManager, error) {
+	if algorithm != AlgorithmEdDSA && algorithm != AlgorithmRS256 {
+		return nil, fmt.Errorf("unsupported JWT key algorithm %q, expected %s or %s", algorithm, AlgorithmEdDSA, AlgorithmRS256)
+	}
+
+	m := &KeyManager{
+		dir:       dir,
+		algorithm: algorithm,
+		rotation:  rotation,
+		keys:      make(map[string]*signingKey),
+		now:       time.Now,
+	}
+	if err := m.Reload(); err != nil {
+		return nil, err
+	}
+	if m.signer == nil {
+		if err := m.Rotate(); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Reload re-reads the key directory and deletes the generated keys that
+// were retired
+func (m *KeyManager) Reload() error {
+	if m.dir == "" {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.activateLocked(m.generated)
+		kept := m.generated[:0]
+		for _, key := range m.generated {
+			if m.keys[key.ID] != nil {
+				kept = append(kept, key)
+			}
+		}
+		m.generated = kept
+		return nil
+	}
+
+	keys, err := loadKeys(m.dir)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.activateLocked(keys)
+	var retired []*signingKey
+	for _, key := range keys {
+		if _, generated := generatedKeyTime(key.ID); generated && m.keys[key.ID] == nil {
+			retired = append(retired, key)
+		}
+	}
+	m.mu.Unlock()
+
+	// Another instance sharing the directory may have deleted it already
+	for _, key := range retired {
+		if err := os.Remove(filepath.Join(m.dir, key.ID+".pem")); err != nil && !os.IsNotExist(err) {
+			log.Printf("Warning: deleting retired JWT key %s: %v", key.ID, err)
+		}
+	}
+	return nil
+}
+
+// Rotate generates a new signing key. It is published right away but only
+// signs tokens after KeyActivationDelay, unless there is no other key to sign
+// with. Tokens signed by the previous key stay valid until they expire.
+func (m *KeyManager) Rotate() error {
+	key, err := generateKey(m.algorithm, m.now())
+	if err != nil {
+		return err
+	}
+
+	if m.dir == "" {
+		m.mu.Lock()
+		m.generated = append(m.generated, key)
+		m.mu.Unlock()
+		return m.Reload()
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key.Private)
+	if err != nil {
+		return err
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	// Never replace a key file, in case another instance picked the same kid
+	path := filepath.Join(m.dir, key.ID+".pem")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("writing JWT key: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("writing JWT key: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing JWT key: %w", err)
+	}
+	return m.Reload()
+}
+
+// StartRotation re-reads the key directory every minute and generates a new
+// signing key once the newest one is older than the rotation interval. It
+// returns a function that stops the background goroutine.
+func (m *KeyManager) StartRotation() (stop func()) {
+	done := make(chan struct{})
+	ticker := time.NewTicker(keyCheckInterval)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := m.Reload(); err != nil {
+					log.Printf("Warning: reloading JWT keys: %v", err)
+					continue
+				}
+				if m.rotationDue() {
+					if err := m.Rotate(); err != nil {
+						log.Printf("Warning: rotating JWT signing key: %v", err)
+					}
+				}
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+func (m *KeyManager) rotationDue() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.rotation > 0 && (m.newest == nil || m.now().Sub(m.newest.CreatedAt) >= m.rotation)
+}
+
+// activateLocked picks the newest private key that is old enough as the
+// signer, keeps newer keys published until they take over and drops private
+// keys whose tokens have all expired, and have dropped out of every cached
+// key set, since they were replaced
+func (m *KeyManager) activateLocked(keys []*signingKey) {
+	var private []*signingKey
+	m.keys = make(map[string]*signingKey, len(keys))
+	for _, key := range keys {
+		if key.Private == nil {
+			m.keys[key.ID] = key
+			continue
+		}
+		private = append(private, key)
+	}
+	sort.SliceStable(private, func(i, j int) bool {
+		return private[i].CreatedAt.Before(private[j].CreatedAt)
+	})
+
+	m.signer, m.newest = nil, nil
+	if len(private) == 0 {
+		return
+	}
+	m.newest = private[len(private)-1]
+
+	// Walk newest first. Keys too new to sign are only published. A key was
+	// retired when the next newer key started signing, and is dropped once
+	// the tokens it signed have expired.
+	now := m.now()
+	var replacedAt time.Time
+	for i := len(private) - 1; i >= 0; i-- {
+		key := private[i]
+		activatesAt := key.CreatedAt.Add(KeyActivationDelay)
+		switch {
+		case m.signer == nil && i > 0 && now.Before(activatesAt):
+			// Pending: an older key keeps signing until this one activates
+		case m.signer == nil:
+			m.signer = key
+		case now.After(replacedAt.Add(AccessTokenTTL() + JWKSMaxAge)):
+			replacedAt = activatesAt
+			continue
+		}
+		m.keys[key.ID] = key
+		if m.signer != nil {
+			replacedAt = activatesAt
+		}
+	}
+}
+
+// verificationKey returns the key with the given kid if it is still trusted
+func (m *KeyManager) verificationKey(kid string) (*signingKey, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	key, ok := m.keys[kid]
+	return key, ok
+}
+
+// currentSigner returns the key that signs new tokens
+func (m *KeyManager) currentSigner() *signingKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.signer
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519 keys
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public halves of every key that can verify tokens
+func (m *KeyManager) JWKS() JWKS {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ids := make([]string, 0, len(m.keys))
+	for id := range m.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	set := JWKS{Keys: make([]JWK, 0, len(ids))}
+	for _, id := range ids {
+		key := m.keys[id]
+		jwk := JWK{KeyID: key.ID, Use: "sig", Algorithm: key.Algorithm}
+		switch pub := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// generateKey creates a new private key named after its creation time
+func generateKey(algorithm string, now time.Time) (*signingKey, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	key := &signingKey{
+		ID:        fmt.Sprintf("%s-%x", now.UTC().Format(keyIDLayout), suffix),
+		Algorithm: algorithm,
+		CreatedAt: now,
+	}
+
+	switch algorithm {
+	case AlgorithmRS256:
+		private, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		key.Private, key.Public = private, &private.PublicKey
+	default:
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		key.Private, key.Public = private, public
+	}
+	return key, nil
+}
+
+// loadKeys reads every *.pem file in dir
+func loadKeys(dir string) ([]*signingKey, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*signingKey, 0, len(paths))
+	for _, path := range paths {
+		key, err := loadKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("loading JWT key %s: %w", filepath.Base(path), err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// loadKey parses an RSA or Ed25519 private key (PKCS#1 or PKCS#8) or public
+// key (PKIX) from a PEM file
+func loadKey(path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{
+		ID:        strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		CreatedAt: info.ModTime(),
+	}
+	if created, ok := generatedKeyTime(key.ID); ok {
+		key.CreatedAt = created
+	}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Algorithm, key.Private, key.Public = AlgorithmRS256, k, &k.PublicKey
+	case ed25519.PrivateKey:
+		key.Algorithm, key.Private, key.Public = AlgorithmEdDSA, k, k.Public()
+	case *rsa.PublicKey:
+		key.Algorithm, key.Public = AlgorithmRS256, k
+	case ed25519.PublicKey:
+		key.Algorithm, key.Public = AlgorithmEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, expected RSA or Ed25519", parsed)
+	}
+	return key, nil
+}
+
+// generatedKeyTime returns the creation time in the name of a generated key.
+// Keys generated before names had a random suffix are recognised too.
+func generatedKeyTime(id string) (time.Time, bool) {
+	stamp, _, _ := strings.Cut(id, "-")
+	created, err := time.Parse(keyIDLayout, stamp)
+	return created, err == nil
+}
@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeClock is a settable time source for KeyManager.now
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func newTestKeyManager(t *testing.T, clock *fakeClock) *KeyManager {
+	t.Helper()
+	m := &KeyManager{algorithm: AlgorithmEdDSA, rotation: time.Hour, keys: make(map[string]*signingKey), now: clock.now}
+	if err := m.Rotate(); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	return m
+}
+
+func publishedKeys(m *KeyManager) map[string]bool {
+	ids := make(map[string]bool)
+	for _, key := range m.JWKS().Keys {
+		ids[key.KeyID] = true
+	}
+	return ids
+}
+
+func TestKeyRotationPublishesBeforeSigning(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	m := newTestKeyManager(t, clock)
+	first := m.currentSigner()
+	if first == nil {
+		t.Fatal("the only key does not sign")
+	}
+
+	clock.t = clock.t.Add(time.Hour)
+	if !m.rotationDue() {
+		t.Fatal("rotation is not due after the rotation interval")
+	}
+	if err := m.Rotate(); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	second := m.newest
+	if m.currentSigner() != first {
+		t.Error("the new key signs before verifiers can have fetched it")
+	}
+	if !publishedKeys(m)[second.ID] {
+		t.Error("the new key is not published")
+	}
+	if m.rotationDue() {
+		t.Error("rotation is due again while the new key is pending")
+	}
+
+	// Tokens signed now must keep verifying after the switch
+	token, err := m.GenerateJWT("user-1", "lifter@example.com", "", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateJWT: %v", err)
+	}
+
+	clock.t = second.CreatedAt.Add(KeyActivationDelay - time.Second)
+	m.Reload()
+	if m.currentSigner() != first {
+		t.Error("the new key signs before KeyActivationDelay")
+	}
+
+	clock.t = second.CreatedAt.Add(KeyActivationDelay)
+	m.Reload()
+	if m.currentSigner() != second {
+		t.Error("the new key does not sign after KeyActivationDelay")
+	}
+	if !publishedKeys(m)[first.ID] {
+		t.Error("the retired key is no longer published")
+	}
+	if _, err := m.ParseJWT(token); err != nil {
+		t.Errorf("token signed by the retired key: %v", err)
+	}
+
+	clock.t = second.CreatedAt.Add(KeyActivationDelay + AccessTokenTTL() + JWKSMaxAge - time.Second)
+	m.Reload()
+	if !publishedKeys(m)[first.ID] {
+		t.Error("the retired key is dropped while cached key sets may still list it")
+	}
+
+	clock.t = second.CreatedAt.Add(KeyActivationDelay + AccessTokenTTL() + JWKSMaxAge + time.Second)
+	m.Reload()
+	if publishedKeys(m)[first.ID] {
+		t.Error("the retired key is still published after its tokens expired")
+	}
+	if len(m.generated) != 1 {
+		t.Errorf("%d generated keys are kept, want only the signer", len(m.generated))
+	}
+}
+
+func TestKeyRotationSuccessivePendingKeys(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	m := newTestKeyManager(t, clock)
+	first := m.currentSigner()
+
+	// Two keys generated within one activation delay: the older one
+	// takes over first
+	clock.t = clock.t.Add(time.Hour)
+	m.Rotate()
+	second := m.newest
+	clock.t = clock.t.Add(time.Minute)
+	m.Rotate()
+	third := m.newest
+
+	if m.currentSigner() != first {
+		t.Error("a pending key signs")
+	}
+	clock.t = second.CreatedAt.Add(KeyActivationDelay)
+	m.Reload()
+	if m.currentSigner() != second {
+		t.Error("the older pending key does not take over first")
+	}
+	clock.t = third.CreatedAt.Add(KeyActivationDelay)
+	m.Reload()
+	if m.currentSigner() != third {
+		t.Error("the newest key does not take over")
+	}
+	if ids := publishedKeys(m); !ids[first.ID] || !ids[second.ID] {
+		t.Error("retired keys are not published")
+	}
+}
+
+func TestKeyDirectory(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{t: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	newManager := func() *KeyManager {
+		m := &KeyManager{dir: dir, algorithm: AlgorithmEdDSA, rotation: time.Hour, keys: make(map[string]*signingKey), now: clock.now}
+		if err := m.Rotate(); err != nil {
+			t.Fatalf("Rotate: %v", err)
+		}
+		return m
+	}
+
+	// Two instances generating a key in the same second keep both
+	m := newManager()
+	newManager()
+	m.Reload()
+	if ids := publishedKeys(m); len(ids) != 2 {
+		t.Errorf("published keys = %v, want both instances' keys", ids)
+	}
+	if !m.newest.CreatedAt.Equal(clock.t) {
+		t.Errorf("key created at %s, want the time in its name", m.newest.CreatedAt)
+	}
+
+	// Once retired and expired, the older key's file is deleted
+	clock.t = clock.t.Add(time.Hour)
+	m.Rotate()
+	clock.t = m.newest.CreatedAt.Add(KeyActivationDelay + AccessTokenTTL() + JWKSMaxAge + time.Second)
+	m.Reload()
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if len(paths) != 1 {
+		t.Errorf("key files = %v, want only the signer's", paths)
+	}
+	if _, err := os.Stat(filepath.Join(dir, m.currentSigner().ID+".pem")); err != nil {
+		t.Errorf("signer's key file: %v", err)
+	}
+}
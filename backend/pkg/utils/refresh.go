@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"time"
+)
+
+// Default token lifetimes, overridable with ACCESS_TOKEN_TTL and
+// REFRESH_TOKEN_TTL (Go durations such as "15m" or "720h")
+const (
+	DefaultAccessTokenTTL  = 15 * time.Minute
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// AccessTokenTTL returns how long issued JWTs stay valid
+func AccessTokenTTL() time.Duration {
+	return durationFromEnv("ACCESS_TOKEN_TTL", DefaultAccessTokenTTL)
+}
+
+// RefreshTokenTTL returns how long a refresh token can be exchanged
+func RefreshTokenTTL() time.Duration {
+	return durationFromEnv("REFRESH_TOKEN_TTL", DefaultRefreshTokenTTL)
+}
+
+// GenerateRefreshToken returns a new opaque refresh token
+func GenerateRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the SHA-256 hex digest under which a refresh token is stored
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func durationFromEnv(name string, fallback time.Duration) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(name))
+	if err != nil || d <= 0 {
+		return fallback
+	}
+	return d
+}